@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strconv"
 )
 
 type Config struct {
@@ -14,6 +15,25 @@ type Config struct {
 	MongoDBDatabase   string
 	DefaultLanguage   string
 	WebhookBaseURL    string
+	TelephonyProvider string
+
+	// TwilioValidateSignature rejects webhook requests without a valid
+	// X-Twilio-Signature; only disable it for local development
+	TwilioValidateSignature bool
+
+	// Authentication of the management API
+	AuthEnabled   bool
+	AdminAPIKey   string // bootstrap key with the admin role, used to create the first stored keys
+	JWTSecret     string // HS256 signing secret; bearer tokens are disabled when empty
+	JWTTTLMinutes int
+
+	// Bulk dialing
+	DialCallsPerSecond float64
+	DialWorkers        int
+	DialMaxLiveCalls   int // per campaign, 0 means unlimited
+
+	// Days call recordings are kept when the campaign sets no retention; 0 keeps them
+	RecordingRetentionDays int
 }
 
 func LoadConfig() *Config {
@@ -27,6 +47,20 @@ func LoadConfig() *Config {
 		MongoDBDatabase:   getEnv("MONGODB_DATABASE", "ivr_calling_system"),
 		DefaultLanguage:   getEnv("DEFAULT_LANGUAGE", "en"),
 		WebhookBaseURL:    getEnv("WEBHOOK_BASE_URL", "http://localhost:8080"),
+		TelephonyProvider: getEnv("TELEPHONY_PROVIDER", "twilio"),
+
+		TwilioValidateSignature: getEnvBool("TWILIO_VALIDATE_SIGNATURE", true),
+
+		AuthEnabled:   getEnvBool("AUTH_ENABLED", true),
+		AdminAPIKey:   getEnv("ADMIN_API_KEY", ""),
+		JWTSecret:     getEnv("JWT_SECRET", ""),
+		JWTTTLMinutes: getEnvInt("JWT_TTL_MINUTES", 60),
+
+		DialCallsPerSecond: getEnvFloat("DIAL_CALLS_PER_SECOND", 1),
+		DialWorkers:        getEnvInt("DIAL_WORKERS", 5),
+		DialMaxLiveCalls:   getEnvInt("DIAL_MAX_LIVE_CALLS", 10),
+
+		RecordingRetentionDays: getEnvInt("RECORDING_RETENTION_DAYS", 90),
 	}
 }
 
@@ -37,3 +71,27 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
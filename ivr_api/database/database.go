@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -12,6 +13,23 @@ import (
 type MongoDB struct {
 	Client   *mongo.Client
 	Database *mongo.Database
+
+	memory *memoryStore // set instead of Client and Database by NewMemoryDB
+}
+
+// Collection is the part of a MongoDB collection the services use. It is
+// implemented by *mongo.Collection and by the collections of NewMemoryDB.
+type Collection interface {
+	InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error)
+	InsertMany(ctx context.Context, documents []interface{}, opts ...*options.InsertManyOptions) (*mongo.InsertManyResult, error)
+	FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult
+	Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error)
+	CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error)
+	DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+	UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult
+	Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (*mongo.Cursor, error)
 }
 
 // InitDB initializes MongoDB connection
@@ -57,6 +75,9 @@ func createIndexes(ctx context.Context, db *mongo.Database) error {
 		{
 			Keys: map[string]interface{}{"is_active": 1},
 		},
+		{
+			Keys: map[string]interface{}{"phone_numbers": 1},
+		},
 	}
 	_, err := db.Collection("campaigns").Indexes().CreateMany(ctx, campaignIndexes)
 	if err != nil {
@@ -77,6 +98,9 @@ func createIndexes(ctx context.Context, db *mongo.Database) error {
 		{
 			Keys: map[string]interface{}{"phone_number": 1},
 		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+		},
 	}
 	_, err = db.Collection("calls").Indexes().CreateMany(ctx, callIndexes)
 	if err != nil {
@@ -97,15 +121,131 @@ func createIndexes(ctx context.Context, db *mongo.Database) error {
 		return fmt.Errorf("failed to create call_log indexes: %w", err)
 	}
 
+	// CallAttempt indexes
+	callAttemptIndexes := []mongo.IndexModel{
+		{
+			Keys: map[string]interface{}{"call_id": 1},
+		},
+		{
+			Keys: map[string]interface{}{"twilio_call_sid": 1},
+		},
+	}
+	_, err = db.Collection("call_attempts").Indexes().CreateMany(ctx, callAttemptIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create call_attempt indexes: %w", err)
+	}
+
+	// Recording indexes
+	recordingIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"recording_sid": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: map[string]interface{}{"call_id": 1},
+		},
+		{
+			Keys: bson.D{{Key: "campaign_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: map[string]interface{}{"expires_at": 1},
+		},
+	}
+	_, err = db.Collection("recordings").Indexes().CreateMany(ctx, recordingIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create recording indexes: %w", err)
+	}
+
+	// Dial job indexes
+	dialJobIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+		{
+			Keys: map[string]interface{}{"campaign_id": 1},
+		},
+	}
+	_, err = db.Collection("dial_jobs").Indexes().CreateMany(ctx, dialJobIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create dial_job indexes: %w", err)
+	}
+
+	// Do-not-call indexes
+	dncIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"phone_number": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: map[string]interface{}{"created_at": -1},
+		},
+	}
+	_, err = db.Collection("dnc").Indexes().CreateMany(ctx, dncIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create dnc indexes: %w", err)
+	}
+
+	// Contact list indexes
+	contactListIndexes := []mongo.IndexModel{
+		{
+			Keys: map[string]interface{}{"created_at": -1},
+		},
+	}
+	_, err = db.Collection("contact_lists").Indexes().CreateMany(ctx, contactListIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create contact_list indexes: %w", err)
+	}
+
+	// Language pack indexes
+	languagePackIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"code": 1},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	_, err = db.Collection("language_packs").Indexes().CreateMany(ctx, languagePackIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create language_pack indexes: %w", err)
+	}
+
+	// Business calendar indexes
+	businessCalendarIndexes := []mongo.IndexModel{
+		{
+			Keys: map[string]interface{}{"name": 1},
+		},
+	}
+	_, err = db.Collection("business_calendars").Indexes().CreateMany(ctx, businessCalendarIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create business_calendar indexes: %w", err)
+	}
+
+	// API key indexes
+	apiKeyIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"key_hash": 1},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	_, err = db.Collection("api_keys").Indexes().CreateMany(ctx, apiKeyIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create api_key indexes: %w", err)
+	}
+
 	return nil
 }
 
 // Close closes the MongoDB connection
 func (m *MongoDB) Close(ctx context.Context) error {
+	if m.Client == nil {
+		return nil
+	}
 	return m.Client.Disconnect(ctx)
 }
 
-// Collection returns a MongoDB collection
-func (m *MongoDB) Collection(name string) *mongo.Collection {
+// Collection returns a MongoDB collection, or an in-memory one for NewMemoryDB
+func (m *MongoDB) Collection(name string) Collection {
+	if m.memory != nil {
+		return &memoryCollection{store: m.memory, name: name}
+	}
 	return m.Database.Collection(name)
 }
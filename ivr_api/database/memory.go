@@ -0,0 +1,1039 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrAggregateUnsupported is returned by in-memory collections for aggregation pipelines
+var ErrAggregateUnsupported = errors.New("aggregation pipelines are not supported by the in-memory database")
+
+// memoryUniqueKeys mirrors the unique indexes created by createIndexes
+var memoryUniqueKeys = map[string]string{
+	"recordings":     "recording_sid",
+	"dnc":            "phone_number",
+	"language_packs": "code",
+	"api_keys":       "key_hash",
+}
+
+// memoryStore holds the documents of an in-memory database, per collection in insertion order
+type memoryStore struct {
+	mu          sync.Mutex
+	collections map[string][]bson.M
+}
+
+// NewMemoryDB returns a database kept in memory, for tests and local
+// development without MongoDB. It supports the filters, updates and options
+// the services use; aggregation pipelines return ErrAggregateUnsupported.
+func NewMemoryDB() *MongoDB {
+	return &MongoDB{memory: &memoryStore{collections: make(map[string][]bson.M)}}
+}
+
+// memoryCollection is a Collection of a memoryStore
+type memoryCollection struct {
+	store *memoryStore
+	name  string
+}
+
+func (c *memoryCollection) InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	doc, err := toMemoryDocument(document)
+	if err != nil {
+		return nil, err
+	}
+
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	id, err := c.insert(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &mongo.InsertOneResult{InsertedID: id}, nil
+}
+
+func (c *memoryCollection) InsertMany(ctx context.Context, documents []interface{}, opts ...*options.InsertManyOptions) (*mongo.InsertManyResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	docs := make([]bson.M, 0, len(documents))
+	for _, document := range documents {
+		doc, err := toMemoryDocument(document)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	result := &mongo.InsertManyResult{}
+	for _, doc := range docs {
+		id, err := c.insert(doc)
+		if err != nil {
+			return result, err
+		}
+		result.InsertedIDs = append(result.InsertedIDs, id)
+	}
+	return result, nil
+}
+
+func (c *memoryCollection) FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult {
+	var sortSpec, projection interface{}
+	var skip int64
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if opt.Sort != nil {
+			sortSpec = opt.Sort
+		}
+		if opt.Projection != nil {
+			projection = opt.Projection
+		}
+		if opt.Skip != nil {
+			skip = *opt.Skip
+		}
+	}
+
+	docs, err := c.find(ctx, filter, sortSpec, projection, skip, 1)
+	if err != nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	}
+	if len(docs) == 0 {
+		return mongo.NewSingleResultFromDocument(bson.D{}, mongo.ErrNoDocuments, nil)
+	}
+	return mongo.NewSingleResultFromDocument(docs[0], nil, nil)
+}
+
+func (c *memoryCollection) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	var sortSpec, projection interface{}
+	var skip, limit int64
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if opt.Sort != nil {
+			sortSpec = opt.Sort
+		}
+		if opt.Projection != nil {
+			projection = opt.Projection
+		}
+		if opt.Skip != nil {
+			skip = *opt.Skip
+		}
+		if opt.Limit != nil {
+			limit = *opt.Limit
+		}
+	}
+
+	docs, err := c.find(ctx, filter, sortSpec, projection, skip, limit)
+	if err != nil {
+		return nil, err
+	}
+	documents := make([]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		documents = append(documents, doc)
+	}
+	return mongo.NewCursorFromDocuments(documents, nil, nil)
+}
+
+func (c *memoryCollection) CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error) {
+	docs, err := c.find(ctx, filter, nil, nil, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(docs)), nil
+}
+
+func (c *memoryCollection) DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := toMemoryDocument(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	positions, err := c.matching(f, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(positions) == 0 {
+		return &mongo.DeleteResult{}, nil
+	}
+	docs := c.store.collections[c.name]
+	c.store.collections[c.name] = append(docs[:positions[0]:positions[0]], docs[positions[0]+1:]...)
+	return &mongo.DeleteResult{DeletedCount: 1}, nil
+}
+
+func (c *memoryCollection) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.update(ctx, filter, update, false, opts)
+}
+
+func (c *memoryCollection) UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.update(ctx, filter, update, true, opts)
+}
+
+func (c *memoryCollection) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	var sortSpec, projection interface{}
+	upsert, returnAfter := false, false
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if opt.Sort != nil {
+			sortSpec = opt.Sort
+		}
+		if opt.Projection != nil {
+			projection = opt.Projection
+		}
+		if opt.Upsert != nil {
+			upsert = *opt.Upsert
+		}
+		if opt.ReturnDocument != nil {
+			returnAfter = *opt.ReturnDocument == options.After
+		}
+	}
+
+	doc, err := c.findOneAndUpdate(ctx, filter, update, sortSpec, upsert, returnAfter)
+	if err == nil && doc == nil {
+		err = mongo.ErrNoDocuments
+	}
+	if err == nil {
+		doc, err = applyProjection(doc, projection)
+	}
+	if err != nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	}
+	return mongo.NewSingleResultFromDocument(doc, nil, nil)
+}
+
+func (c *memoryCollection) Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+	return nil, ErrAggregateUnsupported
+}
+
+// find returns copies of the matching documents, sorted, skipped, limited and projected
+func (c *memoryCollection) find(ctx context.Context, filter, sortSpec, projection interface{}, skip, limit int64) ([]bson.M, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := toMemoryDocument(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	positions, err := c.matching(f, sortSpec)
+	if err != nil {
+		return nil, err
+	}
+	if skip > 0 {
+		positions = positions[min(int(skip), len(positions)):]
+	}
+	if limit > 0 && int(limit) < len(positions) {
+		positions = positions[:limit]
+	}
+
+	docs := make([]bson.M, 0, len(positions))
+	for _, position := range positions {
+		doc, err := applyProjection(copyValue(c.store.collections[c.name][position]).(bson.M), projection)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+func (c *memoryCollection) update(ctx context.Context, filter, update interface{}, many bool, opts []*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	upsert := false
+	for _, opt := range opts {
+		if opt != nil && opt.Upsert != nil {
+			upsert = *opt.Upsert
+		}
+	}
+	f, err := toMemoryDocument(filter)
+	if err != nil {
+		return nil, err
+	}
+	u, err := toMemoryDocument(update)
+	if err != nil {
+		return nil, err
+	}
+
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	positions, err := c.matching(f, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !many && len(positions) > 1 {
+		positions = positions[:1]
+	}
+
+	result := &mongo.UpdateResult{}
+	for _, position := range positions {
+		modified, err := c.updateAt(position, u)
+		if err != nil {
+			return nil, err
+		}
+		result.MatchedCount++
+		if modified {
+			result.ModifiedCount++
+		}
+	}
+
+	if len(positions) == 0 && upsert {
+		doc, err := upsertDocument(f, u)
+		if err != nil {
+			return nil, err
+		}
+		id, err := c.insert(doc)
+		if err != nil {
+			return nil, err
+		}
+		result.UpsertedCount = 1
+		result.UpsertedID = id
+	}
+	return result, nil
+}
+
+// findOneAndUpdate updates the first matching document and returns it before
+// or after the update, or nil when nothing matched and nothing was returned
+func (c *memoryCollection) findOneAndUpdate(ctx context.Context, filter, update, sortSpec interface{}, upsert, returnAfter bool) (bson.M, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := toMemoryDocument(filter)
+	if err != nil {
+		return nil, err
+	}
+	u, err := toMemoryDocument(update)
+	if err != nil {
+		return nil, err
+	}
+
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	positions, err := c.matching(f, sortSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(positions) > 0 {
+		before := copyValue(c.store.collections[c.name][positions[0]]).(bson.M)
+		if _, err := c.updateAt(positions[0], u); err != nil {
+			return nil, err
+		}
+		if returnAfter {
+			return copyValue(c.store.collections[c.name][positions[0]]).(bson.M), nil
+		}
+		return before, nil
+	}
+
+	if !upsert {
+		return nil, nil
+	}
+	doc, err := upsertDocument(f, u)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.insert(doc); err != nil {
+		return nil, err
+	}
+	if returnAfter {
+		return copyValue(doc).(bson.M), nil
+	}
+	return nil, nil
+}
+
+// insert stores a document, giving it an ObjectID when it has no _id. The store must be locked.
+func (c *memoryCollection) insert(doc bson.M) (interface{}, error) {
+	if _, ok := doc["_id"]; !ok {
+		doc["_id"] = primitive.NewObjectID()
+	}
+	if err := c.checkUnique(doc, -1); err != nil {
+		return nil, err
+	}
+	c.store.collections[c.name] = append(c.store.collections[c.name], doc)
+	return doc["_id"], nil
+}
+
+// updateAt applies an update to the document at position. The store must be locked.
+func (c *memoryCollection) updateAt(position int, update bson.M) (bool, error) {
+	current := c.store.collections[c.name][position]
+	updated := copyValue(current).(bson.M)
+	if err := applyUpdate(updated, update, false); err != nil {
+		return false, err
+	}
+	if err := c.checkUnique(updated, position); err != nil {
+		return false, err
+	}
+	c.store.collections[c.name][position] = updated
+	return !reflect.DeepEqual(current, updated), nil
+}
+
+// checkUnique rejects a document whose _id or unique key is already used by
+// another document, with the error MongoDB reports for duplicate keys
+func (c *memoryCollection) checkUnique(doc bson.M, skip int) error {
+	keys := []string{"_id"}
+	if key, ok := memoryUniqueKeys[c.name]; ok {
+		keys = append(keys, key)
+	}
+
+	for _, key := range keys {
+		value, ok := doc[key]
+		if !ok {
+			continue
+		}
+		for i, other := range c.store.collections[c.name] {
+			if i == skip {
+				continue
+			}
+			if otherValue, ok := other[key]; ok && valuesEqual(value, otherValue) {
+				return mongo.WriteException{WriteErrors: []mongo.WriteError{{
+					Code:    11000,
+					Message: fmt.Sprintf("E11000 duplicate key error collection: %s index: %s dup key: %v", c.name, key, value),
+				}}}
+			}
+		}
+	}
+	return nil
+}
+
+// matching returns the positions of the documents matching filter, sorted by
+// sortSpec and otherwise in insertion order. The store must be locked.
+func (c *memoryCollection) matching(filter bson.M, sortSpec interface{}) ([]int, error) {
+	docs := c.store.collections[c.name]
+	var positions []int
+	for i, doc := range docs {
+		ok, err := matchDocument(doc, filter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			positions = append(positions, i)
+		}
+	}
+	if sortSpec == nil {
+		return positions, nil
+	}
+
+	keys, err := sortKeys(sortSpec)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(positions, func(i, j int) bool {
+		for _, key := range keys {
+			a := firstValue(lookupValues(docs[positions[i]], key.path))
+			b := firstValue(lookupValues(docs[positions[j]], key.path))
+			if order := compareForSort(a, b) * key.direction; order != 0 {
+				return order < 0
+			}
+		}
+		return false
+	})
+	return positions, nil
+}
+
+type sortKey struct {
+	path      []string
+	direction int
+}
+
+func sortKeys(spec interface{}) ([]sortKey, error) {
+	var fields bson.D
+	if ordered, ok := spec.(bson.D); ok {
+		fields = ordered
+	} else {
+		doc, err := toMemoryDocument(spec)
+		if err != nil {
+			return nil, err
+		}
+		for field, direction := range doc {
+			fields = append(fields, bson.E{Key: field, Value: direction})
+		}
+	}
+
+	keys := make([]sortKey, 0, len(fields))
+	for _, field := range fields {
+		direction, ok := numberValue(field.Value)
+		if !ok || (direction != 1 && direction != -1) {
+			return nil, fmt.Errorf("sort direction of %s must be 1 or -1", field.Key)
+		}
+		keys = append(keys, sortKey{path: strings.Split(field.Key, "."), direction: int(direction)})
+	}
+	return keys, nil
+}
+
+// toMemoryDocument converts a document, filter or update to a bson.M holding
+// its values the way MongoDB stores them
+func toMemoryDocument(value interface{}) (bson.M, error) {
+	if value == nil {
+		return bson.M{}, nil
+	}
+	data, err := bson.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return normalizeValue(doc).(bson.M), nil
+}
+
+// normalizeValue turns every embedded document into a bson.M
+func normalizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case bson.D:
+		doc := make(bson.M, len(v))
+		for _, e := range v {
+			doc[e.Key] = normalizeValue(e.Value)
+		}
+		return doc
+	case bson.M:
+		doc := make(bson.M, len(v))
+		for key, elem := range v {
+			doc[key] = normalizeValue(elem)
+		}
+		return doc
+	case primitive.A:
+		array := make(primitive.A, len(v))
+		for i, elem := range v {
+			array[i] = normalizeValue(elem)
+		}
+		return array
+	default:
+		return value
+	}
+}
+
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case bson.M:
+		doc := make(bson.M, len(v))
+		for key, elem := range v {
+			doc[key] = copyValue(elem)
+		}
+		return doc
+	case primitive.A:
+		array := make(primitive.A, len(v))
+		for i, elem := range v {
+			array[i] = copyValue(elem)
+		}
+		return array
+	default:
+		return value
+	}
+}
+
+func matchDocument(doc bson.M, filter bson.M) (bool, error) {
+	for key, condition := range filter {
+		switch key {
+		case "$and", "$or", "$nor":
+			clauses, ok := condition.(primitive.A)
+			if !ok {
+				return false, fmt.Errorf("%s must be an array", key)
+			}
+			matched := 0
+			for _, clause := range clauses {
+				sub, ok := clause.(bson.M)
+				if !ok {
+					return false, fmt.Errorf("%s must hold documents", key)
+				}
+				ok, err := matchDocument(doc, sub)
+				if err != nil {
+					return false, err
+				}
+				if ok {
+					matched++
+				}
+			}
+			if (key == "$and" && matched < len(clauses)) || (key == "$or" && matched == 0) || (key == "$nor" && matched > 0) {
+				return false, nil
+			}
+		default:
+			if strings.HasPrefix(key, "$") {
+				return false, fmt.Errorf("query operator %s is not supported by the in-memory database", key)
+			}
+			ok, err := matchField(lookupValues(doc, strings.Split(key, ".")), condition)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+func matchField(values []interface{}, condition interface{}) (bool, error) {
+	operators, ok := condition.(bson.M)
+	if !ok || !isOperatorDocument(operators) {
+		return matchEqual(values, condition), nil
+	}
+
+	for operator, argument := range operators {
+		var matched bool
+		switch operator {
+		case "$eq":
+			matched = matchEqual(values, argument)
+		case "$ne":
+			matched = !matchEqual(values, argument)
+		case "$in", "$nin":
+			candidates, ok := argument.(primitive.A)
+			if !ok {
+				return false, fmt.Errorf("%s must be an array", operator)
+			}
+			for _, candidate := range candidates {
+				if matchEqual(values, candidate) {
+					matched = true
+					break
+				}
+			}
+			if operator == "$nin" {
+				matched = !matched
+			}
+		case "$exists":
+			matched = (len(values) > 0) == truthy(argument)
+		case "$gt", "$gte", "$lt", "$lte":
+			for _, value := range expandArrays(values) {
+				order, ok := compareValues(value, argument)
+				if ok && ((operator == "$gt" && order > 0) || (operator == "$gte" && order >= 0) ||
+					(operator == "$lt" && order < 0) || (operator == "$lte" && order <= 0)) {
+					matched = true
+					break
+				}
+			}
+		default:
+			return false, fmt.Errorf("query operator %s is not supported by the in-memory database", operator)
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// matchEqual reports whether a field equals value, or holds an array with an
+// element equal to it. A missing field equals null.
+func matchEqual(values []interface{}, value interface{}) bool {
+	if len(values) == 0 {
+		return value == nil
+	}
+	for _, v := range values {
+		if valuesEqual(v, value) {
+			return true
+		}
+		if array, ok := v.(primitive.A); ok {
+			for _, elem := range array {
+				if valuesEqual(elem, value) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func isOperatorDocument(doc bson.M) bool {
+	if len(doc) == 0 {
+		return false
+	}
+	for key := range doc {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupValues returns the values at a dotted path. Arrays are traversed, so
+// "contacts.phone_number" yields the phone number of every contact, while a
+// numeric part such as "contacts.2" picks one element.
+func lookupValues(value interface{}, path []string) []interface{} {
+	if len(path) == 0 {
+		return []interface{}{value}
+	}
+	switch v := value.(type) {
+	case bson.M:
+		child, ok := v[path[0]]
+		if !ok {
+			return nil
+		}
+		return lookupValues(child, path[1:])
+	case primitive.A:
+		if index, err := strconv.Atoi(path[0]); err == nil {
+			if index < 0 || index >= len(v) {
+				return nil
+			}
+			return lookupValues(v[index], path[1:])
+		}
+		var found []interface{}
+		for _, elem := range v {
+			if _, ok := elem.(bson.M); ok {
+				found = append(found, lookupValues(elem, path)...)
+			}
+		}
+		return found
+	default:
+		return nil
+	}
+}
+
+func firstValue(values []interface{}) interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
+
+func expandArrays(values []interface{}) []interface{} {
+	var expanded []interface{}
+	for _, value := range values {
+		if array, ok := value.(primitive.A); ok {
+			expanded = append(expanded, array...)
+		} else {
+			expanded = append(expanded, value)
+		}
+	}
+	return expanded
+}
+
+func numberValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func truthy(value interface{}) bool {
+	if b, ok := value.(bool); ok {
+		return b
+	}
+	n, ok := numberValue(value)
+	return ok && n != 0
+}
+
+// compareValues orders two values of the same BSON type; ok is false when they cannot be compared
+func compareValues(a, b interface{}) (order int, ok bool) {
+	if x, ok := numberValue(a); ok {
+		y, ok := numberValue(b)
+		if !ok {
+			return 0, false
+		}
+		return compareOrdered(x, y), true
+	}
+
+	switch x := a.(type) {
+	case nil:
+		return 0, b == nil
+	case string:
+		y, ok := b.(string)
+		return compareOrdered(x, y), ok
+	case primitive.DateTime:
+		y, ok := b.(primitive.DateTime)
+		return compareOrdered(x, y), ok
+	case primitive.ObjectID:
+		y, ok := b.(primitive.ObjectID)
+		return bytes.Compare(x[:], y[:]), ok
+	case bool:
+		y, ok := b.(bool)
+		if !ok || x == y {
+			return 0, ok
+		}
+		if y {
+			return -1, true
+		}
+		return 1, true
+	default:
+		return 0, false
+	}
+}
+
+func compareOrdered[T int64 | float64 | string | primitive.DateTime](x, y T) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func valuesEqual(a, b interface{}) bool {
+	if order, ok := compareValues(a, b); ok {
+		return order == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compareForSort orders values of different types the way MongoDB does
+func compareForSort(a, b interface{}) int {
+	if order, ok := compareValues(a, b); ok {
+		return order
+	}
+	return typeRank(a) - typeRank(b)
+}
+
+func typeRank(value interface{}) int {
+	if _, ok := numberValue(value); ok {
+		return 1
+	}
+	switch value.(type) {
+	case nil:
+		return 0
+	case string:
+		return 2
+	case bson.M:
+		return 3
+	case primitive.A:
+		return 4
+	case primitive.ObjectID:
+		return 5
+	case bool:
+		return 6
+	case primitive.DateTime:
+		return 7
+	default:
+		return 8
+	}
+}
+
+// applyProjection keeps or drops top-level fields as a find projection does
+func applyProjection(doc bson.M, projection interface{}) (bson.M, error) {
+	if projection == nil {
+		return doc, nil
+	}
+	fields, err := toMemoryDocument(projection)
+	if err != nil {
+		return nil, err
+	}
+
+	include := false
+	for field, value := range fields {
+		if field != "_id" && truthy(value) {
+			include = true
+		}
+	}
+	if !include {
+		for field, value := range fields {
+			if !truthy(value) {
+				delete(doc, field)
+			}
+		}
+		return doc, nil
+	}
+
+	projected := bson.M{}
+	if value, ok := fields["_id"]; !ok || truthy(value) {
+		projected["_id"] = doc["_id"]
+	}
+	for field, value := range fields {
+		if elem, ok := doc[field]; ok && truthy(value) {
+			projected[field] = elem
+		}
+	}
+	return projected, nil
+}
+
+// upsertDocument builds the document an upsert inserts: the equality
+// conditions of the filter with the update applied
+func upsertDocument(filter, update bson.M) (bson.M, error) {
+	doc := bson.M{}
+	for field, condition := range filter {
+		if strings.HasPrefix(field, "$") {
+			continue
+		}
+		if operators, ok := condition.(bson.M); ok && isOperatorDocument(operators) {
+			value, ok := operators["$eq"]
+			if !ok {
+				continue
+			}
+			condition = value
+		}
+		if err := setPath(doc, field, copyValue(condition)); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyUpdate(doc, update, true); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func applyUpdate(doc bson.M, update bson.M, inserting bool) error {
+	if !isOperatorDocument(update) {
+		return fmt.Errorf("update document must only hold update operators")
+	}
+
+	for operator, argument := range update {
+		fields, ok := argument.(bson.M)
+		if !ok {
+			return fmt.Errorf("%s must be a document", operator)
+		}
+		for path, value := range fields {
+			var err error
+			switch operator {
+			case "$set":
+				err = setPath(doc, path, copyValue(value))
+			case "$setOnInsert":
+				if inserting {
+					err = setPath(doc, path, copyValue(value))
+				}
+			case "$unset":
+				unsetPath(doc, path)
+			case "$inc":
+				var sum interface{}
+				if sum, err = addNumbers(firstValue(lookupValues(doc, strings.Split(path, "."))), value); err == nil {
+					err = setPath(doc, path, sum)
+				}
+			case "$push":
+				err = pushPath(doc, path, value)
+			default:
+				return fmt.Errorf("update operator %s is not supported by the in-memory database", operator)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setPath sets the value at a dotted path, creating the documents on the way
+func setPath(doc bson.M, path string, value interface{}) error {
+	parts := strings.Split(path, ".")
+	var container interface{} = doc
+	for i, part := range parts {
+		last := i == len(parts)-1
+		switch c := container.(type) {
+		case bson.M:
+			if last {
+				c[part] = value
+				return nil
+			}
+			child, ok := c[part]
+			if !ok || child == nil {
+				child = bson.M{}
+				c[part] = child
+			}
+			container = child
+		case primitive.A:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(c) {
+				return fmt.Errorf("cannot set %s: %s is not an element of the array", path, part)
+			}
+			if last {
+				c[index] = value
+				return nil
+			}
+			container = c[index]
+		default:
+			return fmt.Errorf("cannot set %s: %s is not a document", path, strings.Join(parts[:i], "."))
+		}
+	}
+	return nil
+}
+
+func unsetPath(doc bson.M, path string) {
+	parts := strings.Split(path, ".")
+	parent := firstValue(lookupValues(doc, parts[:len(parts)-1]))
+	last := parts[len(parts)-1]
+	switch p := parent.(type) {
+	case bson.M:
+		delete(p, last)
+	case primitive.A:
+		if index, err := strconv.Atoi(last); err == nil && index >= 0 && index < len(p) {
+			p[index] = nil
+		}
+	}
+}
+
+// pushPath appends to the array at a dotted path; {"$each": [...]} appends several values
+func pushPath(doc bson.M, path string, value interface{}) error {
+	var array primitive.A
+	switch current := firstValue(lookupValues(doc, strings.Split(path, "."))).(type) {
+	case nil:
+	case primitive.A:
+		array = current
+	default:
+		return fmt.Errorf("cannot push to %s: it is not an array", path)
+	}
+
+	if each, ok := value.(bson.M); ok {
+		if values, ok := each["$each"].(primitive.A); ok {
+			for _, v := range values {
+				array = append(array, copyValue(v))
+			}
+			return setPath(doc, path, array)
+		}
+	}
+	return setPath(doc, path, append(array, copyValue(value)))
+}
+
+func addNumbers(current, delta interface{}) (interface{}, error) {
+	if _, ok := numberValue(delta); !ok {
+		return nil, fmt.Errorf("cannot increment by a non-numeric value")
+	}
+	if current == nil {
+		return delta, nil
+	}
+	if _, ok := numberValue(current); !ok {
+		return nil, fmt.Errorf("cannot increment a non-numeric value")
+	}
+
+	switch x := current.(type) {
+	case int32:
+		if y, ok := delta.(int32); ok {
+			return x + y, nil
+		}
+		if y, ok := delta.(int64); ok {
+			return int64(x) + y, nil
+		}
+	case int64:
+		switch y := delta.(type) {
+		case int32:
+			return x + int64(y), nil
+		case int64:
+			return x + y, nil
+		}
+	}
+	a, _ := numberValue(current)
+	b, _ := numberValue(delta)
+	return a + b, nil
+}
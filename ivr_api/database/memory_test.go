@@ -0,0 +1,156 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type memoryContact struct {
+	Name   string `bson:"name"`
+	Status string `bson:"status"`
+}
+
+type memoryJob struct {
+	Name      string          `bson:"name"`
+	Status    string          `bson:"status"`
+	Count     int             `bson:"count"`
+	Tags      []string        `bson:"tags"`
+	Contacts  []memoryContact `bson:"contacts"`
+	CreatedAt time.Time       `bson:"created_at"`
+}
+
+func seedMemoryJobs(t *testing.T, collection Collection) time.Time {
+	t.Helper()
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	jobs := []interface{}{
+		memoryJob{Name: "a", Status: "queued", Count: 3, Tags: []string{"x"}, CreatedAt: start.Add(2 * time.Hour),
+			Contacts: []memoryContact{{Name: "Asha", Status: "pending"}}},
+		memoryJob{Name: "b", Status: "running", Count: 1, Tags: []string{"x", "y"}, CreatedAt: start},
+		memoryJob{Name: "c", Status: "queued", Count: 2, CreatedAt: start.Add(time.Hour)},
+	}
+	if _, err := collection.InsertMany(context.Background(), jobs); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+	return start
+}
+
+func memoryNames(t *testing.T, cursor *mongo.Cursor, err error) []string {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	var jobs []memoryJob
+	if err := cursor.All(context.Background(), &jobs); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	names := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		names = append(names, job.Name)
+	}
+	return names
+}
+
+func TestMemoryFind(t *testing.T) {
+	ctx := context.Background()
+	collection := NewMemoryDB().Collection("jobs")
+	start := seedMemoryJobs(t, collection)
+
+	tests := []struct {
+		name   string
+		filter bson.M
+		opts   *options.FindOptions
+		want   []string
+	}{
+		{"equality", bson.M{"status": "queued"}, nil, []string{"a", "c"}},
+		{"array element", bson.M{"tags": "y"}, nil, []string{"b"}},
+		{"nested array field", bson.M{"contacts.name": "Asha"}, nil, []string{"a"}},
+		{"in", bson.M{"name": bson.M{"$in": []string{"b", "c"}}}, nil, []string{"b", "c"}},
+		{"ne", bson.M{"status": bson.M{"$ne": "queued"}}, nil, []string{"b"}},
+		{"exists", bson.M{"tags": bson.M{"$exists": true, "$ne": nil}}, nil, []string{"a", "b"}},
+		{"time range", bson.M{"created_at": bson.M{"$gt": start, "$lte": start.Add(2 * time.Hour)}}, nil, []string{"a", "c"}},
+		{"or", bson.M{"$or": []bson.M{{"name": "a"}, {"count": bson.M{"$lt": 2}}}}, nil, []string{"a", "b"}},
+		{"sorted", bson.M{}, options.Find().SetSort(bson.M{"created_at": 1}), []string{"b", "c", "a"}},
+		{"sorted descending and limited", bson.M{}, options.Find().SetSort(bson.D{{Key: "count", Value: -1}}).SetLimit(2), []string{"a", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []*options.FindOptions
+			if tt.opts != nil {
+				opts = append(opts, tt.opts)
+			}
+			cursor, err := collection.Find(ctx, tt.filter, opts...)
+			got := memoryNames(t, cursor, err)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Find = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Find = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryUpdate(t *testing.T) {
+	ctx := context.Background()
+	collection := NewMemoryDB().Collection("jobs")
+	seedMemoryJobs(t, collection)
+
+	result, err := collection.UpdateOne(ctx, bson.M{"name": "a"}, bson.M{
+		"$set":  bson.M{"contacts.0.status": "called"},
+		"$inc":  bson.M{"count": 2},
+		"$push": bson.M{"tags": "z"},
+	})
+	if err != nil || result.MatchedCount != 1 || result.ModifiedCount != 1 {
+		t.Fatalf("UpdateOne = %+v, %v, want one modified document", result, err)
+	}
+	var job memoryJob
+	if err := collection.FindOne(ctx, bson.M{"name": "a"}).Decode(&job); err != nil {
+		t.Fatalf("FindOne: %v", err)
+	}
+	if job.Count != 5 || len(job.Tags) != 2 || job.Contacts[0].Status != "called" {
+		t.Errorf("updated job = %+v, want count 5, tags [x z] and a called contact", job)
+	}
+
+	// Claiming takes the oldest queued job and returns it updated
+	claimed := collection.FindOneAndUpdate(ctx,
+		bson.M{"status": "queued"},
+		bson.M{"$set": bson.M{"status": "running"}},
+		options.FindOneAndUpdate().SetSort(bson.M{"created_at": 1}).SetReturnDocument(options.After),
+	)
+	if err := claimed.Decode(&job); err != nil || job.Name != "c" || job.Status != "running" {
+		t.Errorf("FindOneAndUpdate = %+v, %v, want the running job c", job, err)
+	}
+
+	result, err = collection.UpdateOne(ctx, bson.M{"name": "d"}, bson.M{"$setOnInsert": bson.M{"status": "new"}}, options.Update().SetUpsert(true))
+	if err != nil || result.UpsertedCount != 1 {
+		t.Fatalf("upsert = %+v, %v, want one inserted document", result, err)
+	}
+	if err := collection.FindOne(ctx, bson.M{"name": "d", "status": "new"}).Decode(&job); err != nil {
+		t.Errorf("upserted document not found: %v", err)
+	}
+
+	if err := collection.FindOne(ctx, bson.M{"name": "missing"}).Err(); err != mongo.ErrNoDocuments {
+		t.Errorf("FindOne of a missing document = %v, want ErrNoDocuments", err)
+	}
+}
+
+func TestMemoryUniqueIndex(t *testing.T) {
+	ctx := context.Background()
+	collection := NewMemoryDB().Collection("dnc")
+
+	if _, err := collection.InsertOne(ctx, bson.M{"phone_number": "+14155550123"}); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+	_, err := collection.InsertOne(ctx, bson.M{"phone_number": "+14155550123"})
+	if !mongo.IsDuplicateKeyError(err) {
+		t.Errorf("second insert = %v, want a duplicate key error", err)
+	}
+}
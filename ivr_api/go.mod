@@ -8,6 +8,7 @@ require (
 	github.com/gin-contrib/cors v1.7.6
 	github.com/gin-gonic/gin v1.10.1
 	github.com/joho/godotenv v1.5.1
+	github.com/prabhatkumar/ivrshared v0.0.0
 	github.com/twilio/twilio-go v1.15.0
 	go.mongodb.org/mongo-driver v1.13.1
 )
@@ -49,3 +50,5 @@ require (
 	google.golang.org/protobuf v1.36.6 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/prabhatkumar/ivrshared => ../ivr_shared
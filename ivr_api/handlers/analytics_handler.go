@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AnalyticsHandler struct {
+	db               *database.MongoDB
+	analyticsService *services.AnalyticsService
+}
+
+func NewAnalyticsHandler(db *database.MongoDB, analyticsService *services.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		db:               db,
+		analyticsService: analyticsService,
+	}
+}
+
+// GetCampaignAnalytics returns the call funnel, key press distribution and
+// duration statistics of a campaign. Optional query parameters: from and to
+// (RFC 3339), bucket (hour or day) and tz (IANA zone for bucket boundaries).
+func (h *AnalyticsHandler) GetCampaignAnalytics(c *gin.Context) {
+	campaignID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+
+	query := services.AnalyticsQuery{
+		Bucket:   c.Query("bucket"),
+		Timezone: c.Query("tz"),
+	}
+	if query.Bucket != "" && query.Bucket != "hour" && query.Bucket != "day" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bucket must be 'hour' or 'day'"})
+		return
+	}
+	if query.Timezone != "" {
+		if _, err := time.LoadLocation(query.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tz must be a valid IANA time zone"})
+			return
+		}
+	}
+	for name, target := range map[string]**time.Time{"from": &query.From, "to": &query.To} {
+		if value := c.Query(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be an RFC 3339 timestamp"})
+				return
+			}
+			*target = &t
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	count, err := h.db.Collection("campaigns").CountDocuments(ctx, bson.M{"_id": campaignID})
+	if err != nil {
+		log.Printf("Failed to look up campaign %s: %v", campaignID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve campaign"})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	analytics, err := h.analyticsService.CampaignAnalytics(ctx, campaignID, query)
+	if err != nil {
+		log.Printf("Failed to compute analytics for campaign %s: %v", campaignID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute campaign analytics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"campaign_id": campaignID,
+		"from":        query.From,
+		"to":          query.To,
+		"bucket":      query.Bucket,
+		"summary":     analytics.Summary,
+		"buckets":     analytics.Buckets,
+	})
+}
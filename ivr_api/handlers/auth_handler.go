@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/middleware"
+	"github.com/prabhatkumar/ivrcalling/models"
+	"github.com/prabhatkumar/ivrcalling/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AuthHandler struct {
+	db          *database.MongoDB
+	authService *services.AuthService
+}
+
+func NewAuthHandler(db *database.MongoDB, authService *services.AuthService) *AuthHandler {
+	return &AuthHandler{
+		db:          db,
+		authService: authService,
+	}
+}
+
+// GetCurrentPrincipal returns the caller's identity and role
+func (h *AuthHandler) GetCurrentPrincipal(c *gin.Context) {
+	c.JSON(http.StatusOK, middleware.CurrentPrincipal(c))
+}
+
+// IssueToken exchanges an API key for a short-lived JWT bearer token
+func (h *AuthHandler) IssueToken(c *gin.Context) {
+	principal := middleware.CurrentPrincipal(c)
+	if principal.Method != "api_key" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tokens can only be issued for API keys"})
+		return
+	}
+
+	token, expiresAt, err := h.authService.IssueToken(principal)
+	if err != nil {
+		if errors.Is(err, services.ErrTokensDisabled) {
+			c.JSON(http.StatusNotImplemented, gin.H{"error": "JWT authentication is not configured (set JWT_SECRET)"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"token_type": "Bearer",
+		"expires_at": expiresAt,
+	})
+}
+
+// ListAPIKeys lists the stored API keys without their secrets
+func (h *AuthHandler) ListAPIKeys(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := h.db.Collection("api_keys").Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve API keys"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var keys []models.APIKey
+	if err = cursor.All(ctx, &keys); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode API keys"})
+		return
+	}
+
+	if keys == nil {
+		keys = []models.APIKey{}
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// CreateAPIKey creates a new API key. The key itself is only shown in this response.
+func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
+	var request models.APIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !models.ValidRole(request.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be one of admin, campaign-manager, read-only"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	key, apiKey, err := h.authService.CreateAPIKey(ctx, request.Name, request.Role, middleware.CurrentSubject(c))
+	if err != nil {
+		log.Printf("Failed to create API key: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	log.Printf("✓ API key created - ID: %s, Name: %s, Role: %s", apiKey.ID.Hex(), apiKey.Name, apiKey.Role)
+	c.JSON(http.StatusCreated, gin.H{
+		"key":     key,
+		"api_key": apiKey,
+		"message": "Store this key now - it cannot be retrieved again",
+	})
+}
+
+// DeleteAPIKey revokes an API key and every token issued for it
+func (h *AuthHandler) DeleteAPIKey(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := h.db.Collection("api_keys").DeleteOne(ctx, bson.M{"_id": objID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete API key"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	log.Printf("✓ API key %s revoked", objID.Hex())
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
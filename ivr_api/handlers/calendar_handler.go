@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/middleware"
+	"github.com/prabhatkumar/ivrcalling/models"
+	"github.com/prabhatkumar/ivrcalling/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CalendarHandler struct {
+	db *database.MongoDB
+}
+
+func NewCalendarHandler(db *database.MongoDB) *CalendarHandler {
+	return &CalendarHandler{db: db}
+}
+
+// ListCalendars retrieves all business calendars
+func (h *CalendarHandler) ListCalendars(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := h.db.Collection("business_calendars").Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve business calendars"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var calendars []models.BusinessCalendar
+	if err = cursor.All(ctx, &calendars); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode business calendars"})
+		return
+	}
+
+	if calendars == nil {
+		calendars = []models.BusinessCalendar{}
+	}
+
+	c.JSON(http.StatusOK, calendars)
+}
+
+// GetCalendar retrieves a business calendar and whether it is open right now
+func (h *CalendarHandler) GetCalendar(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid calendar ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var calendar models.BusinessCalendar
+	if err := h.db.Collection("business_calendars").FindOne(ctx, bson.M{"_id": objID}).Decode(&calendar); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Business calendar not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"calendar": calendar,
+		"open_now": services.IsOpen(&calendar, time.Now()),
+	})
+}
+
+// CreateCalendar adds a business calendar
+func (h *CalendarHandler) CreateCalendar(c *gin.Context) {
+	var calendar models.BusinessCalendar
+	if err := c.ShouldBindJSON(&calendar); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.ValidateBusinessCalendar(&calendar); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	calendar.ID = primitive.NilObjectID
+	calendar.CreatedBy = middleware.CurrentSubject(c)
+	calendar.CreatedAt = time.Now()
+	calendar.UpdatedAt = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := h.db.Collection("business_calendars").InsertOne(ctx, calendar)
+	if err != nil {
+		log.Printf("Failed to save business calendar: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save business calendar"})
+		return
+	}
+	calendar.ID = result.InsertedID.(primitive.ObjectID)
+
+	log.Printf("✓ Business calendar %s (%s) created", calendar.ID.Hex(), calendar.Name)
+	c.JSON(http.StatusCreated, calendar)
+}
+
+// UpdateCalendar replaces the name, time zone, hours and holidays of a business calendar
+func (h *CalendarHandler) UpdateCalendar(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid calendar ID"})
+		return
+	}
+
+	var calendar models.BusinessCalendar
+	if err := c.ShouldBindJSON(&calendar); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.ValidateBusinessCalendar(&calendar); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var updated models.BusinessCalendar
+	err = h.db.Collection("business_calendars").FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": objID},
+		bson.M{"$set": bson.M{
+			"name":       calendar.Name,
+			"timezone":   calendar.Timezone,
+			"hours":      calendar.Hours,
+			"holidays":   calendar.Holidays,
+			"updated_at": time.Now(),
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Business calendar not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update business calendar"})
+		return
+	}
+
+	log.Printf("✓ Business calendar %s updated", objID.Hex())
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteCalendar removes a business calendar that no campaign uses
+func (h *CalendarHandler) DeleteCalendar(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid calendar ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Forward actions of a deleted calendar would silently become available at all hours
+	if err := h.db.Collection("campaigns").FindOne(ctx, calendarUsageFilter(objID)).Err(); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Campaigns still use this business calendar"})
+		return
+	}
+
+	result, err := h.db.Collection("business_calendars").DeleteOne(ctx, bson.M{"_id": objID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete business calendar"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Business calendar not found"})
+		return
+	}
+
+	log.Printf("✓ Business calendar %s deleted", objID.Hex())
+	c.JSON(http.StatusOK, gin.H{"message": "Business calendar deleted successfully"})
+}
+
+// calendarUsageFilter matches campaigns with a forward action on any menu level using the calendar
+func calendarUsageFilter(id primitive.ObjectID) bson.M {
+	var paths bson.A
+	prefix := "actions"
+	for depth := 0; depth < models.MaxMenuDepth; depth++ {
+		paths = append(paths, bson.M{prefix + ".forward.calendar_id": id})
+		prefix += ".sub_menu.actions"
+	}
+	return bson.M{"$or": paths}
+}
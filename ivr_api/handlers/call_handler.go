@@ -6,29 +6,47 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/middleware"
 	"github.com/prabhatkumar/ivrcalling/models"
 	"github.com/prabhatkumar/ivrcalling/services"
+	"github.com/prabhatkumar/ivrshared/phone"
+	"github.com/prabhatkumar/ivrshared/twiml"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// bulkCallTimeout bounds each step of a bulk call request that scales with the
+// number of contacts: loading the contact list, the do-not-call check and
+// storing the dial job
+const bulkCallTimeout = 30 * time.Second
+
 type CallHandler struct {
-	db            *database.MongoDB
-	twilioService *services.TwilioService
+	db         *database.MongoDB
+	dncService *services.DNCService
+	dialer     *services.Dialer
+	dialQueue  *services.DialQueue
+	eventBus   *services.EventBus
 }
 
-func NewCallHandler(db *database.MongoDB, twilioService *services.TwilioService) *CallHandler {
+func NewCallHandler(db *database.MongoDB, dncService *services.DNCService, dialer *services.Dialer, dialQueue *services.DialQueue, eventBus *services.EventBus) *CallHandler {
 	return &CallHandler{
-		db:            db,
-		twilioService: twilioService,
+		db:         db,
+		dncService: dncService,
+		dialer:     dialer,
+		dialQueue:  dialQueue,
+		eventBus:   eventBus,
 	}
 }
 
-// InitiateBulkCalls handles the bulk call endpoint
+// InitiateBulkCalls queues a dial job for the contacts and returns immediately.
+// The calls are placed in the background by the dial queue; progress is
+// available from GET /api/jobs/:id.
 func (h *CallHandler) InitiateBulkCalls(c *gin.Context) {
 	var request models.BulkCallRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -36,10 +54,10 @@ func (h *CallHandler) InitiateBulkCalls(c *gin.Context) {
 		return
 	}
 
-	log.Printf("=== BULK CALL REQUEST ===")
-	log.Printf("Campaign ID: %s", request.CampaignID)
-	log.Printf("Language: %s", request.Language)
-	log.Printf("Contact count: %d", len(request.Contacts))
+	if (len(request.Contacts) == 0) == (request.ContactListID == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provide either contacts or contact_list_id"})
+		return
+	}
 
 	// Convert campaign ID string to ObjectID
 	campaignObjID, err := primitive.ObjectIDFromHex(request.CampaignID)
@@ -51,6 +69,39 @@ func (h *CallHandler) InitiateBulkCalls(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// Take the contacts from a stored contact list
+	var contactListID *primitive.ObjectID
+	if request.ContactListID != "" {
+		listObjID, err := primitive.ObjectIDFromHex(request.ContactListID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contact list ID"})
+			return
+		}
+
+		// A list can hold tens of thousands of contacts, so it gets more time than a lookup
+		listCtx, listCancel := context.WithTimeout(context.Background(), bulkCallTimeout)
+		defer listCancel()
+
+		var list models.ContactList
+		err = h.db.Collection("contact_lists").FindOne(listCtx, bson.M{"_id": listObjID}).Decode(&list)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Contact list not found"})
+			return
+		}
+
+		request.Contacts = make([]models.ContactRequest, 0, len(list.Contacts))
+		for _, contact := range list.Contacts {
+			request.Contacts = append(request.Contacts, models.ContactRequest{PhoneNumber: contact.PhoneNumber, Name: contact.Name, Fields: contact.Fields})
+		}
+		contactListID = &listObjID
+	}
+
+	log.Printf("=== BULK CALL REQUEST ===")
+	log.Printf("Campaign ID: %s", request.CampaignID)
+	log.Printf("Language: %s", request.Language)
+	log.Printf("Contact list: %s", request.ContactListID)
+	log.Printf("Contact count: %d", len(request.Contacts))
+
 	// Verify campaign exists
 	var campaign models.Campaign
 	err = h.db.Collection("campaigns").FindOne(ctx, bson.M{"_id": campaignObjID}).Decode(&campaign)
@@ -68,100 +119,126 @@ func (h *CallHandler) InitiateBulkCalls(c *gin.Context) {
 		return
 	}
 
+	if campaign.Schedule != nil && campaign.Schedule.EndDate != nil && !time.Now().Before(*campaign.Schedule.EndDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Campaign schedule has ended"})
+		return
+	}
+
 	// Determine language
 	language := request.Language
 	if language == "" {
 		language = campaign.Language
 	}
 
-	// Create calls and initiate them
-	var successCount, failCount int
-	var callIDs []string
+	// Template variables every contact must provide
+	variables, err := services.CampaignVariables(&campaign)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	for _, contact := range request.Contacts {
-		// Create call record
-		call := models.Call{
-			CampaignID:   campaignObjID,
-			PhoneNumber:  contact.PhoneNumber,
-			CustomerName: contact.Name,
-			Status:       "pending",
-			Language:     language,
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
+	// Normalize every number to E.164 and reject the request if any contact is invalid
+	invalidContacts := []gin.H{}
+	for i, contact := range request.Contacts {
+		number, err := phone.ParseDialable(contact.PhoneNumber, request.DefaultRegion)
+		if err != nil {
+			invalidContacts = append(invalidContacts, gin.H{"index": i, "phone_number": contact.PhoneNumber, "error": err.Error()})
+			continue
 		}
+		request.Contacts[i].PhoneNumber = number.E164
 
-		callCtx, callCancel := context.WithTimeout(context.Background(), 5*time.Second)
-		result, err := h.db.Collection("calls").InsertOne(callCtx, call)
-		callCancel()
-
-		if err != nil {
-			log.Printf("Failed to create call record: %v", err)
-			failCount++
+		if err := services.ValidateFields(contact.Fields); err != nil {
+			invalidContacts = append(invalidContacts, gin.H{"index": i, "phone_number": contact.PhoneNumber, "error": err.Error()})
 			continue
 		}
+		if missing := services.MissingVariables(variables, contact.Fields); len(missing) > 0 {
+			invalidContacts = append(invalidContacts, gin.H{
+				"index":        i,
+				"phone_number": contact.PhoneNumber,
+				"error":        "missing template variables: " + strings.Join(missing, ", "),
+			})
+		}
+	}
+	if len(invalidContacts) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":            fmt.Sprintf("%d contacts are invalid", len(invalidContacts)),
+			"invalid_contacts": invalidContacts,
+		})
+		return
+	}
 
-		call.ID = result.InsertedID.(primitive.ObjectID)
-		callIDs = append(callIDs, call.ID.Hex())
+	// Look up which contacts are on the do-not-call list
+	phoneNumbers := make([]string, 0, len(request.Contacts))
+	for _, contact := range request.Contacts {
+		phoneNumbers = append(phoneNumbers, contact.PhoneNumber)
+	}
+	dncCtx, dncCancel := context.WithTimeout(context.Background(), bulkCallTimeout)
+	defer dncCancel()
+	blocked, err := h.dncService.BlockedNumbers(dncCtx, phoneNumbers)
+	if err != nil {
+		log.Printf("Failed to check do-not-call list: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check do-not-call list"})
+		return
+	}
 
-		log.Printf("Call record created - ID: %s, Phone: %s, Name: %s", call.ID.Hex(), contact.PhoneNumber, contact.Name)
+	// Build the dial job - numbers on the do-not-call list are settled up front
+	job := models.DialJob{
+		CampaignID:    campaignObjID,
+		Language:      language,
+		Status:        "queued",
+		Contacts:      make([]models.DialJobContact, 0, len(request.Contacts)),
+		Total:         len(request.Contacts),
+		ContactListID: contactListID,
+		CreatedBy:     middleware.CurrentSubject(c),
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	dncNumbers := []string{}
 
-		// Initiate Twilio call
-		twilioCall, err := h.twilioService.MakeCall(contact.PhoneNumber, language, call.ID.Hex())
-		if err != nil {
-			log.Printf("Failed to initiate call for %s: %v", contact.PhoneNumber, err)
-
-			// Update call status to failed
-			updateCtx, updateCancel := context.WithTimeout(context.Background(), 5*time.Second)
-			h.db.Collection("calls").UpdateOne(
-				updateCtx,
-				bson.M{"_id": call.ID},
-				bson.M{"$set": bson.M{
-					"status":        "failed",
-					"error_message": err.Error(),
-					"updated_at":    time.Now(),
-				}},
-			)
-			updateCancel()
-
-			failCount++
-			continue
+	for _, contact := range request.Contacts {
+		jobContact := models.DialJobContact{
+			PhoneNumber: contact.PhoneNumber,
+			Name:        contact.Name,
+			Fields:      contact.Fields,
+			Status:      "pending",
 		}
-
-		// Update call with Twilio SID
-		updateCtx, updateCancel := context.WithTimeout(context.Background(), 5*time.Second)
-		h.db.Collection("calls").UpdateOne(
-			updateCtx,
-			bson.M{"_id": call.ID},
-			bson.M{"$set": bson.M{
-				"status":          "initiated",
-				"twilio_call_sid": *twilioCall.Sid,
-				"updated_at":      time.Now(),
-			}},
-		)
-		updateCancel()
-
-		log.Printf("✓ Call initiated successfully - SID: %s", *twilioCall.Sid)
-
-		// Create call log
-		callLog := models.CallLog{
-			CallID:    call.ID,
-			Event:     "initiated",
-			Details:   fmt.Sprintf("Call initiated to %s", contact.PhoneNumber),
-			CreatedAt: time.Now(),
+		if blocked[services.NormalizePhoneNumber(contact.PhoneNumber, "")] {
+			log.Printf("Skipping %s - number is on the do-not-call list", contact.PhoneNumber)
+			jobContact.Status = "dnc"
+			dncNumbers = append(dncNumbers, contact.PhoneNumber)
+			job.DNCCount++
+			job.Processed++
 		}
+		job.Contacts = append(job.Contacts, jobContact)
+	}
 
-		logCtx, logCancel := context.WithTimeout(context.Background(), 5*time.Second)
-		h.db.Collection("call_logs").InsertOne(logCtx, callLog)
-		logCancel()
+	if job.Processed == job.Total {
+		now := time.Now()
+		job.Status = "completed"
+		job.CompletedAt = &now
+	}
 
-		successCount++
+	insertCtx, insertCancel := context.WithTimeout(context.Background(), bulkCallTimeout)
+	defer insertCancel()
+	result, err := h.db.Collection("dial_jobs").InsertOne(insertCtx, job)
+	if err != nil {
+		log.Printf("Failed to create dial job: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue calls"})
+		return
 	}
+	job.ID = result.InsertedID.(primitive.ObjectID)
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":       "Bulk calls initiated",
-		"success_count": successCount,
-		"fail_count":    failCount,
-		"call_ids":      callIDs,
+	if job.Status == "queued" {
+		h.dialQueue.Enqueue(job.ID)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":     "Bulk calls queued",
+		"job_id":      job.ID.Hex(),
+		"status":      job.Status,
+		"total":       job.Total,
+		"dnc_count":   len(dncNumbers),
+		"dnc_numbers": dncNumbers,
 	})
 }
 
@@ -190,6 +267,24 @@ func (h *CallHandler) GetCallStatus(c *gin.Context) {
 		var callLogs []models.CallLog
 		cursor.All(ctx, &callLogs)
 
+		// Get dial attempts
+		var attempts []models.CallAttempt
+		if attemptCursor, err := h.db.Collection("call_attempts").Find(ctx, bson.M{"call_id": objID}); err == nil {
+			attemptCursor.All(ctx, &attempts)
+		}
+		if attempts == nil {
+			attempts = []models.CallAttempt{}
+		}
+
+		// Get recordings
+		var recordings []models.Recording
+		if recordingCursor, err := h.db.Collection("recordings").Find(ctx, bson.M{"call_id": objID}); err == nil {
+			recordingCursor.All(ctx, &recordings)
+		}
+		if recordings == nil {
+			recordings = []models.Recording{}
+		}
+
 		// Return call with logs
 		c.JSON(http.StatusOK, gin.H{
 			"id":              call.ID,
@@ -197,13 +292,20 @@ func (h *CallHandler) GetCallStatus(c *gin.Context) {
 			"phone_number":    call.PhoneNumber,
 			"customer_name":   call.CustomerName,
 			"status":          call.Status,
+			"outcome":         call.Outcome,
+			"answered_by":     call.AnsweredBy,
+			"attempts":        call.Attempts,
+			"next_attempt_at": call.NextAttemptAt,
 			"twilio_call_sid": call.TwilioCallSID,
 			"language":        call.Language,
 			"duration":        call.Duration,
 			"error_message":   call.ErrorMessage,
+			"created_by":      call.CreatedBy,
 			"created_at":      call.CreatedAt,
 			"updated_at":      call.UpdatedAt,
 			"call_logs":       callLogs,
+			"call_attempts":   attempts,
+			"recordings":      recordings,
 		})
 		return
 	}
@@ -240,21 +342,60 @@ func (h *CallHandler) GetCampaignCalls(c *gin.Context) {
 		calls = []models.Call{}
 	}
 
-	// Calculate statistics
-	total, _ := h.db.Collection("calls").CountDocuments(ctx, bson.M{"campaign_id": objID})
-	pending, _ := h.db.Collection("calls").CountDocuments(ctx, bson.M{"campaign_id": objID, "status": "pending"})
-	initiated, _ := h.db.Collection("calls").CountDocuments(ctx, bson.M{"campaign_id": objID, "status": "initiated"})
-	completed, _ := h.db.Collection("calls").CountDocuments(ctx, bson.M{"campaign_id": objID, "status": "completed"})
-	failed, _ := h.db.Collection("calls").CountDocuments(ctx, bson.M{"campaign_id": objID, "status": "failed"})
+	// Calculate statistics - one count per status in a single aggregation
+	counts := map[string]int{}
+	total := 0
+	statusCursor, err := h.db.Collection("calls").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"campaign_id": objID}}},
+		{{Key: "$group", Value: bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}}},
+	})
+	if err == nil {
+		var rows []struct {
+			Status string `bson:"_id"`
+			Count  int    `bson:"count"`
+		}
+		if statusCursor.All(ctx, &rows) == nil {
+			for _, row := range rows {
+				counts[row.Status] = row.Count
+				total += row.Count
+			}
+		}
+	}
+
+	// Machine detection results, for campaigns that detect answering machines
+	answeredBy := gin.H{
+		services.AnsweredByHuman:   0,
+		services.AnsweredByMachine: 0,
+		services.AnsweredByFax:     0,
+		services.AnsweredByUnknown: 0,
+	}
+	answeredByCursor, err := h.db.Collection("calls").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"campaign_id": objID, "answered_by": bson.M{"$exists": true}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$answered_by", "count": bson.M{"$sum": 1}}}},
+	})
+	if err == nil {
+		var rows []struct {
+			AnsweredBy string `bson:"_id"`
+			Count      int    `bson:"count"`
+		}
+		if answeredByCursor.All(ctx, &rows) == nil {
+			for _, row := range rows {
+				answeredBy[row.AnsweredBy] = row.Count
+			}
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"calls": calls,
 		"stats": gin.H{
-			"total":     total,
-			"pending":   pending,
-			"initiated": initiated,
-			"completed": completed,
-			"failed":    failed,
+			"total":       total,
+			"pending":     counts["pending"],
+			"initiated":   counts["initiated"],
+			"in_progress": counts["in-progress"],
+			"completed":   counts["completed"],
+			"failed":      counts["failed"],
+			"scheduled":   counts["scheduled"] + counts["claimed"],
+			"answered_by": answeredBy,
 		},
 	})
 }
@@ -279,35 +420,38 @@ func (h *CallHandler) HandleStatusWebhook(c *gin.Context) {
 		return
 	}
 
-	// Map Twilio status to our status
-	var newStatus string
-	switch statusUpdate.CallStatus {
-	case "queued", "ringing":
-		newStatus = "initiated"
-	case "in-progress":
-		newStatus = "in-progress"
-	case "completed":
-		newStatus = "completed"
-	case "failed", "busy", "no-answer":
-		newStatus = "failed"
-	default:
-		newStatus = call.Status
+	duration := 0
+	if statusUpdate.CallDuration != "" {
+		if d, err := strconv.Atoi(statusUpdate.CallDuration); err == nil {
+			duration = d
+		}
 	}
 
-	// Update call status
-	updateData := bson.M{
-		"status":     newStatus,
-		"updated_at": time.Now(),
+	// Calls placed with machine detection report who answered
+	if statusUpdate.AnsweredBy != "" {
+		h.dialer.RecordAnsweredBy(ctx, &call, statusUpdate.AnsweredBy)
 	}
 
-	if statusUpdate.CallDuration != "" {
-		if duration, err := strconv.Atoi(statusUpdate.CallDuration); err == nil {
-			updateData["duration"] = duration
+	// Map Twilio status to our status
+	// Final outcomes are published by the dialer once the retry policy has been applied
+	switch statusUpdate.CallStatus {
+	case "queued", "ringing":
+		h.updateCallStatus(ctx, &call, "initiated", duration)
+		if statusUpdate.CallStatus == "ringing" {
+			h.eventBus.PublishCall("ringing", &call, "")
 		}
+	case "in-progress":
+		h.updateCallStatus(ctx, &call, "in-progress", duration)
+		h.eventBus.PublishCall("answered", &call, "")
+	case "completed", "failed", "busy", "no-answer", "canceled":
+		// Final outcome of this attempt - keep the original Twilio outcome and
+		// let the campaign's retry policy decide whether to redial
+		h.updateCallStatus(ctx, &call, call.Status, duration)
+		h.dialer.FinishAttemptRecord(ctx, statusUpdate.CallSid, statusUpdate.CallStatus, duration)
+		newStatus := h.dialer.CompleteAttempt(ctx, &call, statusUpdate.CallStatus)
+		log.Printf("Call %s ended with '%s' - status: %s", call.ID.Hex(), statusUpdate.CallStatus, newStatus)
 	}
 
-	h.db.Collection("calls").UpdateOne(ctx, bson.M{"_id": call.ID}, bson.M{"$set": updateData})
-
 	// Create call log
 	callLog := models.CallLog{
 		CallID:    call.ID,
@@ -317,5 +461,19 @@ func (h *CallHandler) HandleStatusWebhook(c *gin.Context) {
 	}
 	h.db.Collection("call_logs").InsertOne(ctx, callLog)
 
-	c.XML(http.StatusOK, []byte("<Response></Response>"))
+	writeTwiML(c, twiml.NewResponse().String())
+}
+
+// updateCallStatus stores the call status and, when reported, its duration
+func (h *CallHandler) updateCallStatus(ctx context.Context, call *models.Call, status string, duration int) {
+	updateData := bson.M{
+		"status":     status,
+		"updated_at": time.Now(),
+	}
+	if duration > 0 {
+		updateData["duration"] = duration
+	}
+
+	h.db.Collection("calls").UpdateOne(ctx, bson.M{"_id": call.ID}, bson.M{"$set": updateData})
+	call.Status = status
 }
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prabhatkumar/ivrcalling/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBulkCallFlow(t *testing.T) {
+	env := newTestEnv(t)
+
+	campaignID := env.insertCampaign(t, models.Campaign{
+		Name:      "Reminders",
+		Language:  "en",
+		IntroText: "Hello {{.name}}",
+		Actions:   []models.IVRAction{{ActionType: "information", ActionInput: "1", Message: "Your appointment is tomorrow"}},
+	})
+	if _, err := env.dncs.Add(env.ctx, models.DNCEntry{PhoneNumber: "+14155550199", Source: "manual", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("failed to add do-not-call entry: %v", err)
+	}
+
+	w := env.postJSON(t, "/api/calls/bulk", gin.H{
+		"campaign_id": campaignID.Hex(),
+		"contacts": []gin.H{
+			{"phone_number": "+1 415 555 0123", "name": "Asha"},
+			{"phone_number": "+14155550199", "name": "Lee"},
+		},
+	})
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("bulk call = %d, want 202: %s", w.Code, w.Body.String())
+	}
+	var queued struct {
+		JobID      string   `json:"job_id"`
+		DNCNumbers []string `json:"dnc_numbers"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &queued); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(queued.DNCNumbers) != 1 || queued.DNCNumbers[0] != "+14155550199" {
+		t.Errorf("dnc_numbers = %v, want [+14155550199]", queued.DNCNumbers)
+	}
+
+	// The dial queue places the one callable contact
+	waitFor(t, "the call to be placed", func() bool { return len(env.provider.Calls()) == 1 })
+	placed := env.provider.Calls()[0]
+	if placed.To != "+14155550123" {
+		t.Errorf("dialed %s, want +14155550123", placed.To)
+	}
+	callID, err := primitive.ObjectIDFromHex(env.provider.CallID(placed.SID))
+	if err != nil {
+		t.Fatalf("fake call has no call ID: %v", err)
+	}
+	waitFor(t, "the call record to be initiated", func() bool {
+		return env.findCall(t, bson.M{"_id": callID}).TwilioCallSID == placed.SID
+	})
+
+	jobID, _ := primitive.ObjectIDFromHex(queued.JobID)
+	waitFor(t, "the dial job to complete", func() bool {
+		var job models.DialJob
+		err := env.db.Collection("dial_jobs").FindOne(env.ctx, bson.M{"_id": jobID}).Decode(&job)
+		return err == nil && job.Status == "completed" && job.SuccessCount == 1 && job.DNCCount == 1
+	})
+
+	// Answering plays the campaign menu to the contact
+	env.postForm(t, "/api/webhook/status", url.Values{"CallSid": {placed.SID}, "CallStatus": {"in-progress"}})
+	body := env.postForm(t, "/api/webhook/voice?call_id="+callID.Hex()+"&language=en", url.Values{"CallSid": {placed.SID}})
+	if !strings.Contains(body, "Hello Asha") || !strings.Contains(body, `<Gather`) {
+		t.Errorf("voice response does not greet Asha with the menu: %s", body)
+	}
+	if call := env.findCall(t, bson.M{"_id": callID}); call.Status != "in-progress" {
+		t.Errorf("status after answering = %s, want in-progress", call.Status)
+	}
+
+	body = env.postForm(t, "/api/webhook/gather", url.Values{"CallSid": {placed.SID}, "Digits": {"1"}})
+	if !strings.Contains(body, "Your appointment is tomorrow") {
+		t.Errorf("gather response does not play the information: %s", body)
+	}
+
+	env.postForm(t, "/api/webhook/status", url.Values{"CallSid": {placed.SID}, "CallStatus": {"completed"}, "CallDuration": {"42"}})
+	call := env.findCall(t, bson.M{"_id": callID})
+	if call.Status != "completed" || call.Duration != 42 {
+		t.Errorf("after hanging up: status %s, duration %d, want completed, 42", call.Status, call.Duration)
+	}
+	if events := env.logEvents(t, callID); !contains(events, "action_information_executed") {
+		t.Errorf("call log events %v, want action_information_executed", events)
+	}
+}
+
+func TestBusyCallIsRetried(t *testing.T) {
+	env := newTestEnv(t)
+
+	campaignID := env.insertCampaign(t, models.Campaign{
+		Name:        "Reminders",
+		Language:    "en",
+		IntroText:   "Hello {{.name}}",
+		Actions:     []models.IVRAction{{ActionType: "information", ActionInput: "1", Message: "Thanks"}},
+		RetryPolicy: &models.RetryPolicy{MaxAttempts: 2, RetryOn: []string{"busy"}},
+	})
+
+	w := env.postJSON(t, "/api/calls/bulk", gin.H{
+		"campaign_id": campaignID.Hex(),
+		"contacts":    []gin.H{{"phone_number": "+14155550123", "name": "Asha"}},
+	})
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("bulk call = %d, want 202: %s", w.Code, w.Body.String())
+	}
+
+	waitFor(t, "the first attempt", func() bool { return len(env.provider.Calls()) == 1 })
+	first := env.provider.Calls()[0]
+	callID, err := primitive.ObjectIDFromHex(env.provider.CallID(first.SID))
+	if err != nil {
+		t.Fatalf("fake call has no call ID: %v", err)
+	}
+	waitFor(t, "the call record to be initiated", func() bool {
+		return env.findCall(t, bson.M{"_id": callID}).TwilioCallSID == first.SID
+	})
+
+	// A busy line is retried by the scheduler
+	env.postForm(t, "/api/webhook/status", url.Values{"CallSid": {first.SID}, "CallStatus": {"busy"}})
+	call := env.findCall(t, bson.M{"_id": callID})
+	if call.Status != "scheduled" || call.Outcome != "busy" || call.NextAttemptAt == nil {
+		t.Fatalf("after busy: status %s, outcome %s, next attempt %v, want a scheduled retry", call.Status, call.Outcome, call.NextAttemptAt)
+	}
+
+	// The retry is due now rather than after the policy's backoff
+	_, err = env.db.Collection("calls").UpdateOne(env.ctx, bson.M{"_id": callID}, bson.M{"$set": bson.M{"next_attempt_at": time.Now()}})
+	if err != nil {
+		t.Fatalf("failed to move the retry forward: %v", err)
+	}
+	env.scheduler.Start(env.ctx)
+	waitFor(t, "the retry", func() bool { return len(env.provider.Calls()) == 2 })
+	second := env.provider.Calls()[1]
+	waitFor(t, "the retry to be stored", func() bool {
+		return env.findCall(t, bson.M{"_id": callID}).TwilioCallSID == second.SID
+	})
+	if call := env.findCall(t, bson.M{"_id": callID}); call.Attempts != 2 || call.Status != "initiated" || call.ClaimedAt != nil {
+		t.Errorf("after retry: attempts %d, status %s, claimed at %v, want 2, initiated and no claim", call.Attempts, call.Status, call.ClaimedAt)
+	}
+
+	// The last attempt settles the call
+	env.postForm(t, "/api/webhook/status", url.Values{"CallSid": {second.SID}, "CallStatus": {"busy"}, "CallDuration": {"0"}})
+	if call := env.findCall(t, bson.M{"_id": callID}); call.Status != "failed" || call.Outcome != "busy" {
+		t.Errorf("after the last attempt: status %s, outcome %s, want failed, busy", call.Status, call.Outcome)
+	}
+
+	attempts, err := env.db.Collection("call_attempts").CountDocuments(env.ctx, bson.M{"call_id": callID, "outcome": "busy"})
+	if err != nil || attempts != 2 {
+		t.Errorf("finished attempts = %d (%v), want 2", attempts, err)
+	}
+	if events := env.logEvents(t, callID); !contains(events, "retry_scheduled") {
+		t.Errorf("call log events %v, want retry_scheduled", events)
+	}
+}
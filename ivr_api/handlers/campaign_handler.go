@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,17 +12,22 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/middleware"
 	"github.com/prabhatkumar/ivrcalling/models"
+	"github.com/prabhatkumar/ivrcalling/services"
+	"github.com/prabhatkumar/ivrshared/phone"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type CampaignHandler struct {
-	db *database.MongoDB
+	db            *database.MongoDB
+	businessHours *services.BusinessHoursService
+	inbound       *services.InboundService
 }
 
-func NewCampaignHandler(db *database.MongoDB) *CampaignHandler {
-	return &CampaignHandler{db: db}
+func NewCampaignHandler(db *database.MongoDB, businessHours *services.BusinessHoursService, inbound *services.InboundService) *CampaignHandler {
+	return &CampaignHandler{db: db, businessHours: businessHours, inbound: inbound}
 }
 
 // CreateCampaign creates a new campaign
@@ -57,8 +64,13 @@ func (h *CampaignHandler) CreateCampaign(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Intro text is required"})
 		return
 	}
+	if err := services.ValidateMessage(campaign.IntroText); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Intro text: " + err.Error()})
+		return
+	}
 
-	// Set timestamps
+	// Set audit fields
+	campaign.CreatedBy = middleware.CurrentSubject(c)
 	campaign.CreatedAt = time.Now()
 	campaign.UpdatedAt = time.Now()
 
@@ -67,33 +79,76 @@ func (h *CampaignHandler) CreateCampaign(c *gin.Context) {
 		campaign.Language = "en"
 	}
 
+	if err := services.ValidateVoice(campaign.Language, campaign.Voice); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if campaign.Voice != "" {
+		// Store the catalogue spelling of the name
+		voice, _ := services.FindVoice(campaign.Language, campaign.Voice)
+		campaign.Voice = voice.Name
+	}
+
 	// Initialize actions array if nil
 	if campaign.Actions == nil {
 		campaign.Actions = []models.IVRAction{}
 	}
 
-	// Validate actions
-	for i, action := range campaign.Actions {
-		if strings.TrimSpace(action.ActionInput) == "" {
-			log.Printf("WARNING: Action %d has empty action_input", i+1)
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Action %d must have a key press (action_input)", i+1)})
-			return
-		}
-		if action.ActionType == "information" && strings.TrimSpace(action.Message) == "" {
-			log.Printf("WARNING: Information action %d has empty message", i+1)
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Information action %d must have a message", i+1)})
-			return
-		}
-		if action.ActionType == "forward" && strings.TrimSpace(action.ForwardPhone) == "" {
-			log.Printf("WARNING: Forward action %d has empty phone number", i+1)
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Forward action %d must have a phone number", i+1)})
-			return
-		}
+	// Validate actions (including nested sub-menus)
+	if err := validateActions(campaign.Actions, "", 0); err != nil {
+		log.Printf("WARNING: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validateRetryPolicy(campaign.RetryPolicy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.ValidateSpeechSettings(campaign.SpeechInput); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.ValidateSchedule(campaign.Schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.ValidateMachineDetection(campaign.MachineDetection); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.ValidateRecordingSettings(campaign.Recording); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.ValidatePhoneNumbers(campaign.PhoneNumbers); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := services.CampaignVariables(&campaign); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	if err := h.businessHours.CheckCalendars(ctx, campaign.Actions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.inbound.CheckPhoneNumbers(ctx, primitive.NilObjectID, campaign.PhoneNumbers); err != nil {
+		c.JSON(phoneNumbersStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
 	result, err := h.db.Collection("campaigns").InsertOne(ctx, campaign)
 	if err != nil {
 		log.Printf("Failed to insert campaign into database: %v", err)
@@ -168,12 +223,200 @@ func (h *CampaignHandler) UpdateCampaign(c *gin.Context) {
 		return
 	}
 
+	// Audit fields cannot be changed
+	delete(updateData, "created_by")
+	delete(updateData, "created_at")
+
+	// Decode the schedule into its model so dates are stored as dates, not strings
+	if raw, ok := updateData["schedule"]; ok && raw != nil {
+		var schedule models.Schedule
+		encoded, _ := json.Marshal(raw)
+		if err := json.Unmarshal(encoded, &schedule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule: " + err.Error()})
+			return
+		}
+		if err := services.ValidateSchedule(&schedule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updateData["schedule"] = schedule
+	}
+
+	if raw, ok := updateData["retry_policy"]; ok && raw != nil {
+		var retryPolicy models.RetryPolicy
+		encoded, _ := json.Marshal(raw)
+		if err := json.Unmarshal(encoded, &retryPolicy); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid retry_policy: " + err.Error()})
+			return
+		}
+		if err := validateRetryPolicy(&retryPolicy); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updateData["retry_policy"] = retryPolicy
+	}
+
+	if raw, ok := updateData["speech_input"]; ok && raw != nil {
+		var speechInput models.SpeechSettings
+		encoded, _ := json.Marshal(raw)
+		if err := json.Unmarshal(encoded, &speechInput); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid speech_input: " + err.Error()})
+			return
+		}
+		if err := services.ValidateSpeechSettings(&speechInput); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updateData["speech_input"] = speechInput
+	}
+
+	if raw, ok := updateData["machine_detection"]; ok && raw != nil {
+		var machineDetection models.MachineDetection
+		encoded, _ := json.Marshal(raw)
+		if err := json.Unmarshal(encoded, &machineDetection); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid machine_detection: " + err.Error()})
+			return
+		}
+		if err := services.ValidateMachineDetection(&machineDetection); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updateData["machine_detection"] = machineDetection
+	}
+
+	if raw, ok := updateData["recording"]; ok && raw != nil {
+		var recording models.RecordingSettings
+		encoded, _ := json.Marshal(raw)
+		if err := json.Unmarshal(encoded, &recording); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recording: " + err.Error()})
+			return
+		}
+		if err := services.ValidateRecordingSettings(&recording); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updateData["recording"] = recording
+	}
+
+	if raw, ok := updateData["phone_numbers"]; ok {
+		var phoneNumbers []string
+		encoded, _ := json.Marshal(raw)
+		if err := json.Unmarshal(encoded, &phoneNumbers); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid phone_numbers: " + err.Error()})
+			return
+		}
+		if err := services.ValidatePhoneNumbers(phoneNumbers); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updateData["phone_numbers"] = phoneNumbers
+	}
+
+	if introText, ok := updateData["intro_text"].(string); ok {
+		if err := services.ValidateMessage(introText); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Intro text: " + err.Error()})
+			return
+		}
+	}
+
+	// The voice must be offered for the campaign's language, whichever of the two changes
+	_, voiceSet := updateData["voice"]
+	_, languageSet := updateData["language"]
+	if voiceSet || languageSet {
+		var current models.Campaign
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := h.db.Collection("campaigns").FindOne(ctx, bson.M{"_id": objID}).Decode(&current)
+		cancel()
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+			return
+		}
+
+		language, voice := current.Language, current.Voice
+		if languageSet {
+			language, _ = updateData["language"].(string)
+		}
+		if voiceSet {
+			voice, _ = updateData["voice"].(string)
+		}
+		if err := services.ValidateVoice(language, voice); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if voiceSet && voice != "" {
+			found, _ := services.FindVoice(language, voice)
+			updateData["voice"] = found.Name
+		}
+	}
+
+	// Validate actions like on create, storing forward numbers in E.164
+	if raw, ok := updateData["actions"]; ok {
+		var actions []models.IVRAction
+		encoded, _ := json.Marshal(raw)
+		if err := json.Unmarshal(encoded, &actions); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid actions: " + err.Error()})
+			return
+		}
+		if actions == nil {
+			actions = []models.IVRAction{}
+		}
+		if err := validateActions(actions, "", 0); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updateData["actions"] = actions
+	}
+
 	// Add updated timestamp
 	updateData["updated_at"] = time.Now()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	if actions, ok := updateData["actions"].([]models.IVRAction); ok {
+		if err := h.businessHours.CheckCalendars(ctx, actions); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if phoneNumbers, ok := updateData["phone_numbers"].([]string); ok {
+		if err := h.inbound.CheckPhoneNumbers(ctx, objID, phoneNumbers); err != nil {
+			c.JSON(phoneNumbersStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// The updated messages must still be fillable for the contacts waiting to be called
+	var current models.Campaign
+	if err := h.db.Collection("campaigns").FindOne(ctx, bson.M{"_id": objID}).Decode(&current); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+	updated, err := applyCampaignUpdate(&current, updateData)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	variables, err := services.CampaignVariables(updated)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	invalidContacts, err := h.contactsMissingVariables(ctx, objID, variables)
+	if err != nil {
+		log.Printf("Failed to check the contacts of campaign %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check the campaign's contacts"})
+		return
+	}
+	if len(invalidContacts) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":            fmt.Sprintf("%d waiting contacts are missing template variables", len(invalidContacts)),
+			"invalid_contacts": invalidContacts,
+		})
+		return
+	}
+
 	result, err := h.db.Collection("campaigns").UpdateOne(
 		ctx,
 		bson.M{"_id": objID},
@@ -201,6 +444,90 @@ func (h *CampaignHandler) UpdateCampaign(c *gin.Context) {
 	c.JSON(http.StatusOK, campaign)
 }
 
+// applyCampaignUpdate returns the campaign as it will be stored once the
+// update fields are set on it
+func applyCampaignUpdate(campaign *models.Campaign, updateData map[string]interface{}) (*models.Campaign, error) {
+	encoded, err := bson.Marshal(campaign)
+	if err != nil {
+		return nil, err
+	}
+	var merged bson.M
+	if err := bson.Unmarshal(encoded, &merged); err != nil {
+		return nil, err
+	}
+	for field, value := range updateData {
+		merged[field] = value
+	}
+
+	if encoded, err = bson.Marshal(merged); err != nil {
+		return nil, fmt.Errorf("invalid campaign update: %w", err)
+	}
+	var updated models.Campaign
+	if err := bson.Unmarshal(encoded, &updated); err != nil {
+		return nil, fmt.Errorf("invalid campaign update: %w", err)
+	}
+	return &updated, nil
+}
+
+// contactsMissingVariables lists the contacts of a campaign that have not been
+// called yet and lack a value for one of the variables: calls that are pending
+// or scheduled, and contacts still queued in a dial job
+func (h *CampaignHandler) contactsMissingVariables(ctx context.Context, campaignID primitive.ObjectID, variables []string) ([]gin.H, error) {
+	invalidContacts := []gin.H{}
+	// Every contact has the built-in variables
+	if len(services.MissingVariables(variables, nil)) == 0 {
+		return invalidContacts, nil
+	}
+
+	cursor, err := h.db.Collection("calls").Find(ctx, bson.M{
+		"campaign_id": campaignID,
+		"status":      bson.M{"$in": []string{"pending", "scheduled", "claimed"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var calls []models.Call
+	if err := cursor.All(ctx, &calls); err != nil {
+		return nil, err
+	}
+	for _, call := range calls {
+		if missing := services.MissingVariables(variables, call.Fields); len(missing) > 0 {
+			invalidContacts = append(invalidContacts, gin.H{
+				"call_id":      call.ID.Hex(),
+				"phone_number": call.PhoneNumber,
+				"error":        "missing template variables: " + strings.Join(missing, ", "),
+			})
+		}
+	}
+
+	cursor, err = h.db.Collection("dial_jobs").Find(ctx, bson.M{
+		"campaign_id": campaignID,
+		"status":      bson.M{"$in": []string{"queued", "running"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var jobs []models.DialJob
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		for _, contact := range job.Contacts {
+			if contact.Status != "pending" {
+				continue
+			}
+			if missing := services.MissingVariables(variables, contact.Fields); len(missing) > 0 {
+				invalidContacts = append(invalidContacts, gin.H{
+					"job_id":       job.ID.Hex(),
+					"phone_number": contact.PhoneNumber,
+					"error":        "missing template variables: " + strings.Join(missing, ", "),
+				})
+			}
+		}
+	}
+	return invalidContacts, nil
+}
+
 // DeleteCampaign deletes a campaign
 func (h *CampaignHandler) DeleteCampaign(c *gin.Context) {
 	id := c.Param("id")
@@ -226,3 +553,129 @@ func (h *CampaignHandler) DeleteCampaign(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Campaign deleted successfully"})
 }
+
+// validateActions checks one level of the menu tree and recurses into sub-menus.
+// prefix is the label of the parent action (e.g. "2" for the sub-menu behind action 2).
+func validateActions(actions []models.IVRAction, prefix string, depth int) error {
+	if depth >= models.MaxMenuDepth {
+		return fmt.Errorf("Menus cannot be nested more than %d levels deep", models.MaxMenuDepth)
+	}
+
+	seen := make(map[string]bool)
+	keywords := make(map[string]string) // normalized keyword → label of the action using it
+	for i, action := range actions {
+		label := fmt.Sprintf("%d", i+1)
+		if prefix != "" {
+			label = prefix + "." + label
+		}
+
+		input := strings.TrimSpace(action.ActionInput)
+		if input == "" {
+			return fmt.Errorf("Action %s must have a key press (action_input)", label)
+		}
+		if input == "0" {
+			return fmt.Errorf("Action %s cannot use key 0, it is reserved for repeating the menu or going back", label)
+		}
+		if seen[input] {
+			return fmt.Errorf("Action %s uses key %s which is already used on the same menu level", label, input)
+		}
+		seen[input] = true
+
+		if err := services.ValidateMessage(action.Message); err != nil {
+			return fmt.Errorf("Action %s message: %v", label, err)
+		}
+
+		for _, keyword := range action.Keywords {
+			normalized := services.NormalizeSpeech(keyword)
+			if normalized == "" {
+				return fmt.Errorf("Action %s has an empty keyword", label)
+			}
+			if other, ok := keywords[normalized]; ok && other != label {
+				return fmt.Errorf("Action %s uses keyword '%s' which is already used by action %s", label, keyword, other)
+			}
+			keywords[normalized] = label
+		}
+
+		switch action.ActionType {
+		case "information":
+			if strings.TrimSpace(action.Message) == "" {
+				return fmt.Errorf("Information action %s must have a message", label)
+			}
+		case "forward":
+			if strings.TrimSpace(action.ForwardPhone) == "" {
+				return fmt.Errorf("Forward action %s must have a phone number", label)
+			}
+			number, err := phone.ParseDialable(action.ForwardPhone, "")
+			if err != nil {
+				return fmt.Errorf("Forward action %s: %v", label, err)
+			}
+			actions[i].ForwardPhone = number.E164
+			if err := services.ValidateForwardSettings(&actions[i], actions); err != nil {
+				return fmt.Errorf("Forward action %s: %v", label, err)
+			}
+		case "menu":
+			if action.SubMenu == nil || len(action.SubMenu.Actions) == 0 {
+				return fmt.Errorf("Menu action %s must have a sub_menu with at least one action", label)
+			}
+			if err := services.ValidateMessage(action.SubMenu.Prompt); err != nil {
+				return fmt.Errorf("Menu action %s prompt: %v", label, err)
+			}
+			if err := validateActions(action.SubMenu.Actions, label, depth+1); err != nil {
+				return err
+			}
+		case "collect":
+			if action.Collect == nil {
+				return fmt.Errorf("Collect action %s must have collect settings", label)
+			}
+			if strings.TrimSpace(action.Message) == "" {
+				return fmt.Errorf("Collect action %s must have a message prompting for the value", label)
+			}
+			if err := services.ValidateCollectSettings(action.Collect); err != nil {
+				return fmt.Errorf("Collect action %s: %v", label, err)
+			}
+		case "record":
+			if action.Record == nil {
+				return fmt.Errorf("Record action %s must have record settings", label)
+			}
+			if strings.TrimSpace(action.Message) == "" {
+				return fmt.Errorf("Record action %s must have a message prompting for the recording", label)
+			}
+			if err := services.ValidateRecordSettings(action.Record); err != nil {
+				return fmt.Errorf("Record action %s: %v", label, err)
+			}
+		default:
+			return fmt.Errorf("Action %s has unsupported action_type '%s' (allowed: information, forward, menu, collect, record)", label, action.ActionType)
+		}
+	}
+
+	return nil
+}
+
+// validateRetryPolicy checks the limits of a campaign's retry policy
+func validateRetryPolicy(policy *models.RetryPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	if policy.MaxAttempts < 1 || policy.MaxAttempts > 10 {
+		return fmt.Errorf("Retry policy max_attempts must be between 1 and 10")
+	}
+	if policy.BackoffMinutes < 1 {
+		return fmt.Errorf("Retry policy backoff_minutes must be at least 1")
+	}
+	for _, outcome := range policy.RetryOn {
+		switch outcome {
+		case "busy", "no-answer", "failed":
+		default:
+			return fmt.Errorf("Retry policy cannot retry on '%s' (allowed: busy, no-answer, failed)", outcome)
+		}
+	}
+	return nil
+}
+
+// phoneNumbersStatus maps an error of InboundService.CheckPhoneNumbers to an HTTP status
+func phoneNumbersStatus(err error) int {
+	if errors.Is(err, services.ErrPhoneNumberInUse) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
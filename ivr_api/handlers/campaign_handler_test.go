@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prabhatkumar/ivrcalling/models"
+)
+
+func TestValidateActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions []models.IVRAction
+		wantErr string
+	}{
+		{
+			name:    "information",
+			actions: []models.IVRAction{{ActionType: "information", ActionInput: "1", Message: "We are open daily"}},
+		},
+		{
+			name: "nested menu",
+			actions: []models.IVRAction{{ActionType: "menu", ActionInput: "1", Message: "Billing", SubMenu: &models.MenuNode{
+				Actions: []models.IVRAction{{ActionType: "information", ActionInput: "1", Message: "Pay online"}},
+			}}},
+		},
+		{
+			name:    "unknown type",
+			actions: []models.IVRAction{{ActionType: "transfer", ActionInput: "1", Message: "Hold on"}},
+			wantErr: "unsupported action_type 'transfer'",
+		},
+		{
+			name:    "missing type",
+			actions: []models.IVRAction{{ActionInput: "1", Message: "Hold on"}},
+			wantErr: "unsupported action_type ''",
+		},
+		{
+			name: "unknown type in a sub menu",
+			actions: []models.IVRAction{{ActionType: "menu", ActionInput: "1", Message: "Billing", SubMenu: &models.MenuNode{
+				Actions: []models.IVRAction{{ActionType: "voicemail", ActionInput: "1"}},
+			}}},
+			wantErr: "Action 1.1 has unsupported action_type 'voicemail'",
+		},
+		{
+			name: "duplicate key",
+			actions: []models.IVRAction{
+				{ActionType: "information", ActionInput: "1", Message: "Open daily"},
+				{ActionType: "information", ActionInput: "1", Message: "Closed on holidays"},
+			},
+			wantErr: "already used",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateActions(tt.actions, "", 0)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateActions: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateActions = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpdateCampaignChecksWaitingContacts(t *testing.T) {
+	env := newTestEnv(t)
+
+	campaignID := env.insertCampaign(t, models.Campaign{
+		Name:      "Reminders",
+		Language:  "en",
+		IntroText: "Hello {{.name}}",
+		Actions:   []models.IVRAction{{ActionType: "information", ActionInput: "1", Message: "Thanks"}},
+	})
+	env.insertCall(t, models.Call{CampaignID: campaignID, PhoneNumber: "+14155550123", Status: "scheduled", Fields: map[string]string{"amount": "10"}})
+	env.insertCall(t, models.Call{CampaignID: campaignID, PhoneNumber: "+14155550124", Status: "scheduled"})
+	env.insertCall(t, models.Call{CampaignID: campaignID, PhoneNumber: "+14155550125", Status: "completed"})
+
+	tests := []struct {
+		name     string
+		update   gin.H
+		wantCode int
+		wantErr  string
+	}{
+		{
+			name:     "variable a waiting contact lacks",
+			update:   gin.H{"intro_text": "You owe {{.amount}}"},
+			wantCode: http.StatusBadRequest,
+			wantErr:  "1 waiting contacts are missing template variables",
+		},
+		{
+			name: "variable in an updated action",
+			update: gin.H{"actions": []gin.H{
+				{"action_type": "information", "action_input": "1", "message": "Due on {{.due_date}}"},
+			}},
+			wantCode: http.StatusBadRequest,
+			wantErr:  "missing template variables",
+		},
+		{
+			name:     "template syntax error",
+			update:   gin.H{"intro_text": "You owe {{.amount"},
+			wantCode: http.StatusBadRequest,
+			wantErr:  "Intro text",
+		},
+		{
+			name:     "built-in variables only",
+			update:   gin.H{"intro_text": "Hello {{.name}}, this call is for {{.phone_number}}"},
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := env.sendJSON(t, http.MethodPut, "/api/campaigns/"+campaignID.Hex(), tt.update)
+			if w.Code != tt.wantCode {
+				t.Fatalf("update = %d, want %d: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantErr) {
+				t.Errorf("update response %s, want it to contain %q", w.Body.String(), tt.wantErr)
+			}
+		})
+	}
+
+	// Only the waiting call without an amount is reported
+	w := env.sendJSON(t, http.MethodPut, "/api/campaigns/"+campaignID.Hex(), gin.H{"intro_text": "You owe {{.amount}}"})
+	if body := w.Body.String(); !strings.Contains(body, "+14155550124") || strings.Contains(body, "+14155550123") || strings.Contains(body, "+14155550125") {
+		t.Errorf("invalid contacts %s, want only +14155550124", body)
+	}
+}
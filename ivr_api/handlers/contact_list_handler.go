@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/middleware"
+	"github.com/prabhatkumar/ivrcalling/models"
+	"github.com/prabhatkumar/ivrcalling/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxContactFileSize limits uploaded contact files
+const maxContactFileSize = 10 << 20
+
+type ContactListHandler struct {
+	db *database.MongoDB
+}
+
+func NewContactListHandler(db *database.MongoDB) *ContactListHandler {
+	return &ContactListHandler{db: db}
+}
+
+// UploadContactList creates a contact list from an uploaded CSV or XLSX file.
+// The "phone_column" and "name_column" form fields map columns by header name
+// or 1-based number, and "has_header" overrides header row detection. Numbers
+// without a country code are read as numbers of the "default_region" country.
+// Other columns become custom fields for message templates; with "campaign_id"
+// every contact must have a value for each variable the campaign uses.
+// Invalid and duplicate rows are skipped and reported per line.
+func (h *ContactListHandler) UploadContactList(c *gin.Context) {
+	name := strings.TrimSpace(c.PostForm("name"))
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CSV or XLSX file is required (form field 'file')"})
+		return
+	}
+	if file.Size > maxContactFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is larger than 10 MB"})
+		return
+	}
+
+	mapping := services.ContactColumnMapping{
+		Phone:         c.PostForm("phone_column"),
+		Name:          c.PostForm("name_column"),
+		DefaultRegion: c.PostForm("default_region"),
+	}
+	if value := c.PostForm("has_header"); value != "" {
+		hasHeader, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "has_header must be true or false"})
+			return
+		}
+		mapping.HasHeader = &hasHeader
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if campaignID := c.PostForm("campaign_id"); campaignID != "" {
+		campaignObjID, err := primitive.ObjectIDFromHex(campaignID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+			return
+		}
+		var campaign models.Campaign
+		if err := h.db.Collection("campaigns").FindOne(ctx, bson.M{"_id": campaignObjID}).Decode(&campaign); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+			return
+		}
+		if mapping.Required, err = services.CampaignVariables(&campaign); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxContactFileSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+
+	rows, err := services.ReadSpreadsheet(file.Filename, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	imported, err := services.ParseContacts(rows, mapping)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(imported.Contacts) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":        "The file contains no valid contacts",
+			"invalid_rows": imported.InvalidRows,
+		})
+		return
+	}
+
+	list := models.ContactList{
+		Name:         name,
+		Description:  c.PostForm("description"),
+		SourceFile:   file.Filename,
+		Contacts:     imported.Contacts,
+		Fields:       imported.Fields,
+		Total:        len(imported.Contacts),
+		InvalidCount: len(imported.InvalidRows),
+		CreatedBy:    middleware.CurrentSubject(c),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	result, err := h.db.Collection("contact_lists").InsertOne(ctx, list)
+	if err != nil {
+		log.Printf("Failed to save contact list: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save contact list"})
+		return
+	}
+	list.ID = result.InsertedID.(primitive.ObjectID)
+
+	log.Printf("✓ Contact list created - ID: %s, Name: %s, Contacts: %d, Invalid rows: %d",
+		list.ID.Hex(), list.Name, list.Total, list.InvalidCount)
+
+	// The contacts were just uploaded, so only the summary is returned
+	list.Contacts = nil
+	c.JSON(http.StatusCreated, gin.H{
+		"contact_list":    list,
+		"valid_count":     list.Total,
+		"invalid_count":   len(imported.InvalidRows) - imported.DuplicateCount,
+		"duplicate_count": imported.DuplicateCount,
+		"invalid_rows":    imported.InvalidRows,
+	})
+}
+
+// ListContactLists retrieves all contact lists without their contacts
+func (h *ContactListHandler) ListContactLists(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := h.db.Collection("contact_lists").Find(
+		ctx,
+		bson.M{},
+		options.Find().SetSort(bson.M{"created_at": -1}).SetProjection(bson.M{"contacts": 0}),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve contact lists"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var lists []models.ContactList
+	if err = cursor.All(ctx, &lists); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode contact lists"})
+		return
+	}
+
+	if lists == nil {
+		lists = []models.ContactList{}
+	}
+
+	c.JSON(http.StatusOK, lists)
+}
+
+// GetContactList retrieves a contact list.
+// Its contacts are included when called with ?contacts=true.
+func (h *ContactListHandler) GetContactList(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contact list ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.FindOne()
+	if c.Query("contacts") != "true" {
+		opts.SetProjection(bson.M{"contacts": 0})
+	}
+
+	var list models.ContactList
+	err = h.db.Collection("contact_lists").FindOne(ctx, bson.M{"_id": objID}, opts).Decode(&list)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact list not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// DeleteContactList deletes a contact list. Jobs already started from it are not affected.
+func (h *ContactListHandler) DeleteContactList(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contact list ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := h.db.Collection("contact_lists").DeleteOne(ctx, bson.M{"_id": objID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete contact list"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact list not found"})
+		return
+	}
+
+	log.Printf("✓ Contact list %s deleted", objID.Hex())
+	c.JSON(http.StatusOK, gin.H{"message": "Contact list deleted successfully"})
+}
@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/models"
+	"github.com/prabhatkumar/ivrcalling/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type DNCHandler struct {
+	db         *database.MongoDB
+	dncService *services.DNCService
+}
+
+func NewDNCHandler(db *database.MongoDB, dncService *services.DNCService) *DNCHandler {
+	return &DNCHandler{
+		db:         db,
+		dncService: dncService,
+	}
+}
+
+// ListDNC retrieves do-not-call entries, newest first
+func (h *DNCHandler) ListDNC(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return
+	}
+	skip, err := strconv.Atoi(c.DefaultQuery("skip", "0"))
+	if err != nil || skip < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "skip must be a non-negative number"})
+		return
+	}
+
+	filter := bson.M{}
+	if source := c.Query("source"); source != "" {
+		filter["source"] = source
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := h.db.Collection("dnc").Find(
+		ctx,
+		filter,
+		options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(int64(limit)).SetSkip(int64(skip)),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve do-not-call list"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.DNCEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode do-not-call list"})
+		return
+	}
+
+	if entries == nil {
+		entries = []models.DNCEntry{}
+	}
+
+	total, _ := h.db.Collection("dnc").CountDocuments(ctx, filter)
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"total":   total,
+	})
+}
+
+// GetDNC checks whether a phone number is on the do-not-call list. National
+// numbers need the default_region query parameter.
+func (h *DNCHandler) GetDNC(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	entry, err := h.dncService.Get(ctx, services.NormalizePhoneNumber(c.Param("phone"), c.Query("default_region")))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Phone number is not on the do-not-call list"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve do-not-call entry"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// AddDNC adds a single phone number to the do-not-call list
+func (h *DNCHandler) AddDNC(c *gin.Context) {
+	var request models.DNCRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	phone, err := services.ParseDNCNumber(request.PhoneNumber, request.DefaultRegion)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	created, err := h.dncService.Add(ctx, models.DNCEntry{
+		PhoneNumber: phone,
+		Source:      "manual",
+		Reason:      request.Reason,
+	})
+	if err != nil {
+		log.Printf("Failed to add %s to do-not-call list: %v", phone, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add phone number"})
+		return
+	}
+
+	entry, err := h.dncService.Get(ctx, phone)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve do-not-call entry"})
+		return
+	}
+
+	if created {
+		log.Printf("✓ Added %s to do-not-call list", phone)
+		c.JSON(http.StatusCreated, entry)
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// DeleteDNC removes a phone number from the do-not-call list. National
+// numbers need the default_region query parameter.
+func (h *DNCHandler) DeleteDNC(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.dncService.Remove(ctx, services.NormalizePhoneNumber(c.Param("phone"), c.Query("default_region"))); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Phone number is not on the do-not-call list"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove phone number"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Phone number removed from do-not-call list"})
+}
+
+// ImportDNC bulk-imports phone numbers from an uploaded CSV file.
+// The phone number is read from a "phone_number", "phone" or "number" column,
+// or from the first column when the file has no header row. An optional
+// "reason" column is stored with each entry. Numbers written without a country
+// code are read in the default_region form field.
+func (h *DNCHandler) ImportDNC(c *gin.Context) {
+	defaultRegion := c.PostForm("default_region")
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CSV file is required (form field 'file')"})
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	phoneCol, reasonCol := 0, -1
+	line := 0
+	var added, existing int
+	invalid := []gin.H{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			invalid = append(invalid, gin.H{"line": line, "error": err.Error()})
+			continue
+		}
+		if len(record) == 0 {
+			continue
+		}
+
+		// Detect a header row on the first line
+		if line == 1 {
+			if col, reason, ok := dncHeaderColumns(record); ok {
+				phoneCol, reasonCol = col, reason
+				continue
+			}
+		}
+
+		if phoneCol >= len(record) {
+			invalid = append(invalid, gin.H{"line": line, "error": "missing phone number column"})
+			continue
+		}
+
+		phone, err := services.ParseDNCNumber(record[phoneCol], defaultRegion)
+		if err != nil {
+			invalid = append(invalid, gin.H{"line": line, "value": record[phoneCol], "error": err.Error()})
+			continue
+		}
+
+		entry := models.DNCEntry{
+			PhoneNumber: phone,
+			Source:      "import",
+		}
+		if reasonCol >= 0 && reasonCol < len(record) {
+			entry.Reason = record[reasonCol]
+		}
+
+		created, err := h.dncService.Add(ctx, entry)
+		if err != nil {
+			invalid = append(invalid, gin.H{"line": line, "value": record[phoneCol], "error": err.Error()})
+			continue
+		}
+		if created {
+			added++
+		} else {
+			existing++
+		}
+	}
+
+	log.Printf("✓ Do-not-call import finished - added: %d, already listed: %d, invalid: %d", added, existing, len(invalid))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        fmt.Sprintf("Imported %d phone numbers", added),
+		"added_count":    added,
+		"existing_count": existing,
+		"invalid_count":  len(invalid),
+		"invalid_rows":   invalid,
+	})
+}
+
+// dncHeaderColumns returns the phone and reason column indexes if record is a header row
+func dncHeaderColumns(record []string) (int, int, bool) {
+	phoneCol, reasonCol := -1, -1
+	for i, name := range record {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "phone_number", "phone", "number", "phonenumber":
+			phoneCol = i
+		case "reason":
+			reasonCol = i
+		}
+	}
+	if phoneCol < 0 {
+		return 0, -1, false
+	}
+	return phoneCol, reasonCol, true
+}
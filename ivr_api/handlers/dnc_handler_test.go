@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestAddDNC(t *testing.T) {
+	env := newTestEnv(t)
+
+	tests := []struct {
+		name       string
+		body       gin.H
+		wantStatus int
+		wantPhone  string
+	}{
+		{"international", gin.H{"phone_number": "+1 (415) 555-0123"}, http.StatusCreated, "+14155550123"},
+		{"national with region", gin.H{"phone_number": "(415) 555-0124", "default_region": "US"}, http.StatusCreated, "+14155550124"},
+		{"already listed", gin.H{"phone_number": "+14155550123"}, http.StatusOK, "+14155550123"},
+		{"too short", gin.H{"phone_number": "12"}, http.StatusBadRequest, ""},
+		{"national without region", gin.H{"phone_number": "4155550125"}, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := env.postJSON(t, "/api/dnc", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("AddDNC = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantPhone == "" {
+				return
+			}
+			var entry struct {
+				PhoneNumber string `json:"phone_number"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &entry); err != nil || entry.PhoneNumber != tt.wantPhone {
+				t.Errorf("phone_number = %q (%v), want %s", entry.PhoneNumber, err, tt.wantPhone)
+			}
+		})
+	}
+
+	count, err := env.db.Collection("dnc").CountDocuments(env.ctx, bson.M{})
+	if err != nil || count != 2 {
+		t.Errorf("do-not-call entries = %d (%v), want 2", count, err)
+	}
+}
+
+func TestImportDNCUsesTheAddRule(t *testing.T) {
+	env := newTestEnv(t)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, err := form.CreateFormFile("file", "dnc.csv")
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	file.Write([]byte("phone_number,reason\n+14155550123,asked\n12,typo\n4155550125,no region\n"))
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/dnc/import", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("ImportDNC = %d, want 200: %s", w.Code, w.Body.String())
+	}
+
+	var result struct {
+		AddedCount  int `json:"added_count"`
+		InvalidRows []struct {
+			Line int `json:"line"`
+		} `json:"invalid_rows"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if result.AddedCount != 1 || len(result.InvalidRows) != 2 || result.InvalidRows[0].Line != 3 || result.InvalidRows[1].Line != 4 {
+		t.Errorf("import = %+v, want 1 added and lines 3 and 4 invalid", result)
+	}
+}
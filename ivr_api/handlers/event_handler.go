@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// eventKeepAlive is how often a comment is sent on idle streams so proxies keep them open
+const eventKeepAlive = 15 * time.Second
+
+type EventHandler struct {
+	db       *database.MongoDB
+	eventBus *services.EventBus
+}
+
+func NewEventHandler(db *database.MongoDB, eventBus *services.EventBus) *EventHandler {
+	return &EventHandler{
+		db:       db,
+		eventBus: eventBus,
+	}
+}
+
+// StreamCampaignEvents streams the call events of a campaign as Server-Sent Events.
+// Each event is named after its type (initiated, ringing, answered, digit_pressed,
+// completed, ...) and carries a models.CallEvent as JSON data.
+func (h *EventHandler) StreamCampaignEvents(c *gin.Context) {
+	campaignID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	count, err := h.db.Collection("campaigns").CountDocuments(ctx, bson.M{"_id": campaignID})
+	cancel()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve campaign"})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	events, unsubscribe := h.eventBus.Subscribe(campaignID)
+	defer unsubscribe()
+
+	log.Printf("Event stream opened for campaign %s", campaignID.Hex())
+	defer log.Printf("Event stream closed for campaign %s", campaignID.Hex())
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // disable nginx response buffering
+
+	c.SSEvent("connected", gin.H{"campaign_id": campaignID})
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+		case <-keepAlive.C:
+			io.WriteString(w, ": keep-alive\n\n")
+		}
+		return true
+	})
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prabhatkumar/ivrcalling/config"
+	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/models"
+	"github.com/prabhatkumar/ivrcalling/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The handler tests run the webhooks against the in-memory database and the
+// fake telephony provider; every test gets its own database.
+
+type testEnv struct {
+	ctx       context.Context
+	db        *database.MongoDB
+	provider  *services.FakeProvider
+	dncs      *services.DNCService
+	scheduler *services.CallScheduler
+	router    *gin.Engine
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	db := database.NewMemoryDB()
+
+	// Background services stop when the test ends
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	cfg := &config.Config{DialCallsPerSecond: 50, DialWorkers: 2, DialMaxLiveCalls: 10}
+	provider := services.NewFakeProvider("+14155550000")
+	eventBus := services.NewEventBus()
+	dncService := services.NewDNCService(db)
+	dialer := services.NewDialer(db, provider, dncService, eventBus)
+	dialQueue := services.NewDialQueue(db, dialer, cfg)
+	dialQueue.Start(ctx)
+	businessHours := services.NewBusinessHoursService(db)
+	inbound := services.NewInboundService(db, eventBus)
+
+	callHandler := NewCallHandler(db, dncService, dialer, dialQueue, eventBus)
+	webhookHandler := NewWebhookHandler(db, dncService, eventBus, dialer, provider, businessHours, inbound)
+	dncHandler := NewDNCHandler(db, dncService)
+	campaignHandler := NewCampaignHandler(db, businessHours, inbound)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.PUT("/api/campaigns/:id", campaignHandler.UpdateCampaign)
+	router.POST("/api/calls/bulk", callHandler.InitiateBulkCalls)
+	router.POST("/api/dnc", dncHandler.AddDNC)
+	router.POST("/api/dnc/import", dncHandler.ImportDNC)
+	webhook := router.Group("/api/webhook")
+	{
+		webhook.POST("/voice", webhookHandler.HandleVoiceWebhook)
+		webhook.POST("/gather", webhookHandler.HandleGatherWebhook)
+		webhook.POST("/collect", webhookHandler.HandleCollectWebhook)
+		webhook.POST("/record", webhookHandler.HandleRecordWebhook)
+		webhook.POST("/forward", webhookHandler.HandleForwardWebhook)
+		webhook.POST("/status", callHandler.HandleStatusWebhook)
+	}
+
+	return &testEnv{
+		ctx:       ctx,
+		db:        db,
+		provider:  provider,
+		dncs:      dncService,
+		scheduler: services.NewCallScheduler(db, dialQueue, dialer, dncService, eventBus),
+		router:    router,
+	}
+}
+
+func (e *testEnv) postJSON(t *testing.T, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	return e.sendJSON(t, http.MethodPost, path, body)
+}
+
+func (e *testEnv) sendJSON(t *testing.T, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	payload, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("failed to encode request: %v", err)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	e.router.ServeHTTP(w, req)
+	return w
+}
+
+// postForm posts a webhook the way Twilio does
+func (e *testEnv) postForm(t *testing.T, path string, form url.Values) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	e.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST %s = %d, want 200: %s", path, w.Code, w.Body.String())
+	}
+	return compactXML(w.Body.String())
+}
+
+func (e *testEnv) insertCampaign(t *testing.T, campaign models.Campaign) primitive.ObjectID {
+	t.Helper()
+	campaign.IsActive = true
+	campaign.CreatedAt = time.Now()
+	campaign.UpdatedAt = time.Now()
+	result, err := e.db.Collection("campaigns").InsertOne(e.ctx, campaign)
+	if err != nil {
+		t.Fatalf("failed to insert campaign: %v", err)
+	}
+	return result.InsertedID.(primitive.ObjectID)
+}
+
+func (e *testEnv) insertCall(t *testing.T, call models.Call) primitive.ObjectID {
+	t.Helper()
+	call.CreatedAt = time.Now()
+	call.UpdatedAt = time.Now()
+	result, err := e.db.Collection("calls").InsertOne(e.ctx, call)
+	if err != nil {
+		t.Fatalf("failed to insert call: %v", err)
+	}
+	return result.InsertedID.(primitive.ObjectID)
+}
+
+func (e *testEnv) findCall(t *testing.T, filter bson.M) models.Call {
+	t.Helper()
+	var call models.Call
+	if err := e.db.Collection("calls").FindOne(e.ctx, filter).Decode(&call); err != nil {
+		t.Fatalf("failed to find call %v: %v", filter, err)
+	}
+	return call
+}
+
+func (e *testEnv) logEvents(t *testing.T, callID primitive.ObjectID) []string {
+	t.Helper()
+	cursor, err := e.db.Collection("call_logs").Find(e.ctx, bson.M{"call_id": callID})
+	if err != nil {
+		t.Fatalf("failed to load call logs: %v", err)
+	}
+	var logs []models.CallLog
+	if err := cursor.All(e.ctx, &logs); err != nil {
+		t.Fatalf("failed to decode call logs: %v", err)
+	}
+	events := make([]string, 0, len(logs))
+	for _, callLog := range logs {
+		events = append(events, callLog.Event)
+	}
+	return events
+}
+
+// waitFor polls until done reports true, for work done by the dial queue and scheduler
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// compactXML drops the indentation of a TwiML document
+func compactXML(document string) string {
+	lines := strings.Split(document, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, "")
+}
+
+func contains(events []string, event string) bool {
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
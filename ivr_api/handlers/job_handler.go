@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type JobHandler struct {
+	db *database.MongoDB
+}
+
+func NewJobHandler(db *database.MongoDB) *JobHandler {
+	return &JobHandler{db: db}
+}
+
+// GetDialJob reports the progress of a bulk dial job.
+// Per-contact results are included when called with ?contacts=true.
+func (h *JobHandler) GetDialJob(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.FindOne()
+	if c.Query("contacts") != "true" {
+		opts.SetProjection(bson.M{"contacts": 0})
+	}
+
+	var job models.DialJob
+	err = h.db.Collection("dial_jobs").FindOne(ctx, bson.M{"_id": objID}, opts).Decode(&job)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	progress := 100.0
+	if job.Total > 0 {
+		progress = float64(job.Processed) * 100 / float64(job.Total)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job":      job,
+		"progress": progress,
+	})
+}
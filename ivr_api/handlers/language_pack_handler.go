@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/middleware"
+	"github.com/prabhatkumar/ivrcalling/models"
+	"github.com/prabhatkumar/ivrcalling/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type LanguagePackHandler struct {
+	db            *database.MongoDB
+	languagePacks *services.LanguagePackService
+}
+
+func NewLanguagePackHandler(db *database.MongoDB, languagePacks *services.LanguagePackService) *LanguagePackHandler {
+	return &LanguagePackHandler{
+		db:            db,
+		languagePacks: languagePacks,
+	}
+}
+
+// ListLanguagePacks retrieves all language packs
+func (h *LanguagePackHandler) ListLanguagePacks(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := h.db.Collection("language_packs").Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"code": 1}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve language packs"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var packs []models.LanguagePack
+	if err = cursor.All(ctx, &packs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode language packs"})
+		return
+	}
+
+	if packs == nil {
+		packs = []models.LanguagePack{}
+	}
+
+	c.JSON(http.StatusOK, packs)
+}
+
+// GetLanguagePack retrieves a language pack along with the fallback chain its code resolves through
+func (h *LanguagePackHandler) GetLanguagePack(c *gin.Context) {
+	code := services.CanonicalLanguageCode(c.Param("code"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var pack models.LanguagePack
+	if err := h.db.Collection("language_packs").FindOne(ctx, bson.M{"code": code}).Decode(&pack); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Language pack not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"language_pack":  pack,
+		"fallback_chain": services.LanguageChain(code),
+	})
+}
+
+// CreateLanguagePack adds a language. Every message key must have a text.
+func (h *LanguagePackHandler) CreateLanguagePack(c *gin.Context) {
+	var pack models.LanguagePack
+	if err := c.ShouldBindJSON(&pack); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validateLanguagePack(&pack); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pack.UpdatedBy = middleware.CurrentSubject(c)
+	pack.CreatedAt = time.Now()
+	pack.UpdatedAt = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := h.db.Collection("language_packs").InsertOne(ctx, pack)
+	if mongo.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Language pack '%s' already exists", pack.Code)})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to save language pack: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save language pack"})
+		return
+	}
+	pack.ID = result.InsertedID.(primitive.ObjectID)
+
+	h.reload(ctx)
+	log.Printf("✓ Language pack %s (%s) created", pack.Code, pack.Name)
+	c.JSON(http.StatusCreated, pack)
+}
+
+// UpdateLanguagePack replaces the name, fallback and strings of a language pack
+func (h *LanguagePackHandler) UpdateLanguagePack(c *gin.Context) {
+	code := services.CanonicalLanguageCode(c.Param("code"))
+
+	var pack models.LanguagePack
+	if err := c.ShouldBindJSON(&pack); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The code identifies the pack and cannot be changed
+	pack.Code = code
+	if err := validateLanguagePack(&pack); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var updated models.LanguagePack
+	err := h.db.Collection("language_packs").FindOneAndUpdate(
+		ctx,
+		bson.M{"code": code},
+		bson.M{"$set": bson.M{
+			"name":       pack.Name,
+			"fallback":   pack.Fallback,
+			"strings":    pack.Strings,
+			"updated_by": middleware.CurrentSubject(c),
+			"updated_at": time.Now(),
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Language pack not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update language pack"})
+		return
+	}
+
+	h.reload(ctx)
+	log.Printf("✓ Language pack %s updated", code)
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteLanguagePack removes a language. Calls in that language then use its fallback chain.
+func (h *LanguagePackHandler) DeleteLanguagePack(c *gin.Context) {
+	code := services.CanonicalLanguageCode(c.Param("code"))
+	if code == services.DefaultLanguage {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The default language pack cannot be deleted"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Packs that fall back to this one would silently skip it
+	if err := h.db.Collection("language_packs").FindOne(ctx, bson.M{"fallback": code}).Err(); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Other language packs fall back to this one"})
+		return
+	}
+
+	result, err := h.db.Collection("language_packs").DeleteOne(ctx, bson.M{"code": code})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete language pack"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Language pack not found"})
+		return
+	}
+
+	h.reload(ctx)
+	log.Printf("✓ Language pack %s deleted", code)
+	c.JSON(http.StatusOK, gin.H{"message": "Language pack deleted successfully"})
+}
+
+// reload refreshes the language cache so the change is heard on the next call
+func (h *LanguagePackHandler) reload(ctx context.Context) {
+	if err := h.languagePacks.Reload(ctx); err != nil {
+		log.Printf("✗ Failed to reload language packs: %v", err)
+	}
+}
+
+// validateLanguagePack checks a pack and that its fallback is an existing
+// pack whose chain does not lead back to it
+func validateLanguagePack(pack *models.LanguagePack) error {
+	if err := services.ValidateLanguagePack(pack); err != nil {
+		return err
+	}
+	if pack.Fallback == "" {
+		return nil
+	}
+
+	if !isSupportedLanguage(pack.Fallback) {
+		return fmt.Errorf("fallback language pack '%s' does not exist", pack.Fallback)
+	}
+	for _, code := range services.LanguageChain(pack.Fallback) {
+		if code == pack.Code {
+			return fmt.Errorf("fallback '%s' leads back to '%s'", pack.Fallback, pack.Code)
+		}
+	}
+	return nil
+}
+
+func isSupportedLanguage(code string) bool {
+	for _, supported := range services.GetSupportedLanguages() {
+		if supported == code {
+			return true
+		}
+	}
+	return false
+}
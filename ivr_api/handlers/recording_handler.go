@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/models"
+	"github.com/prabhatkumar/ivrcalling/services"
+	"github.com/prabhatkumar/ivrshared/twiml"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RecordingHandler struct {
+	db               *database.MongoDB
+	recordingService *services.RecordingService
+}
+
+func NewRecordingHandler(db *database.MongoDB, recordingService *services.RecordingService) *RecordingHandler {
+	return &RecordingHandler{
+		db:               db,
+		recordingService: recordingService,
+	}
+}
+
+// ListRecordings retrieves recordings, newest first. Optional query
+// parameters: campaign_id, call_id, limit and skip.
+func (h *RecordingHandler) ListRecordings(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return
+	}
+	skip, err := strconv.Atoi(c.DefaultQuery("skip", "0"))
+	if err != nil || skip < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "skip must be a non-negative number"})
+		return
+	}
+
+	filter := bson.M{}
+	for _, name := range []string{"campaign_id", "call_id"} {
+		if value := c.Query(name); value != "" {
+			objID, err := primitive.ObjectIDFromHex(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+				return
+			}
+			filter[name] = objID
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := h.db.Collection("recordings").Find(
+		ctx,
+		filter,
+		options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(int64(limit)).SetSkip(int64(skip)),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve recordings"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var recordings []models.Recording
+	if err = cursor.All(ctx, &recordings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode recordings"})
+		return
+	}
+
+	if recordings == nil {
+		recordings = []models.Recording{}
+	}
+
+	total, _ := h.db.Collection("recordings").CountDocuments(ctx, filter)
+
+	c.JSON(http.StatusOK, gin.H{
+		"recordings": recordings,
+		"total":      total,
+	})
+}
+
+// GetRecording retrieves the metadata of a recording
+func (h *RecordingHandler) GetRecording(c *gin.Context) {
+	recording, ok := h.findRecording(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, recording)
+}
+
+// StreamRecording proxies the audio of a recording from the telephony
+// provider, so clients never need provider credentials
+func (h *RecordingHandler) StreamRecording(c *gin.Context) {
+	recording, ok := h.findRecording(c)
+	if !ok {
+		return
+	}
+
+	if recording.Status != "completed" {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Recording is %s", recording.Status)})
+		return
+	}
+
+	audio, contentType, err := h.recordingService.Media(c.Request.Context(), recording)
+	if errors.Is(err, services.ErrRecordingNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recording no longer exists at the telephony provider"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch recording %s: %v", recording.RecordingSID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch recording from the telephony provider"})
+		return
+	}
+	defer audio.Close()
+
+	if contentType == "" {
+		contentType = "audio/mpeg"
+	}
+	c.DataFromReader(http.StatusOK, -1, contentType, audio, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`inline; filename="%s.mp3"`, recording.RecordingSID),
+		"Cache-Control":       "no-store",
+	})
+}
+
+// DeleteRecording deletes a recording from the telephony provider and the database
+func (h *RecordingHandler) DeleteRecording(c *gin.Context) {
+	recording, ok := h.findRecording(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := h.recordingService.Delete(ctx, recording); err != nil {
+		log.Printf("Failed to delete recording %s: %v", recording.RecordingSID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to delete recording"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Recording deleted successfully"})
+}
+
+// HandleRecordingWebhook stores the recording metadata reported by Twilio's
+// recording status callbacks against the call
+func (h *RecordingHandler) HandleRecordingWebhook(c *gin.Context) {
+	var update models.RecordingStatusUpdate
+	if err := c.ShouldBind(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("=== RECORDING WEBHOOK CALLED ===")
+	log.Printf("CallSid: %s, RecordingSid: %s, Status: %s, Source: %s",
+		update.CallSid, update.RecordingSid, update.RecordingStatus, update.RecordingSource)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	recording, err := h.recordingService.Store(ctx, update)
+	if err != nil {
+		log.Printf("✗ Failed to store recording %s: %v", update.RecordingSid, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Call not found"})
+		return
+	}
+
+	if recording.Status == "completed" || recording.Status == "failed" || recording.Status == "absent" {
+		h.db.Collection("call_logs").InsertOne(ctx, models.CallLog{
+			CallID:    recording.CallID,
+			Event:     "recording_" + recording.Status,
+			Details:   fmt.Sprintf("Recording %s (%s, %d seconds)", recording.RecordingSID, recording.Source, recording.Duration),
+			CreatedAt: time.Now(),
+		})
+	}
+
+	writeTwiML(c, twiml.NewResponse().String())
+}
+
+// findRecording loads the recording named by the :id parameter, writing the error response if it cannot
+func (h *RecordingHandler) findRecording(c *gin.Context) (*models.Recording, bool) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recording ID"})
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var recording models.Recording
+	if err := h.db.Collection("recordings").FindOne(ctx, bson.M{"_id": objID}).Decode(&recording); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recording not found"})
+		return nil, false
+	}
+	return &recording, true
+}
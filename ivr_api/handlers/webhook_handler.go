@@ -2,34 +2,53 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prabhatkumar/ivrcalling/database"
 	"github.com/prabhatkumar/ivrcalling/models"
 	"github.com/prabhatkumar/ivrcalling/services"
+	"github.com/prabhatkumar/ivrshared/twiml"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type WebhookHandler struct {
-	db *database.MongoDB
+	db            *database.MongoDB
+	dncService    *services.DNCService
+	eventBus      *services.EventBus
+	dialer        *services.Dialer
+	provider      services.TelephonyProvider
+	businessHours *services.BusinessHoursService
+	inbound       *services.InboundService
 }
 
-func NewWebhookHandler(db *database.MongoDB) *WebhookHandler {
-	return &WebhookHandler{db: db}
+func NewWebhookHandler(db *database.MongoDB, dncService *services.DNCService, eventBus *services.EventBus, dialer *services.Dialer, provider services.TelephonyProvider, businessHours *services.BusinessHoursService, inbound *services.InboundService) *WebhookHandler {
+	return &WebhookHandler{
+		db:            db,
+		dncService:    dncService,
+		eventBus:      eventBus,
+		dialer:        dialer,
+		provider:      provider,
+		businessHours: businessHours,
+		inbound:       inbound,
+	}
 }
 
-// HandleVoiceWebhook handles initial voice webhook from Twilio
+// HandleVoiceWebhook handles initial voice webhook from Twilio. Outbound calls
+// carry their call_id; calls without one are inbound calls to a campaign number.
 func (h *WebhookHandler) HandleVoiceWebhook(c *gin.Context) {
 	callIDStr := c.Query("call_id")
 	language := c.Query("language")
+	answeredBy := c.PostForm("AnsweredBy")
 
 	log.Printf("=== VOICE WEBHOOK CALLED ===")
-	log.Printf("Call ID: %s, Language: %s", callIDStr, language)
+	log.Printf("Call ID: %s, Language: %s, Answered by: %s", callIDStr, language, answeredBy)
 
 	if language == "" {
 		language = "en"
@@ -37,8 +56,11 @@ func (h *WebhookHandler) HandleVoiceWebhook(c *gin.Context) {
 
 	// Get call details
 	var customerName string
+	var callID primitive.ObjectID
+	var call models.Call
 	var campaign models.Campaign
 	useDynamicIVR := false
+	startRecording := false // inbound calls have no recording yet when first answered
 
 	if callIDStr != "" {
 		callObjID, err := primitive.ObjectIDFromHex(callIDStr)
@@ -46,10 +68,10 @@ func (h *WebhookHandler) HandleVoiceWebhook(c *gin.Context) {
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
 
-			var call models.Call
 			err = h.db.Collection("calls").FindOne(ctx, bson.M{"_id": callObjID}).Decode(&call)
 			if err == nil {
 				customerName = call.CustomerName
+				callID = call.ID
 				log.Printf("Found call record - Customer: %s, Campaign ID: %s", customerName, call.CampaignID.Hex())
 
 				// Get campaign details for dynamic IVR
@@ -76,24 +98,72 @@ func (h *WebhookHandler) HandleVoiceWebhook(c *gin.Context) {
 			log.Printf("✗ Invalid call ID format: %s", callIDStr)
 		}
 	} else {
-		log.Printf("✗ No call_id provided in webhook")
+		log.Printf("No call_id provided - inbound call from %s to %s", c.PostForm("From"), c.PostForm("To"))
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		inboundCall, inboundCampaign, created, err := h.inbound.Answer(ctx, c.PostForm("CallSid"), c.PostForm("From"), c.PostForm("To"))
+		if err == nil {
+			call, campaign = *inboundCall, *inboundCampaign
+			startRecording = created
+			customerName = call.CustomerName
+			callID = call.ID
+			language = call.Language
+			useDynamicIVR = campaign.IntroText != "" || len(campaign.Actions) > 0
+			log.Printf("✓ Inbound call %s - Campaign: %s, Caller: '%s', Language: %s", callID.Hex(), campaign.Name, customerName, language)
+		} else if errors.Is(err, services.ErrNoInboundCampaign) {
+			log.Printf("✗ No campaign answers %s - using legacy IVR", c.PostForm("To"))
+		} else {
+			log.Printf("✗ Failed to answer inbound call: %v", err)
+		}
 	}
 
 	// Generate TwiML response
-	generator := services.NewTwiMLGenerator(language)
-	var twiml string
+	generator := services.NewTwiMLGenerator(language).
+		WithVoice(campaign.Voice).
+		WithSpeechInput(campaign.SpeechInput).
+		WithBusinessHours(h.openCalendars(&campaign)).
+		WithVariables(services.CallVariables(&call))
+	var response string
+
+	// Without async detection Twilio waits for the result before asking for TwiML
+	if !callID.IsZero() && answeredBy != "" && services.MachineDetectionEnabled(campaign.MachineDetection) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if result := h.dialer.RecordAnsweredBy(ctx, &call, answeredBy); result == services.AnsweredByMachine || result == services.AnsweredByFax {
+			log.Printf("Call answered by %s - skipping the IVR", answeredBy)
+			writeTwiML(c, h.machineResponse(generator, &call, &campaign, answeredBy))
+			return
+		}
+	}
+
+	// Whole-call recording of outbound calls started when the call was
+	// answered; inbound calls start it from their first TwiML
+	if services.RecordsWholeCall(campaign.Recording) {
+		generator.WithConsent(campaign.Recording.ConsentMessage)
+		if startRecording {
+			generator.WithRecordingStart(campaign.Recording)
+		}
+	}
 
 	if useDynamicIVR {
 		log.Printf("Generating dynamic welcome TwiML...")
-		twiml = generator.GenerateDynamicWelcome(customerName, &campaign)
+		// Every call starts at the root of the menu tree
+		h.setMenuPath(callID, nil)
+		response = generator.GenerateDynamicWelcome(customerName, &campaign)
 	} else {
 		log.Printf("Generating legacy welcome TwiML...")
-		twiml = generator.GenerateWelcome(customerName)
+		response = generator.GenerateWelcome(customerName)
 	}
 
-	log.Printf("Sending TwiML response (length: %d bytes)", len(twiml))
-	c.Header("Content-Type", "text/xml")
-	c.String(http.StatusOK, twiml)
+	// The caller answered and is hearing the menu
+	if !callID.IsZero() {
+		h.createCallLog(callID, "menu_played", "Welcome message and menu played", "")
+	}
+
+	log.Printf("Sending TwiML response (length: %d bytes)", len(response))
+	writeTwiML(c, response)
 }
 
 // HandleGatherWebhook handles digit gathering from IVR menu
@@ -105,7 +175,8 @@ func (h *WebhookHandler) HandleGatherWebhook(c *gin.Context) {
 	}
 
 	log.Printf("=== GATHER WEBHOOK CALLED ===")
-	log.Printf("CallSid: %s, Digits pressed: %s", input.CallSid, input.Digits)
+	log.Printf("CallSid: %s, Digits pressed: %s, Speech: '%s' (confidence %.2f)",
+		input.CallSid, input.Digits, input.SpeechResult, input.Confidence)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -121,15 +192,49 @@ func (h *WebhookHandler) HandleGatherWebhook(c *gin.Context) {
 		language = call.Language
 		log.Printf("Found call - ID: %s, Campaign ID: %s", call.ID.Hex(), call.CampaignID.Hex())
 
-		// Log user input
-		callLog := models.CallLog{
-			CallID:    call.ID,
-			Event:     "input_received",
-			UserInput: input.Digits,
-			Details:   fmt.Sprintf("User pressed: %s", input.Digits),
-			CreatedAt: time.Now(),
+		// Log user input (a Redirect after a Gather timeout arrives without digits)
+		if input.Digits != "" {
+			callLog := models.CallLog{
+				CallID:    call.ID,
+				Event:     "input_received",
+				UserInput: input.Digits,
+				Details:   fmt.Sprintf("User pressed: %s", input.Digits),
+				CreatedAt: time.Now(),
+			}
+			h.db.Collection("call_logs").InsertOne(ctx, callLog)
+
+			h.eventBus.Publish(models.CallEvent{
+				Type:        "digit_pressed",
+				CampaignID:  call.CampaignID,
+				CallID:      call.ID,
+				PhoneNumber: call.PhoneNumber,
+				Status:      call.Status,
+				Digits:      input.Digits,
+				Details:     callLog.Details,
+			})
+		}
+
+		// Log what the caller said, whether or not it matches an option
+		if input.Digits == "" && input.SpeechResult != "" {
+			callLog := models.CallLog{
+				CallID:     call.ID,
+				Event:      "speech_received",
+				Transcript: input.SpeechResult,
+				Confidence: input.Confidence,
+				Details:    fmt.Sprintf("User said: '%s' (confidence %.2f)", input.SpeechResult, input.Confidence),
+				CreatedAt:  time.Now(),
+			}
+			h.db.Collection("call_logs").InsertOne(ctx, callLog)
+
+			h.eventBus.Publish(models.CallEvent{
+				Type:        "speech_received",
+				CampaignID:  call.CampaignID,
+				CallID:      call.ID,
+				PhoneNumber: call.PhoneNumber,
+				Status:      call.Status,
+				Details:     callLog.Details,
+			})
 		}
-		h.db.Collection("call_logs").InsertOne(ctx, callLog)
 
 		// Get campaign for dynamic IVR
 		err = h.db.Collection("campaigns").FindOne(ctx, bson.M{"_id": call.CampaignID}).Decode(&campaign)
@@ -141,48 +246,98 @@ func (h *WebhookHandler) HandleGatherWebhook(c *gin.Context) {
 		log.Printf("✗ Failed to find call by SID: %v", err)
 	}
 
-	generator := services.NewTwiMLGenerator(language)
-	var twiml string
+	open := h.openCalendars(&campaign)
+	generator := services.NewTwiMLGenerator(language).
+		WithVoice(campaign.Voice).
+		WithSpeechInput(campaign.SpeechInput).
+		WithRecording(campaign.Recording).
+		WithBusinessHours(open).
+		WithVariables(services.CallVariables(&call))
+	var response string
 
 	if useDynamicIVR {
-		log.Printf("Processing dynamic IVR input: %s", input.Digits)
-		// Handle dynamic IVR based on campaign actions
-		if input.Digits == "0" {
-			// Repeat menu
-			log.Printf("User pressed 0 - repeating menu")
-			twiml = generator.GenerateDynamicWelcome("", &campaign)
-		} else if len(campaign.Actions) == 0 {
-			// Campaign has intro_text but no actions - just repeat the intro
-			log.Printf("No actions defined - repeating intro")
-			twiml = generator.GenerateDynamicWelcome("", &campaign)
+		log.Printf("Processing dynamic IVR input: %s (menu path: %v)", input.Digits, call.MenuPath)
+		// Resolve the menu level the caller is currently on
+		node, path := campaign.MenuAt(call.MenuPath)
+		depth := len(path)
+
+		// The key of the chosen option, pressed or spoken
+		choice, spoken := input.Digits, false
+		if choice == "" && input.SpeechResult != "" && services.SpeechEnabled(campaign.SpeechInput) {
+			choice, spoken = h.matchSpeech(&call, &campaign, node, &input), true
+		}
+
+		if choice == "0" {
+			if depth > 0 {
+				// Go back one level
+				path = path[:depth-1]
+				log.Printf("User pressed 0 - going back to menu path %v", path)
+				h.setMenuPath(call.ID, path)
+				node, _ = campaign.MenuAt(path)
+				response = h.renderMenu(generator, &campaign, node, len(path))
+			} else {
+				// Repeat menu
+				log.Printf("User pressed 0 - repeating menu")
+				response = generator.GenerateDynamicWelcome("", &campaign)
+			}
+		} else if len(node.Actions) == 0 {
+			// Menu level has no actions - just repeat it
+			log.Printf("No actions defined - repeating menu")
+			response = h.renderMenu(generator, &campaign, node, depth)
 		} else {
 			// Find matching action
 			var matchedAction *models.IVRAction
-			for i := range campaign.Actions {
-				log.Printf("Checking action %d: input='%s' vs pressed='%s'", i, campaign.Actions[i].ActionInput, input.Digits)
-				if campaign.Actions[i].ActionInput == input.Digits {
-					matchedAction = &campaign.Actions[i]
+			for i := range node.Actions {
+				log.Printf("Checking action %d: input='%s' vs pressed='%s'", i, node.Actions[i].ActionInput, choice)
+				if node.Actions[i].ActionInput == choice {
+					matchedAction = &node.Actions[i]
 					log.Printf("✓ MATCHED ACTION: Type=%s, Message=%s, Phone=%s",
 						matchedAction.ActionType, matchedAction.Message, matchedAction.ForwardPhone)
 					break
 				}
 			}
 
+			// Options closed by their business calendar run their after-hours action instead
+			if matchedAction != nil && !services.ActionAvailable(matchedAction, open) {
+				closed := matchedAction
+				alternative := services.AfterHoursAction(closed, node)
+				if alternative != nil && services.ActionAvailable(alternative, open) {
+					log.Printf("Action %s is closed - running after-hours action %s", closed.ActionInput, alternative.ActionInput)
+					h.createCallLog(call.ID, "after_hours",
+						fmt.Sprintf("Action %s is closed - running action %s instead", closed.ActionInput, alternative.ActionInput), choice)
+					matchedAction = alternative
+					choice = alternative.ActionInput
+				} else {
+					log.Printf("Action %s is closed - no after-hours action", closed.ActionInput)
+					h.createCallLog(call.ID, "after_hours", fmt.Sprintf("Action %s is closed", closed.ActionInput), choice)
+					matchedAction = nil
+					response = generator.GenerateAfterHours(closed, node, depth)
+				}
+			}
+
 			if matchedAction != nil {
+				// Entering a sub-menu moves the caller down one level
+				if matchedAction.ActionType == "menu" && matchedAction.SubMenu != nil {
+					h.setMenuPath(call.ID, append(path, choice))
+				}
+
 				// Execute the matched action
 				log.Printf("Executing action: %s", matchedAction.ActionType)
-				twiml = generator.GenerateDynamicResponse(matchedAction, &campaign)
+				response = generator.GenerateDynamicResponse(matchedAction, node, depth)
 
 				// Log action execution
 				if !call.ID.IsZero() {
 					eventType := fmt.Sprintf("action_%s_executed", matchedAction.ActionType)
-					details := fmt.Sprintf("User pressed %s - Action type: %s", input.Digits, matchedAction.ActionType)
-					h.createCallLog(call.ID, eventType, details)
+					details := fmt.Sprintf("User pressed %s - Action type: %s", choice, matchedAction.ActionType)
+					if spoken {
+						details = fmt.Sprintf("User said '%s' - Action type: %s", input.SpeechResult, matchedAction.ActionType)
+					}
+					h.createCallLog(call.ID, eventType, details, choice)
 				}
-			} else {
-				// Invalid input - repeat the menu
-				log.Printf("✗ No matching action found for input: %s - repeating menu", input.Digits)
-				twiml = generator.GenerateDynamicWelcome("", &campaign)
+			} else if response == "" {
+				// Invalid or no input - repeat the current menu
+				log.Printf("✗ No matching action found for input: '%s' - repeating menu", choice)
+				response = h.renderMenu(generator, &campaign, node, depth)
 			}
 		}
 	} else {
@@ -190,36 +345,478 @@ func (h *WebhookHandler) HandleGatherWebhook(c *gin.Context) {
 		switch input.Digits {
 		case "1":
 			// Product information
-			twiml = generator.GenerateProductInfo()
+			response = generator.GenerateProductInfo()
 			if !call.ID.IsZero() {
-				h.createCallLog(call.ID, "product_info_requested", "User requested product information")
+				h.createCallLog(call.ID, "product_info_requested", "User requested product information", input.Digits)
 			}
 		case "2":
 			// Special offers
-			twiml = generator.GenerateOfferDetails()
+			response = generator.GenerateOfferDetails()
 			if !call.ID.IsZero() {
-				h.createCallLog(call.ID, "offer_requested", "User requested offer details")
+				h.createCallLog(call.ID, "offer_requested", "User requested offer details", input.Digits)
 			}
 		case "3":
 			// Opt out
-			twiml = generator.GenerateOptOut()
+			response = generator.GenerateOptOut()
 			if !call.ID.IsZero() {
-				h.createCallLog(call.ID, "opt_out_requested", "User requested to opt out")
+				h.createCallLog(call.ID, "opt_out_requested", "User requested to opt out", input.Digits)
 			}
 		case "0":
 			// Return to main menu
-			twiml = generator.GenerateMainMenu()
+			response = generator.GenerateMainMenu()
 		case "9":
 			// Repeat menu
-			twiml = generator.GenerateMainMenu()
+			response = generator.GenerateMainMenu()
 		default:
 			// Invalid input
-			twiml = generator.GenerateInvalidInput()
+			response = generator.GenerateInvalidInput()
+		}
+	}
+
+	writeTwiML(c, response)
+}
+
+// HandleCollectWebhook receives the digits keyed in for a "collect" action.
+// A valid value is stored on the call and the menu is offered again; an
+// invalid one re-prompts until the action's retries are used up.
+func (h *WebhookHandler) HandleCollectWebhook(c *gin.Context) {
+	var input models.IVRInput
+	if err := c.ShouldBind(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key := c.Query("key")
+	attempt, _ := strconv.Atoi(c.Query("attempt"))
+
+	log.Printf("=== COLLECT WEBHOOK CALLED ===")
+	log.Printf("CallSid: %s, Action: %s, Attempt: %d", input.CallSid, key, attempt)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var call models.Call
+	if err := h.db.Collection("calls").FindOne(ctx, bson.M{"twilio_call_sid": input.CallSid}).Decode(&call); err != nil {
+		log.Printf("✗ Failed to find call by SID: %v", err)
+		writeTwiML(c, services.NewTwiMLGenerator("en").GenerateGoodbye())
+		return
+	}
+
+	var campaign models.Campaign
+	if err := h.db.Collection("campaigns").FindOne(ctx, bson.M{"_id": call.CampaignID}).Decode(&campaign); err != nil {
+		log.Printf("✗ Failed to find campaign %s: %v", call.CampaignID.Hex(), err)
+		writeTwiML(c, services.NewTwiMLGenerator(call.Language).GenerateGoodbye())
+		return
+	}
+
+	generator := services.NewTwiMLGenerator(call.Language).
+		WithVoice(campaign.Voice).
+		WithSpeechInput(campaign.SpeechInput).
+		WithBusinessHours(h.openCalendars(&campaign)).
+		WithVariables(services.CallVariables(&call))
+
+	node, path := campaign.MenuAt(call.MenuPath)
+	action := node.FindAction(key)
+	if action == nil || action.ActionType != "collect" || action.Collect == nil {
+		// The campaign was edited mid-call
+		log.Printf("✗ No collect action '%s' on menu path %v - repeating menu", key, path)
+		writeTwiML(c, h.renderMenu(generator, &campaign, node, len(path)))
+		return
+	}
+	settings := action.Collect
+
+	if err := services.ValidateCollectedValue(settings, input.Digits); err != nil {
+		log.Printf("✗ Invalid value for '%s' (attempt %d): %v", settings.Name, attempt+1, err)
+		h.createCallLog(call.ID, "input_invalid", fmt.Sprintf("Invalid %s: %v", settings.Name, err),
+			services.DisplayCollectedValue(settings, input.Digits))
+
+		var response string
+		if attempt < services.CollectRetries(settings) {
+			response = generator.GenerateCollect(action, attempt+1)
+		} else {
+			h.createCallLog(call.ID, "collect_failed",
+				fmt.Sprintf("No valid %s after %d attempts", settings.Name, attempt+1), "")
+			response = generator.GenerateCollectFailed(node, len(path))
+		}
+		writeTwiML(c, response)
+		return
+	}
+
+	// Sensitive values never reach the database, the API or later messages
+	if services.StoresCollectedValue(settings) {
+		_, err := h.db.Collection("calls").UpdateOne(
+			ctx,
+			bson.M{"_id": call.ID},
+			bson.M{"$set": bson.M{"collected." + settings.Name: input.Digits, "updated_at": time.Now()}},
+		)
+		if err != nil {
+			log.Printf("✗ Failed to store collected value '%s': %v", settings.Name, err)
 		}
+		if call.Collected == nil {
+			call.Collected = map[string]string{}
+		}
+		call.Collected[settings.Name] = input.Digits
+	}
+
+	display := services.DisplayCollectedValue(settings, input.Digits)
+	details := fmt.Sprintf("Collected %s: %s", settings.Name, display)
+	h.createCallLog(call.ID, "input_collected", details, display)
+	h.eventBus.Publish(models.CallEvent{
+		Type:        "input_collected",
+		CampaignID:  call.CampaignID,
+		CallID:      call.ID,
+		PhoneNumber: call.PhoneNumber,
+		Status:      call.Status,
+		Details:     details,
+	})
+	services.NotifyCollected(settings, &call, input.Digits)
+	log.Printf("✓ Collected %s for call %s", settings.Name, call.ID.Hex())
+
+	// Later messages can use the value just collected
+	generator.WithVariables(services.CallVariables(&call))
+	writeTwiML(c, generator.GenerateCollected(settings, node, len(path)))
+}
+
+// HandleRecordWebhook receives the message left through a "record" action.
+// The recording is attached to the call and the menu is offered again; when
+// the caller said nothing the menu is simply repeated.
+func (h *WebhookHandler) HandleRecordWebhook(c *gin.Context) {
+	var update models.RecordingStatusUpdate
+	if err := c.ShouldBind(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key := c.Query("key")
+
+	log.Printf("=== RECORD WEBHOOK CALLED ===")
+	log.Printf("CallSid: %s, Action: %s, RecordingSid: %s, Duration: %s",
+		update.CallSid, key, update.RecordingSid, update.RecordingDuration)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var call models.Call
+	if err := h.db.Collection("calls").FindOne(ctx, bson.M{"twilio_call_sid": update.CallSid}).Decode(&call); err != nil {
+		log.Printf("✗ Failed to find call by SID: %v", err)
+		writeTwiML(c, services.NewTwiMLGenerator("en").GenerateGoodbye())
+		return
+	}
+
+	var campaign models.Campaign
+	if err := h.db.Collection("campaigns").FindOne(ctx, bson.M{"_id": call.CampaignID}).Decode(&campaign); err != nil {
+		log.Printf("✗ Failed to find campaign %s: %v", call.CampaignID.Hex(), err)
+		writeTwiML(c, services.NewTwiMLGenerator(call.Language).GenerateGoodbye())
+		return
 	}
 
-	c.Header("Content-Type", "text/xml")
-	c.String(http.StatusOK, twiml)
+	generator := services.NewTwiMLGenerator(call.Language).
+		WithVoice(campaign.Voice).
+		WithSpeechInput(campaign.SpeechInput).
+		WithBusinessHours(h.openCalendars(&campaign)).
+		WithVariables(services.CallVariables(&call))
+
+	node, path := campaign.MenuAt(call.MenuPath)
+	action := node.FindAction(key)
+	if action == nil || action.ActionType != "record" || action.Record == nil {
+		// The campaign was edited mid-call
+		log.Printf("✗ No record action '%s' on menu path %v - repeating menu", key, path)
+		writeTwiML(c, h.renderMenu(generator, &campaign, node, len(path)))
+		return
+	}
+
+	duration, _ := strconv.Atoi(update.RecordingDuration)
+	if update.RecordingURL == "" || duration == 0 {
+		log.Printf("✗ No message recorded for call %s - repeating menu", call.ID.Hex())
+		h.createCallLog(call.ID, "message_empty", "Caller did not leave a message", "")
+		writeTwiML(c, h.renderMenu(generator, &campaign, node, len(path)))
+		return
+	}
+
+	message := services.NewCallerMessage(action, update, duration)
+	message.CreatedAt = time.Now()
+	_, err := h.db.Collection("calls").UpdateOne(
+		ctx,
+		bson.M{"_id": call.ID},
+		bson.M{
+			"$push": bson.M{"messages": message},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		log.Printf("✗ Failed to store message %s: %v", message.RecordingSID, err)
+	}
+
+	details := services.RecordedMessageDetails(message)
+	h.createCallLog(call.ID, "message_recorded", details, key)
+	h.eventBus.Publish(models.CallEvent{
+		Type:        "message_recorded",
+		CampaignID:  call.CampaignID,
+		CallID:      call.ID,
+		PhoneNumber: call.PhoneNumber,
+		Status:      call.Status,
+		Details:     details,
+	})
+	log.Printf("✓ Stored message %s for call %s", message.RecordingSID, call.ID.Hex())
+
+	writeTwiML(c, generator.GenerateRecorded(action.Record, node, len(path)))
+}
+
+// HandleForwardWebhook receives the outcome of a forward action's <Dial> and
+// logs it. When nobody answered, the next number of a sequential ring group
+// is dialed, and once all were tried the action's fallback runs. Redirects
+// with fallback=1 run a fallback action after the unavailable message.
+func (h *WebhookHandler) HandleForwardWebhook(c *gin.Context) {
+	var update models.DialStatusUpdate
+	if err := c.ShouldBind(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key := c.Query("key")
+	leg, _ := strconv.Atoi(c.Query("leg"))
+	runFallback := c.Query("fallback") != ""
+
+	log.Printf("=== FORWARD WEBHOOK CALLED ===")
+	log.Printf("CallSid: %s, Action: %s, Leg: %d, DialCallStatus: %s, Fallback: %t",
+		update.CallSid, key, leg, update.DialCallStatus, runFallback)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var call models.Call
+	if err := h.db.Collection("calls").FindOne(ctx, bson.M{"twilio_call_sid": update.CallSid}).Decode(&call); err != nil {
+		log.Printf("✗ Failed to find call by SID: %v", err)
+		writeTwiML(c, services.NewTwiMLGenerator("en").GenerateGoodbye())
+		return
+	}
+
+	var campaign models.Campaign
+	if err := h.db.Collection("campaigns").FindOne(ctx, bson.M{"_id": call.CampaignID}).Decode(&campaign); err != nil {
+		log.Printf("✗ Failed to find campaign %s: %v", call.CampaignID.Hex(), err)
+		writeTwiML(c, services.NewTwiMLGenerator(call.Language).GenerateGoodbye())
+		return
+	}
+
+	generator := services.NewTwiMLGenerator(call.Language).
+		WithVoice(campaign.Voice).
+		WithSpeechInput(campaign.SpeechInput).
+		WithRecording(campaign.Recording).
+		WithBusinessHours(h.openCalendars(&campaign)).
+		WithVariables(services.CallVariables(&call))
+
+	node, path := campaign.MenuAt(call.MenuPath)
+	action := node.FindAction(key)
+	if action == nil || action.ActionType != "forward" {
+		// The campaign was edited mid-call
+		log.Printf("✗ No forward action '%s' on menu path %v - repeating menu", key, path)
+		writeTwiML(c, h.renderMenu(generator, &campaign, node, len(path)))
+		return
+	}
+
+	if runFallback {
+		fallback := node.FindAction(services.ForwardFallback(action))
+		if fallback == nil {
+			log.Printf("✗ Fallback action '%s' no longer exists - repeating menu", services.ForwardFallback(action))
+			writeTwiML(c, h.renderMenu(generator, &campaign, node, len(path)))
+			return
+		}
+		if fallback.ActionType == "menu" && fallback.SubMenu != nil {
+			h.setMenuPath(call.ID, append(path, fallback.ActionInput))
+		}
+		// Logged as its own event: the caller pressed the forward key, not the fallback's
+		h.createCallLog(call.ID, "forward_fallback",
+			fmt.Sprintf("Forward %s unanswered - falling back to action %s (%s)", key, fallback.ActionInput, fallback.ActionType), key)
+		writeTwiML(c, generator.GenerateDynamicResponse(fallback, node, len(path)))
+		return
+	}
+
+	numbers := services.ForwardNumbers(action)
+	dialed := update.DialCallStatus
+	if leg >= 0 && leg < len(numbers) && !services.ForwardsSimultaneously(action) {
+		dialed = fmt.Sprintf("%s (%s)", update.DialCallStatus, numbers[leg])
+	}
+	details := fmt.Sprintf("Forward %s: %s", key, dialed)
+	if duration, _ := strconv.Atoi(update.DialCallDuration); duration > 0 {
+		details += fmt.Sprintf(", %d seconds", duration)
+	}
+	h.createCallLog(call.ID, services.ForwardOutcomeEvent(update.DialCallStatus), details, key)
+
+	var response string
+	switch {
+	case services.ForwardConnected(update.DialCallStatus):
+		log.Printf("✓ Forward %s answered - ending call", key)
+		response = generator.GenerateForwardEnded()
+	case update.DialCallStatus == "canceled":
+		// The caller hung up while the agents were ringing
+		response = generator.GenerateHangup()
+	case !services.ForwardsSimultaneously(action) && leg+1 < len(numbers):
+		log.Printf("Forward %s: %s unanswered - dialing %s", key, numbers[leg], numbers[leg+1])
+		response = generator.GenerateForward(action, leg+1)
+	default:
+		log.Printf("✗ Forward %s unanswered - falling back to %s", key, services.ForwardFallback(action))
+		response = generator.GenerateForwardFallback(action, node, len(path))
+	}
+	writeTwiML(c, response)
+}
+
+// HandleWhisperWebhook returns the whisper announcement said to the agent who
+// answered a forward action, before the caller is connected
+func (h *WebhookHandler) HandleWhisperWebhook(c *gin.Context) {
+	parentSid := c.PostForm("ParentCallSid")
+	key := c.Query("key")
+
+	log.Printf("=== WHISPER WEBHOOK CALLED ===")
+	log.Printf("ParentCallSid: %s, Action: %s", parentSid, key)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	empty := twiml.NewResponse().String()
+
+	var call models.Call
+	if err := h.db.Collection("calls").FindOne(ctx, bson.M{"twilio_call_sid": parentSid}).Decode(&call); err != nil {
+		log.Printf("✗ Failed to find call by parent SID: %v", err)
+		writeTwiML(c, empty)
+		return
+	}
+
+	var campaign models.Campaign
+	if err := h.db.Collection("campaigns").FindOne(ctx, bson.M{"_id": call.CampaignID}).Decode(&campaign); err != nil {
+		log.Printf("✗ Failed to find campaign %s: %v", call.CampaignID.Hex(), err)
+		writeTwiML(c, empty)
+		return
+	}
+
+	node, _ := campaign.MenuAt(call.MenuPath)
+	action := node.FindAction(key)
+	if action == nil || action.Forward == nil {
+		writeTwiML(c, empty)
+		return
+	}
+
+	generator := services.NewTwiMLGenerator(call.Language).
+		WithVoice(campaign.Voice).
+		WithVariables(services.CallVariables(&call))
+	writeTwiML(c, generator.GenerateWhisper(action.Forward.Whisper))
+}
+
+// HandleTranscriptionWebhook attaches the transcript of a recorded message to
+// the call once Twilio has transcribed it
+func (h *WebhookHandler) HandleTranscriptionWebhook(c *gin.Context) {
+	var update models.TranscriptionUpdate
+	if err := c.ShouldBind(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("=== TRANSCRIPTION WEBHOOK CALLED ===")
+	log.Printf("CallSid: %s, RecordingSid: %s, Status: %s", update.CallSid, update.RecordingSid, update.TranscriptionStatus)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	status := services.TranscriptionCompleted
+	if update.TranscriptionStatus != services.TranscriptionCompleted {
+		status = services.TranscriptionFailed
+	}
+
+	var call models.Call
+	err := h.db.Collection("calls").FindOneAndUpdate(
+		ctx,
+		bson.M{"twilio_call_sid": update.CallSid, "messages.recording_sid": update.RecordingSid},
+		bson.M{"$set": bson.M{
+			"messages.$.transcript":           update.TranscriptionText,
+			"messages.$.transcription_status": status,
+			"updated_at":                      time.Now(),
+		}},
+	).Decode(&call)
+	if err != nil {
+		log.Printf("✗ No message %s on call %s: %v", update.RecordingSid, update.CallSid, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+
+	if status == services.TranscriptionCompleted {
+		h.createCallLog(call.ID, "message_transcribed", fmt.Sprintf("Transcript of %s: %s", update.RecordingSid, update.TranscriptionText), "")
+	} else {
+		h.createCallLog(call.ID, "message_transcription_failed", fmt.Sprintf("Transcription of %s failed", update.RecordingSid), "")
+	}
+
+	writeTwiML(c, twiml.NewResponse().String())
+}
+
+// HandleMachineDetectionWebhook receives the result of asynchronous answering
+// machine detection while the IVR is already playing. Calls answered by a
+// machine are moved to the voicemail message or hung up.
+func (h *WebhookHandler) HandleMachineDetectionWebhook(c *gin.Context) {
+	callSid := c.PostForm("CallSid")
+	answeredBy := c.PostForm("AnsweredBy")
+
+	log.Printf("=== MACHINE DETECTION WEBHOOK CALLED ===")
+	log.Printf("CallSid: %s, Answered by: %s", callSid, answeredBy)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var call models.Call
+	if err := h.db.Collection("calls").FindOne(ctx, bson.M{"twilio_call_sid": callSid}).Decode(&call); err != nil {
+		log.Printf("✗ Failed to find call by SID: %v", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Call not found"})
+		return
+	}
+
+	var campaign models.Campaign
+	if err := h.db.Collection("campaigns").FindOne(ctx, bson.M{"_id": call.CampaignID}).Decode(&campaign); err != nil {
+		log.Printf("✗ Failed to find campaign %s: %v", call.CampaignID.Hex(), err)
+	}
+
+	result := h.dialer.RecordAnsweredBy(ctx, &call, answeredBy)
+	if result == services.AnsweredByMachine || result == services.AnsweredByFax {
+		var err error
+		if result == services.AnsweredByMachine && services.LeavesVoicemail(campaign.MachineDetection) {
+			err = h.provider.RedirectCall(callSid, "/api/webhook/voicemail")
+		} else {
+			err = h.provider.HangupCall(callSid)
+		}
+		if err != nil {
+			log.Printf("✗ Failed to handle call answered by %s: %v", answeredBy, err)
+		}
+	}
+
+	writeTwiML(c, twiml.NewResponse().String())
+}
+
+// HandleVoicemailWebhook serves the voicemail message to a call that
+// asynchronous machine detection redirected away from the IVR
+func (h *WebhookHandler) HandleVoicemailWebhook(c *gin.Context) {
+	callSid := c.PostForm("CallSid")
+
+	log.Printf("=== VOICEMAIL WEBHOOK CALLED ===")
+	log.Printf("CallSid: %s", callSid)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var call models.Call
+	if err := h.db.Collection("calls").FindOne(ctx, bson.M{"twilio_call_sid": callSid}).Decode(&call); err != nil {
+		log.Printf("✗ Failed to find call by SID: %v", err)
+		writeTwiML(c, services.NewTwiMLGenerator("en").GenerateHangup())
+		return
+	}
+
+	var campaign models.Campaign
+	err := h.db.Collection("campaigns").FindOne(ctx, bson.M{"_id": call.CampaignID}).Decode(&campaign)
+	if err != nil || !services.LeavesVoicemail(campaign.MachineDetection) {
+		log.Printf("✗ Campaign %s has no voicemail message - hanging up", call.CampaignID.Hex())
+		writeTwiML(c, services.NewTwiMLGenerator(call.Language).GenerateHangup())
+		return
+	}
+
+	generator := services.NewTwiMLGenerator(call.Language).
+		WithVoice(campaign.Voice).
+		WithVariables(services.CallVariables(&call))
+	writeTwiML(c, h.leaveVoicemail(generator, &call, &campaign))
 }
 
 // HandleOptOutConfirm handles opt-out confirmation
@@ -241,29 +838,117 @@ func (h *WebhookHandler) HandleOptOutConfirm(c *gin.Context) {
 		language = call.Language
 
 		if input.Digits == "1" {
-			// Mark customer as opted out (you can add an opt-out table)
-			h.createCallLog(call.ID, "opted_out", "User confirmed opt-out")
+			// Put the number on the do-not-call list so it is never dialed again
+			campaignID, callID := call.CampaignID, call.ID
+			_, err := h.dncService.Add(ctx, models.DNCEntry{
+				PhoneNumber: call.PhoneNumber,
+				Source:      "opt_out",
+				Reason:      "Caller opted out during IVR call",
+				CampaignID:  &campaignID,
+				CallID:      &callID,
+			})
+			if err != nil {
+				log.Printf("Failed to add %s to do-not-call list: %v", call.PhoneNumber, err)
+			}
+			h.createCallLog(call.ID, "opted_out", "User confirmed opt-out", input.Digits)
 		}
 	}
 
 	generator := services.NewTwiMLGenerator(language)
-	var twiml string
+	var response string
 
 	if input.Digits == "1" {
-		twiml = generator.GenerateOptOutConfirm()
+		response = generator.GenerateOptOutConfirm()
 	} else {
-		twiml = generator.GenerateMainMenu()
+		response = generator.GenerateMainMenu()
 	}
 
-	c.Header("Content-Type", "text/xml")
-	c.String(http.StatusOK, twiml)
+	writeTwiML(c, response)
 }
 
-func (h *WebhookHandler) createCallLog(callID primitive.ObjectID, event, details string) {
+// machineResponse leaves the campaign's voicemail message on an answering
+// machine whose greeting has ended, and hangs up on every other machine
+func (h *WebhookHandler) machineResponse(generator *services.TwiMLGenerator, call *models.Call, campaign *models.Campaign, answeredBy string) string {
+	if !services.LeavesVoicemail(campaign.MachineDetection) || !services.MachineMessageReady(answeredBy) {
+		h.createCallLog(call.ID, "machine_hangup", fmt.Sprintf("Hung up on %s", answeredBy), "")
+		return generator.GenerateHangup()
+	}
+	return h.leaveVoicemail(generator, call, campaign)
+}
+
+// leaveVoicemail logs and generates the campaign's voicemail message
+func (h *WebhookHandler) leaveVoicemail(generator *services.TwiMLGenerator, call *models.Call, campaign *models.Campaign) string {
+	h.createCallLog(call.ID, "voicemail_left", "Voicemail message left after the beep", "")
+	h.eventBus.PublishCall("voicemail_left", call, "")
+	return generator.GenerateVoicemail(campaign.MachineDetection.VoicemailMessage)
+}
+
+// matchSpeech resolves a transcript to the key of the action the caller asked
+// for on the current menu level. Transcripts below the campaign's confidence
+// threshold or matching no keyword return "", which replays the menu.
+func (h *WebhookHandler) matchSpeech(call *models.Call, campaign *models.Campaign, node *models.MenuNode, input *models.IVRInput) string {
+	threshold := services.MinSpeechConfidence(campaign.SpeechInput)
+	if input.Confidence < threshold {
+		log.Printf("✗ Ignoring speech '%s' - confidence %.2f is below %.2f", input.SpeechResult, input.Confidence, threshold)
+		h.createCallLog(call.ID, "speech_rejected",
+			fmt.Sprintf("Confidence %.2f is below the threshold of %.2f", input.Confidence, threshold), "")
+		return ""
+	}
+
+	action := services.MatchSpeech(node.Actions, input.SpeechResult)
+	if action == nil {
+		log.Printf("✗ Speech '%s' matches no action", input.SpeechResult)
+		h.createCallLog(call.ID, "speech_unmatched", "Speech matched no option", "")
+		return ""
+	}
+
+	log.Printf("✓ Speech '%s' matched action %s", input.SpeechResult, action.ActionInput)
+	return action.ActionInput
+}
+
+// renderMenu replays a menu level - the full welcome at the root, the sub-menu prompt below it
+func (h *WebhookHandler) renderMenu(generator *services.TwiMLGenerator, campaign *models.Campaign, node *models.MenuNode, depth int) string {
+	if depth == 0 {
+		return generator.GenerateDynamicWelcome("", campaign)
+	}
+	return generator.GenerateMenu(node, depth)
+}
+
+// openCalendars returns which business calendars used by the campaign are open now
+func (h *WebhookHandler) openCalendars(campaign *models.Campaign) map[primitive.ObjectID]bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return h.businessHours.Availability(ctx, campaign, time.Now())
+}
+
+// setMenuPath stores the caller's current position in the menu tree
+func (h *WebhookHandler) setMenuPath(callID primitive.ObjectID, path []string) {
+	if callID.IsZero() {
+		return
+	}
+	if path == nil {
+		path = []string{}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := h.db.Collection("calls").UpdateOne(
+		ctx,
+		bson.M{"_id": callID},
+		bson.M{"$set": bson.M{"menu_path": path, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		log.Printf("Failed to update menu path: %v", err)
+	}
+}
+
+func (h *WebhookHandler) createCallLog(callID primitive.ObjectID, event, details, userInput string) {
 	callLog := models.CallLog{
 		CallID:    callID,
 		Event:     event,
 		Details:   details,
+		UserInput: userInput,
 		CreatedAt: time.Now(),
 	}
 
@@ -274,3 +959,8 @@ func (h *WebhookHandler) createCallLog(callID primitive.ObjectID, event, details
 		log.Printf("Failed to create call log: %v", err)
 	}
 }
+
+// writeTwiML sends a TwiML document as the response to a Twilio webhook
+func writeTwiML(c *gin.Context, response string) {
+	c.Data(http.StatusOK, "text/xml", []byte(response))
+}
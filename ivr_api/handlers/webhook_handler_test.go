@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/prabhatkumar/ivrcalling/models"
+	"github.com/prabhatkumar/ivrcalling/services"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestForwardFallbackIsNotAKeyPress(t *testing.T) {
+	env := newTestEnv(t)
+
+	campaignID := env.insertCampaign(t, models.Campaign{
+		Name:      "Support",
+		Language:  "en",
+		IntroText: "Welcome to support",
+		Actions: []models.IVRAction{
+			{ActionType: "forward", ActionInput: "1", ForwardPhone: "+14155550150", Forward: &models.ForwardSettings{
+				Fallback: "2", UnavailableMessage: "Nobody is available",
+			}},
+			{ActionType: "information", ActionInput: "2", Message: "Please call again tomorrow"},
+		},
+	})
+	callID := env.insertCall(t, models.Call{
+		CampaignID:    campaignID,
+		PhoneNumber:   "+14155550123",
+		Status:        "in-progress",
+		TwilioCallSID: "CATESTFALLBACK",
+		Language:      "en",
+		Attempts:      1,
+	})
+	sid := url.Values{"CallSid": {"CATESTFALLBACK"}}
+
+	body := env.postForm(t, "/api/webhook/forward?key=1&leg=0", url.Values{"CallSid": sid["CallSid"], "DialCallStatus": {"no-answer"}})
+	if !strings.Contains(body, "fallback=1") {
+		t.Fatalf("an unanswered forward does not redirect to its fallback: %s", body)
+	}
+
+	body = env.postForm(t, "/api/webhook/forward?key=1&fallback=1", sid)
+	if !strings.Contains(body, "Please call again tomorrow") {
+		t.Errorf("the fallback action does not run: %s", body)
+	}
+
+	events := env.logEvents(t, callID)
+	if !contains(events, "forward_fallback") {
+		t.Errorf("call log events %v, want forward_fallback", events)
+	}
+	for _, event := range events {
+		if strings.HasPrefix(event, "action_") {
+			t.Errorf("the fallback is logged as the key press %s", event)
+		}
+	}
+}
+
+func TestCollectWebhook(t *testing.T) {
+	env := newTestEnv(t)
+
+	campaignID := env.insertCampaign(t, models.Campaign{
+		Name:      "Support",
+		Language:  "en",
+		IntroText: "Welcome to support",
+		Actions: []models.IVRAction{
+			{ActionType: "collect", ActionInput: "1", Message: "Enter your order number", Collect: &models.CollectSettings{
+				Name: "order_number", MinLength: 6, MaxLength: 6, SuccessMessage: "Order {{.order_number}} noted",
+			}},
+		},
+	})
+	callID := env.insertCall(t, models.Call{
+		CampaignID:    campaignID,
+		PhoneNumber:   "+14155550123",
+		Status:        "in-progress",
+		TwilioCallSID: "CATESTCOLLECT",
+		Language:      "en",
+		Attempts:      1,
+	})
+	sid := "CATESTCOLLECT"
+
+	body := env.postForm(t, "/api/webhook/gather", url.Values{"CallSid": {sid}, "Digits": {"1"}})
+	if !strings.Contains(body, `action="/api/webhook/collect?key=1&amp;attempt=0"`) {
+		t.Fatalf("gather response does not ask for the order number: %s", body)
+	}
+
+	body = env.postForm(t, "/api/webhook/collect?key=1&attempt=0", url.Values{"CallSid": {sid}, "Digits": {"12"}})
+	if !strings.Contains(body, "attempt=1") {
+		t.Errorf("an invalid value is not re-prompted: %s", body)
+	}
+
+	body = env.postForm(t, "/api/webhook/collect?key=1&attempt=1", url.Values{"CallSid": {sid}, "Digits": {"123456"}})
+	if !strings.Contains(body, "Order 123456 noted") {
+		t.Errorf("collected value is not confirmed: %s", body)
+	}
+	if call := env.findCall(t, bson.M{"_id": callID}); call.Collected["order_number"] != "123456" {
+		t.Errorf("collected = %v, want order_number 123456", call.Collected)
+	}
+	events := env.logEvents(t, callID)
+	if !contains(events, "input_invalid") || !contains(events, "input_collected") {
+		t.Errorf("call log events %v, want input_invalid and input_collected", events)
+	}
+}
+
+func TestRecordWebhook(t *testing.T) {
+	env := newTestEnv(t)
+
+	campaignID := env.insertCampaign(t, models.Campaign{
+		Name:      "Support",
+		Language:  "en",
+		IntroText: "Welcome to support",
+		Actions: []models.IVRAction{
+			{ActionType: "record", ActionInput: "1", Message: "Leave a message", Record: &models.RecordSettings{MaxLength: 60}},
+		},
+	})
+	callID := env.insertCall(t, models.Call{
+		CampaignID:    campaignID,
+		PhoneNumber:   "+14155550123",
+		Status:        "in-progress",
+		TwilioCallSID: "CATESTRECORD",
+		Language:      "en",
+		Attempts:      1,
+	})
+	sid := url.Values{"CallSid": {"CATESTRECORD"}}
+
+	body := env.postForm(t, "/api/webhook/gather", url.Values{"CallSid": sid["CallSid"], "Digits": {"1"}})
+	if !strings.Contains(body, `<Record action="/api/webhook/record?key=1"`) {
+		t.Fatalf("gather response does not record a message: %s", body)
+	}
+
+	// Twilio redirects without a recording when the caller says nothing
+	env.postForm(t, "/api/webhook/record?key=1", sid)
+	if events := env.logEvents(t, callID); !contains(events, "message_empty") {
+		t.Errorf("call log events %v, want message_empty", events)
+	}
+
+	env.postForm(t, "/api/webhook/record?key=1", url.Values{
+		"CallSid":           sid["CallSid"],
+		"RecordingSid":      {"RETESTMESSAGE"},
+		"RecordingUrl":      {"https://api.twilio.com/Recordings/RETESTMESSAGE"},
+		"RecordingDuration": {"7"},
+	})
+	call := env.findCall(t, bson.M{"_id": callID})
+	if len(call.Messages) != 1 || call.Messages[0].RecordingSID != "RETESTMESSAGE" || call.Messages[0].Duration != 7 {
+		t.Errorf("messages = %+v, want the 7 second message RETESTMESSAGE", call.Messages)
+	}
+}
+
+func TestForwardRingGroup(t *testing.T) {
+	env := newTestEnv(t)
+
+	campaignID := env.insertCampaign(t, models.Campaign{
+		Name:      "Support",
+		Language:  "en",
+		IntroText: "Welcome to support",
+		Actions: []models.IVRAction{
+			{ActionType: "forward", ActionInput: "1", ForwardPhone: "+14155550150", Forward: &models.ForwardSettings{
+				Numbers: []string{"+14155550151"}, Fallback: "hangup",
+			}},
+		},
+	})
+	callID := env.insertCall(t, models.Call{
+		CampaignID:    campaignID,
+		PhoneNumber:   "+14155550123",
+		Status:        "in-progress",
+		TwilioCallSID: "CATESTFORWARD",
+		Language:      "en",
+		Attempts:      1,
+	})
+	sid := url.Values{"CallSid": {"CATESTFORWARD"}}
+
+	body := env.postForm(t, "/api/webhook/gather", url.Values{"CallSid": sid["CallSid"], "Digits": {"1"}})
+	if !strings.Contains(body, `action="/api/webhook/forward?key=1&amp;leg=0"`) || !strings.Contains(body, "+14155550150") {
+		t.Fatalf("gather response does not dial the first number: %s", body)
+	}
+
+	body = env.postForm(t, "/api/webhook/forward?key=1&leg=0", url.Values{"CallSid": sid["CallSid"], "DialCallStatus": {"no-answer"}})
+	if !strings.Contains(body, "leg=1") || !strings.Contains(body, "+14155550151") {
+		t.Errorf("an unanswered leg does not dial the next number: %s", body)
+	}
+
+	body = env.postForm(t, "/api/webhook/forward?key=1&leg=1", url.Values{"CallSid": sid["CallSid"], "DialCallStatus": {"busy"}})
+	if !strings.Contains(body, "<Hangup>") {
+		t.Errorf("the hangup fallback does not hang up: %s", body)
+	}
+
+	events := env.logEvents(t, callID)
+	if !contains(events, "forward_no_answer") || !contains(events, "forward_busy") {
+		t.Errorf("call log events %v, want forward_no_answer and forward_busy", events)
+	}
+}
+
+func TestInboundAnswer(t *testing.T) {
+	env := newTestEnv(t)
+
+	campaignID := env.insertCampaign(t, models.Campaign{
+		Name:         "Hotline",
+		Language:     "en",
+		IntroText:    "Thanks for calling",
+		Actions:      []models.IVRAction{{ActionType: "information", ActionInput: "1", Message: "We are open daily"}},
+		PhoneNumbers: []string{"+14155550100"},
+		Recording:    &models.RecordingSettings{Mode: services.RecordCall, ConsentMessage: "This call is recorded"},
+	})
+	// A caller called before is greeted with the name of their outbound call
+	env.insertCall(t, models.Call{CampaignID: campaignID, PhoneNumber: "+14155550123", CustomerName: "Asha", Status: "completed", Language: "en"})
+
+	answer := url.Values{"CallSid": {"CATESTINBOUND"}, "From": {"+1 (415) 555-0123"}, "To": {"+14155550100"}}
+	body := env.postForm(t, "/api/webhook/voice", answer)
+	if !strings.Contains(body, `<Start><Recording`) || !strings.Contains(body, `recordingStatusCallback="/api/webhook/recording"`) {
+		t.Errorf("the first answer does not start the recording: %s", body)
+	}
+	if !strings.Contains(body, "This call is recorded") || !strings.Contains(body, "Thanks for calling") {
+		t.Errorf("the first answer misses the consent message or the welcome: %s", body)
+	}
+
+	call := env.findCall(t, bson.M{"twilio_call_sid": "CATESTINBOUND"})
+	if call.Direction != services.CallInbound || call.CampaignID != campaignID || call.PhoneNumber != "+14155550123" || call.CustomerName != "Asha" {
+		t.Errorf("inbound call = %+v, want an inbound call from Asha on the hotline campaign", call)
+	}
+
+	// Twilio asking for the TwiML again must not start a second recording
+	body = env.postForm(t, "/api/webhook/voice", answer)
+	if strings.Contains(body, "<Start>") {
+		t.Errorf("a repeated answer starts the recording again: %s", body)
+	}
+	count, err := env.db.Collection("calls").CountDocuments(env.ctx, bson.M{"twilio_call_sid": "CATESTINBOUND"})
+	if err != nil || count != 1 {
+		t.Errorf("inbound call records = %d (%v), want 1", count, err)
+	}
+
+	// Numbers no campaign answers get the legacy IVR
+	body = env.postForm(t, "/api/webhook/voice", url.Values{"CallSid": {"CATESTOTHER"}, "From": {"+14155550123"}, "To": {"+14155550111"}})
+	if strings.Contains(body, "<Start>") || strings.Contains(body, "Thanks for calling") {
+		t.Errorf("a number without a campaign runs the campaign: %s", body)
+	}
+}
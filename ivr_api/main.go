@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	_ "time/tzdata" // calling windows need zone data even on minimal images
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -49,7 +50,7 @@ func main() {
 	router := gin.Default()
 
 	// Setup routes
-	routes.SetupRoutes(router, db, cfg)
+	routes.SetupRoutes(ctx, router, db, cfg)
 
 	// Start server
 	port := os.Getenv("PORT")
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prabhatkumar/ivrcalling/config"
+	"github.com/prabhatkumar/ivrcalling/models"
+	"github.com/prabhatkumar/ivrcalling/services"
+)
+
+// principalKey is the gin context key holding the authenticated *models.Principal
+const principalKey = "principal"
+
+// Authenticate identifies the caller from an X-API-Key header or an
+// "Authorization: Bearer <jwt>" header and rejects anonymous requests with 401.
+// With AUTH_ENABLED=false every request is treated as an admin.
+func Authenticate(cfg *config.Config, authService *services.AuthService) gin.HandlerFunc {
+	if !cfg.AuthEnabled {
+		log.Println("WARNING: API authentication is disabled - every request has admin access")
+		return func(c *gin.Context) {
+			c.Set(principalKey, &models.Principal{Subject: "anonymous", Name: "Anonymous", Role: models.RoleAdmin, Method: "disabled"})
+			c.Next()
+		}
+	}
+
+	if cfg.AdminAPIKey == "" {
+		log.Println("WARNING: ADMIN_API_KEY is not set - only keys already stored in the database can be used")
+	}
+
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var principal *models.Principal
+		var err error
+
+		if key := c.GetHeader("X-API-Key"); key != "" {
+			principal, err = authService.AuthenticateAPIKey(ctx, key)
+		} else if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			principal, err = authService.AuthenticateToken(ctx, strings.TrimSpace(token))
+		} else {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required (X-API-Key header or Bearer token)"})
+			return
+		}
+
+		if err != nil {
+			if !errors.Is(err, services.ErrInvalidCredentials) && !errors.Is(err, services.ErrTokensDisabled) {
+				log.Printf("✗ Authentication failed: %v", err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate request"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key or token"})
+			return
+		}
+
+		c.Set(principalKey, principal)
+		c.Next()
+	}
+}
+
+// QueryCredentials lets clients that cannot set headers, such as the browser
+// EventSource API, pass a bearer token as the "access_token" query parameter.
+// API keys are never accepted in the query: URLs end up in access logs, proxy
+// logs and browser history, where only a short-lived token may appear.
+func QueryCredentials() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.Query("access_token"); token != "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		c.Next()
+	}
+}
+
+// RequireRole rejects requests whose principal does not have at least the given role
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := CurrentPrincipal(c)
+		if principal == nil || !models.RoleAllows(principal.Role, role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "This action requires the " + role + " role"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentPrincipal returns the authenticated caller, or nil outside authenticated routes
+func CurrentPrincipal(c *gin.Context) *models.Principal {
+	value, ok := c.Get(principalKey)
+	if !ok {
+		return nil
+	}
+	principal, _ := value.(*models.Principal)
+	return principal
+}
+
+// CurrentSubject returns the subject of the authenticated caller for audit fields
+func CurrentSubject(c *gin.Context) string {
+	if principal := CurrentPrincipal(c); principal != nil {
+		return principal.Subject
+	}
+	return ""
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prabhatkumar/ivrcalling/config"
+	"github.com/prabhatkumar/ivrshared/signature"
+)
+
+// TwilioSignature rejects webhook requests that do not carry a valid
+// X-Twilio-Signature header. The signature is computed by Twilio over the
+// public URL it called plus the POSTed form parameters, so the URL is rebuilt
+// from WEBHOOK_BASE_URL rather than from the (possibly proxied) request host.
+func TwilioSignature(cfg *config.Config) gin.HandlerFunc {
+	if !cfg.TwilioValidateSignature {
+		log.Println("WARNING: Twilio webhook signature validation is disabled")
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	if cfg.TwilioAuthToken == "" {
+		log.Println("WARNING: TWILIO_AUTH_TOKEN is not set - all webhook requests will be rejected")
+	}
+
+	baseURL := strings.TrimRight(cfg.WebhookBaseURL, "/")
+
+	return func(c *gin.Context) {
+		header := c.GetHeader("X-Twilio-Signature")
+		if header == "" || cfg.TwilioAuthToken == "" {
+			log.Printf("✗ Rejected webhook %s - missing Twilio signature", c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Invalid Twilio signature"})
+			return
+		}
+
+		if err := c.Request.ParseForm(); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid form data"})
+			return
+		}
+
+		// Only body parameters are signed separately; query parameters are part of the URL
+		url := baseURL + c.Request.URL.RequestURI()
+		if !signature.Validate(cfg.TwilioAuthToken, url, c.Request.PostForm, header) {
+			log.Printf("✗ Rejected webhook %s - invalid Twilio signature", c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Invalid Twilio signature"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Roles, from most to least privileged
+const (
+	RoleAdmin           = "admin"            // everything, including API key management
+	RoleCampaignManager = "campaign-manager" // manage campaigns, place calls, manage the do-not-call list
+	RoleReadOnly        = "read-only"        // view campaigns, calls and jobs
+)
+
+var roleRanks = map[string]int{
+	RoleReadOnly:        1,
+	RoleCampaignManager: 2,
+	RoleAdmin:           3,
+}
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// RoleAllows reports whether role grants at least the permissions of required
+func RoleAllows(role, required string) bool {
+	rank, ok := roleRanks[role]
+	return ok && rank >= roleRanks[required]
+}
+
+// APIKey is a stored API key. Only the SHA-256 hash of the key is kept.
+type APIKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name       string             `bson:"name" json:"name"`
+	Prefix     string             `bson:"prefix" json:"prefix"` // first characters of the key, to recognise it in listings
+	KeyHash    string             `bson:"key_hash" json:"-"`
+	Role       string             `bson:"role" json:"role"`
+	CreatedBy  string             `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	LastUsedAt *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+}
+
+// APIKeyRequest represents a request to create an API key
+type APIKeyRequest struct {
+	Name string `json:"name" binding:"required"`
+	Role string `json:"role" binding:"required"`
+}
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Subject string `json:"subject"` // "key:<id>" for API keys and the tokens issued for them
+	Name    string `json:"name"`
+	Role    string `json:"role"`
+	Method  string `json:"method"` // api_key, jwt or disabled
+}
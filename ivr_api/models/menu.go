@@ -0,0 +1,41 @@
+package models
+
+// MaxMenuDepth limits how deeply sub-menus can be nested
+const MaxMenuDepth = 5
+
+// RootMenu returns the top level of the campaign's IVR menu tree
+func (c *Campaign) RootMenu() *MenuNode {
+	return &MenuNode{
+		Prompt:  c.IntroText,
+		Actions: c.Actions,
+	}
+}
+
+// MenuAt walks the menu tree along path and returns the deepest node reached
+// together with the part of the path that was valid. A stale or invalid path
+// (e.g. after the campaign was edited mid-call) resolves to the closest valid node.
+func (c *Campaign) MenuAt(path []string) (*MenuNode, []string) {
+	node := c.RootMenu()
+	valid := []string{}
+
+	for _, key := range path {
+		action := node.FindAction(key)
+		if action == nil || action.ActionType != "menu" || action.SubMenu == nil {
+			break
+		}
+		node = action.SubMenu
+		valid = append(valid, key)
+	}
+
+	return node, valid
+}
+
+// FindAction returns the action bound to the given key on this menu level
+func (n *MenuNode) FindAction(key string) *IVRAction {
+	for i := range n.Actions {
+		if n.Actions[i].ActionInput == key {
+			return &n.Actions[i]
+		}
+	}
+	return nil
+}
@@ -8,23 +8,152 @@ import (
 
 // IVRAction represents an action in the IVR flow
 type IVRAction struct {
-	ActionType   string `bson:"action_type" json:"action_type"`                         // "information" or "forward"
-	ActionInput  string `bson:"action_input" json:"action_input"`                       // key press (e.g., "1", "2", "3")
-	Message      string `bson:"message,omitempty" json:"message,omitempty"`             // text or URL for information type
-	ForwardPhone string `bson:"forward_phone,omitempty" json:"forward_phone,omitempty"` // phone number for forward type
+	ActionType   string           `bson:"action_type" json:"action_type"`                         // "information", "forward", "menu", "collect" or "record"
+	ActionInput  string           `bson:"action_input" json:"action_input"`                       // key press (e.g., "1", "2", "3")
+	Message      string           `bson:"message,omitempty" json:"message,omitempty"`             // text or URL for information type, label for menu type
+	ForwardPhone string           `bson:"forward_phone,omitempty" json:"forward_phone,omitempty"` // phone number for forward type
+	SubMenu      *MenuNode        `bson:"sub_menu,omitempty" json:"sub_menu,omitempty"`           // nested menu for menu type
+	Keywords     []string         `bson:"keywords,omitempty" json:"keywords,omitempty"`           // spoken words or phrases that choose this action when speech input is enabled
+	Collect      *CollectSettings `bson:"collect,omitempty" json:"collect,omitempty"`             // digits to gather for collect type; Message is the prompt
+	Record       *RecordSettings  `bson:"record,omitempty" json:"record,omitempty"`               // message to capture for record type; Message is the prompt
+	Forward      *ForwardSettings `bson:"forward,omitempty" json:"forward,omitempty"`             // ring group, timeouts and fallback for forward type
+}
+
+// ForwardSettings describes how a "forward" action dials. ForwardPhone is
+// dialed first, followed by Numbers one after the other, or all of them at
+// once with the "simultaneous" strategy.
+type ForwardSettings struct {
+	Numbers            []string `bson:"numbers,omitempty" json:"numbers,omitempty"`                         // further numbers of the ring group
+	Strategy           string   `bson:"strategy,omitempty" json:"strategy,omitempty"`                       // "sequential" (default) or "simultaneous"
+	Timeout            int      `bson:"timeout,omitempty" json:"timeout,omitempty"`                         // seconds each number rings, default 30
+	CallerID           string   `bson:"caller_id,omitempty" json:"caller_id,omitempty"`                     // number shown to the agent; empty uses the calling number
+	Whisper            string   `bson:"whisper,omitempty" json:"whisper,omitempty"`                         // said to the agent before the calls are connected
+	Fallback           string   `bson:"fallback,omitempty" json:"fallback,omitempty"`                       // "menu" (default), "hangup" or the key of another action on the same menu level
+	UnavailableMessage string   `bson:"unavailable_message,omitempty" json:"unavailable_message,omitempty"` // said before the fallback when no one answered
+
+	CalendarID        *primitive.ObjectID `bson:"calendar_id,omitempty" json:"calendar_id,omitempty"`                 // business calendar outside of which the action is unavailable
+	AfterHoursAction  string              `bson:"after_hours_action,omitempty" json:"after_hours_action,omitempty"`   // key of the action on the same menu level chosen instead while closed
+	AfterHoursMessage string              `bson:"after_hours_message,omitempty" json:"after_hours_message,omitempty"` // said while closed when there is no after-hours action
+}
+
+// BusinessCalendar holds the opening hours of an office. Forward actions
+// attached to a calendar only dial while it is open.
+type BusinessCalendar struct {
+	ID        primitive.ObjectID      `bson:"_id,omitempty" json:"id"`
+	Name      string                  `bson:"name" json:"name"`
+	Timezone  string                  `bson:"timezone" json:"timezone"`                     // IANA zone the hours and holidays are in
+	Hours     map[string][]TimeWindow `bson:"hours" json:"hours"`                           // "mon" ... "sun" to opening windows; days left out are closed
+	Holidays  []Holiday               `bson:"holidays,omitempty" json:"holidays,omitempty"` // dates with no or reduced hours
+	CreatedBy string                  `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt time.Time               `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time               `bson:"updated_at" json:"updated_at"`
+}
+
+// Holiday is a date a business calendar is closed, or only open during Windows
+type Holiday struct {
+	Date    string       `bson:"date" json:"date"` // "YYYY-MM-DD"
+	Name    string       `bson:"name,omitempty" json:"name,omitempty"`
+	Windows []TimeWindow `bson:"windows,omitempty" json:"windows,omitempty"` // reduced hours; empty means closed all day
+}
+
+// CollectSettings describes the digits a "collect" action asks the caller to
+// key in. Unless Sensitive is set, the value is stored on the call under Name
+// and can be used as {{.name}} in later messages.
+type CollectSettings struct {
+	Name           string `bson:"name" json:"name"`
+	MinLength      int    `bson:"min_length,omitempty" json:"min_length,omitempty"`
+	MaxLength      int    `bson:"max_length" json:"max_length"`
+	FinishOnKey    string `bson:"finish_on_key,omitempty" json:"finish_on_key,omitempty"`     // "#" (default) or "*"
+	Pattern        string `bson:"pattern,omitempty" json:"pattern,omitempty"`                 // regular expression the whole value must match
+	MaxRetries     int    `bson:"max_retries,omitempty" json:"max_retries,omitempty"`         // re-prompts after an invalid value, default 2, at most 5
+	InvalidMessage string `bson:"invalid_message,omitempty" json:"invalid_message,omitempty"` // said before re-prompting
+	SuccessMessage string `bson:"success_message,omitempty" json:"success_message,omitempty"` // said once the value is accepted
+	WebhookURL     string `bson:"webhook_url,omitempty" json:"webhook_url,omitempty"`         // receives the value as JSON when collected
+	Sensitive      bool   `bson:"sensitive,omitempty" json:"sensitive,omitempty"`             // mask the value in call logs and events and don't store it on the call
+}
+
+// RecordSettings describes the message a "record" action lets the caller
+// leave after the beep
+type RecordSettings struct {
+	MaxLength      int    `bson:"max_length,omitempty" json:"max_length,omitempty"`           // seconds, default 120
+	FinishOnKey    string `bson:"finish_on_key,omitempty" json:"finish_on_key,omitempty"`     // "#" (default) or "*"
+	Transcribe     bool   `bson:"transcribe,omitempty" json:"transcribe,omitempty"`           // request a transcript, for messages of at most 120 seconds
+	SuccessMessage string `bson:"success_message,omitempty" json:"success_message,omitempty"` // said once the message is recorded
+}
+
+// MenuNode represents one level of the IVR menu tree
+type MenuNode struct {
+	Prompt  string      `bson:"prompt,omitempty" json:"prompt,omitempty"` // played before the options of this level
+	Actions []IVRAction `bson:"actions" json:"actions"`
 }
 
 // Campaign represents a marketing campaign
 type Campaign struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name        string             `bson:"name" json:"name"`
-	Description string             `bson:"description" json:"description"`
-	Language    string             `bson:"language" json:"language"`
-	IntroText   string             `bson:"intro_text" json:"intro_text"`               // Intro text played at start
-	Actions     []IVRAction        `bson:"actions,omitempty" json:"actions,omitempty"` // IVR actions
-	IsActive    bool               `bson:"is_active" json:"is_active"`
-	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name             string             `bson:"name" json:"name"`
+	Description      string             `bson:"description" json:"description"`
+	Language         string             `bson:"language" json:"language"`
+	Voice            string             `bson:"voice,omitempty" json:"voice,omitempty"`               // text-to-speech voice; empty uses the language's default
+	SpeechInput      *SpeechSettings    `bson:"speech_input,omitempty" json:"speech_input,omitempty"` // lets callers say their choice instead of pressing a key
+	IntroText        string             `bson:"intro_text" json:"intro_text"`                         // Intro text played at start
+	Actions          []IVRAction        `bson:"actions,omitempty" json:"actions,omitempty"`           // IVR actions
+	IsActive         bool               `bson:"is_active" json:"is_active"`
+	RetryPolicy      *RetryPolicy       `bson:"retry_policy,omitempty" json:"retry_policy,omitempty"`           // automatic redialing of unsuccessful calls
+	Schedule         *Schedule          `bson:"schedule,omitempty" json:"schedule,omitempty"`                   // when calls may be placed
+	MachineDetection *MachineDetection  `bson:"machine_detection,omitempty" json:"machine_detection,omitempty"` // answering machine detection and voicemail message
+	Recording        *RecordingSettings `bson:"recording,omitempty" json:"recording,omitempty"`                 // call recording for compliance
+	PhoneNumbers     []string           `bson:"phone_numbers,omitempty" json:"phone_numbers,omitempty"`         // Twilio numbers whose inbound calls run this campaign's IVR
+	CreatedBy        string             `bson:"created_by,omitempty" json:"created_by,omitempty"`               // subject of the API key or token that created it
+	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// SpeechSettings turns menus into <Gather input="dtmf speech">. Callers
+// choose an action by saying one of its keywords.
+type SpeechSettings struct {
+	Enabled       bool    `bson:"enabled" json:"enabled"`
+	MinConfidence float64 `bson:"min_confidence,omitempty" json:"min_confidence,omitempty"` // 0 to 1, default 0.5; less confident transcripts are ignored
+}
+
+// MachineDetection asks Twilio to detect answering machines. With a voicemail
+// message the detection waits for the beep and the message is left on the
+// machine; without one, calls answered by a machine are hung up.
+type MachineDetection struct {
+	Enabled          bool   `bson:"enabled" json:"enabled"`
+	Async            bool   `bson:"async,omitempty" json:"async,omitempty"`                         // play the IVR while detecting instead of waiting for the result
+	TimeoutSeconds   int    `bson:"timeout_seconds,omitempty" json:"timeout_seconds,omitempty"`     // 3 to 59, default 30
+	VoicemailMessage string `bson:"voicemail_message,omitempty" json:"voicemail_message,omitempty"` // text or audio URL left after the beep
+}
+
+// RecordingSettings controls which part of a campaign's calls is recorded
+type RecordingSettings struct {
+	Mode           string `bson:"mode" json:"mode"`                                           // "call" records the whole call, "forward" only forwarded conversations
+	DualChannel    bool   `bson:"dual_channel,omitempty" json:"dual_channel,omitempty"`       // record the two parties on separate channels
+	ConsentMessage string `bson:"consent_message,omitempty" json:"consent_message,omitempty"` // said before the recorded part, e.g. "This call is recorded"
+	RetentionDays  int    `bson:"retention_days,omitempty" json:"retention_days,omitempty"`   // 0 uses RECORDING_RETENTION_DAYS
+}
+
+// Schedule restricts when the calls of a campaign may be placed. Weekdays and
+// windows are evaluated in the recipient's local time.
+type Schedule struct {
+	StartDate       *time.Time   `bson:"start_date,omitempty" json:"start_date,omitempty"`
+	EndDate         *time.Time   `bson:"end_date,omitempty" json:"end_date,omitempty"`
+	Weekdays        []string     `bson:"weekdays,omitempty" json:"weekdays,omitempty"`                 // "mon" ... "sun"; empty means every day
+	Windows         []TimeWindow `bson:"windows,omitempty" json:"windows,omitempty"`                   // daily windows; empty means all day
+	DefaultTimezone string       `bson:"default_timezone,omitempty" json:"default_timezone,omitempty"` // IANA zone used when the number's country is unknown
+}
+
+// TimeWindow is a daily time range in "HH:MM" 24-hour format; End is exclusive
+type TimeWindow struct {
+	Start string `bson:"start" json:"start"`
+	End   string `bson:"end" json:"end"`
+}
+
+// RetryPolicy controls automatic redialing of calls that did not connect
+type RetryPolicy struct {
+	MaxAttempts    int      `bson:"max_attempts" json:"max_attempts"`       // total attempts, including the first one
+	BackoffMinutes int      `bson:"backoff_minutes" json:"backoff_minutes"` // wait before each redial
+	RetryOn        []string `bson:"retry_on" json:"retry_on"`               // outcomes to retry: busy, no-answer, failed
 }
 
 // Call represents an individual call
@@ -33,36 +162,125 @@ type Call struct {
 	CampaignID    primitive.ObjectID `bson:"campaign_id" json:"campaign_id"`
 	PhoneNumber   string             `bson:"phone_number" json:"phone_number"`
 	CustomerName  string             `bson:"customer_name" json:"customer_name"`
-	Status        string             `bson:"status" json:"status"` // pending, initiated, in-progress, completed, failed
+	Direction     string             `bson:"direction,omitempty" json:"direction,omitempty"`     // outbound (default) or inbound
+	Fields        map[string]string  `bson:"fields,omitempty" json:"fields,omitempty"`           // custom template variables of the contact
+	Status        string             `bson:"status" json:"status"`                               // pending, initiated, in-progress, completed, failed, scheduled, claimed
+	Outcome       string             `bson:"outcome,omitempty" json:"outcome,omitempty"`         // final Twilio status of the last attempt: completed, busy, no-answer, failed, canceled
+	AnsweredBy    string             `bson:"answered_by,omitempty" json:"answered_by,omitempty"` // machine detection result of the last attempt: human, machine, fax, unknown
 	TwilioCallSID string             `bson:"twilio_call_sid" json:"twilio_call_sid"`
 	Language      string             `bson:"language" json:"language"`
-	Duration      int                `bson:"duration" json:"duration"` // in seconds
+	Duration      int                `bson:"duration" json:"duration"`                       // in seconds
+	MenuPath      []string           `bson:"menu_path,omitempty" json:"menu_path,omitempty"` // keys pressed to reach the current menu node
+	Collected     map[string]string  `bson:"collected,omitempty" json:"collected,omitempty"` // values keyed in through collect actions
+	Messages      []CallerMessage    `bson:"messages,omitempty" json:"messages,omitempty"`   // messages left through record actions
+	Attempts      int                `bson:"attempts" json:"attempts"`
+	NextAttemptAt *time.Time         `bson:"next_attempt_at,omitempty" json:"next_attempt_at,omitempty"` // when a scheduled call is dialed
+	ClaimedAt     *time.Time         `bson:"claimed_at,omitempty" json:"claimed_at,omitempty"`           // when the call scheduler took the call for redialing
 	ErrorMessage  string             `bson:"error_message,omitempty" json:"error_message,omitempty"`
+	CreatedBy     string             `bson:"created_by,omitempty" json:"created_by,omitempty"`
 	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
+// CallAttempt records a single dial attempt of a call
+type CallAttempt struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CallID        primitive.ObjectID `bson:"call_id" json:"call_id"`
+	CampaignID    primitive.ObjectID `bson:"campaign_id" json:"campaign_id"`
+	AttemptNumber int                `bson:"attempt_number" json:"attempt_number"`
+	TwilioCallSID string             `bson:"twilio_call_sid,omitempty" json:"twilio_call_sid,omitempty"`
+	Outcome       string             `bson:"outcome,omitempty" json:"outcome,omitempty"` // empty while the attempt is in progress
+	Duration      int                `bson:"duration" json:"duration"`                   // in seconds
+	ErrorMessage  string             `bson:"error_message,omitempty" json:"error_message,omitempty"`
+	StartedAt     time.Time          `bson:"started_at" json:"started_at"`
+	EndedAt       *time.Time         `bson:"ended_at,omitempty" json:"ended_at,omitempty"`
+}
+
+// Recording is a call recording kept by the telephony provider. The audio is
+// only available through the API's stream proxy.
+type Recording struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CallID        primitive.ObjectID `bson:"call_id" json:"call_id"`
+	CampaignID    primitive.ObjectID `bson:"campaign_id" json:"campaign_id"`
+	RecordingSID  string             `bson:"recording_sid" json:"recording_sid"`
+	TwilioCallSID string             `bson:"twilio_call_sid" json:"twilio_call_sid"`
+	Source        string             `bson:"source" json:"source"` // call, forward or message
+	Status        string             `bson:"status" json:"status"` // in-progress, completed, absent, failed
+	Channels      int                `bson:"channels" json:"channels"`
+	Duration      int                `bson:"duration" json:"duration"` // in seconds
+	MediaURL      string             `bson:"media_url,omitempty" json:"-"`
+	ExpiresAt     *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"` // deleted by the retention job after this time
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// CallerMessage is a message a caller left through a "record" action
+type CallerMessage struct {
+	ActionInput         string    `bson:"action_input" json:"action_input"` // key of the record action
+	RecordingSID        string    `bson:"recording_sid" json:"recording_sid"`
+	RecordingURL        string    `bson:"recording_url" json:"recording_url"`
+	Duration            int       `bson:"duration" json:"duration"` // in seconds
+	Transcript          string    `bson:"transcript,omitempty" json:"transcript,omitempty"`
+	TranscriptionStatus string    `bson:"transcription_status,omitempty" json:"transcription_status,omitempty"` // pending, completed or failed
+	CreatedAt           time.Time `bson:"created_at" json:"created_at"`
+}
+
+// DialStatusUpdate represents the <Dial> action callback from Twilio
+type DialStatusUpdate struct {
+	CallSid          string `form:"CallSid"`
+	DialCallSid      string `form:"DialCallSid"`
+	DialCallStatus   string `form:"DialCallStatus"` // completed, answered, busy, no-answer, failed or canceled
+	DialCallDuration string `form:"DialCallDuration"`
+}
+
+// TranscriptionUpdate represents a transcription callback from Twilio
+type TranscriptionUpdate struct {
+	CallSid             string `form:"CallSid"`
+	RecordingSid        string `form:"RecordingSid"`
+	TranscriptionSid    string `form:"TranscriptionSid"`
+	TranscriptionText   string `form:"TranscriptionText"`
+	TranscriptionStatus string `form:"TranscriptionStatus"` // completed or failed
+}
+
+// RecordingStatusUpdate represents a recording status callback from Twilio
+type RecordingStatusUpdate struct {
+	CallSid           string `form:"CallSid"`
+	RecordingSid      string `form:"RecordingSid"`
+	RecordingURL      string `form:"RecordingUrl"`
+	RecordingStatus   string `form:"RecordingStatus"`
+	RecordingDuration string `form:"RecordingDuration"`
+	RecordingChannels string `form:"RecordingChannels"`
+	RecordingSource   string `form:"RecordingSource"` // OutboundAPI for whole calls, DialVerb for forwards, RecordVerb for caller messages
+	ErrorCode         string `form:"ErrorCode"`
+}
+
 // CallLog represents detailed logs for each call
 type CallLog struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	CallID    primitive.ObjectID `bson:"call_id" json:"call_id"`
-	Event     string             `bson:"event" json:"event"` // initiated, answered, input_received, completed, failed
-	Details   string             `bson:"details" json:"details"`
-	UserInput string             `bson:"user_input,omitempty" json:"user_input,omitempty"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CallID     primitive.ObjectID `bson:"call_id" json:"call_id"`
+	Event      string             `bson:"event" json:"event"` // initiated, menu_played, input_received, action_<type>_executed, completed, failed, ...
+	Details    string             `bson:"details" json:"details"`
+	UserInput  string             `bson:"user_input,omitempty" json:"user_input,omitempty"`
+	Transcript string             `bson:"transcript,omitempty" json:"transcript,omitempty"` // what the caller said, for speech input
+	Confidence float64            `bson:"confidence,omitempty" json:"confidence,omitempty"` // recognition confidence of the transcript, 0 to 1
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
 }
 
-// BulkCallRequest represents the request to initiate bulk calls
+// BulkCallRequest represents the request to initiate bulk calls.
+// Contacts are given either inline or as a stored contact list.
 type BulkCallRequest struct {
-	CampaignID string           `json:"campaign_id" binding:"required"`
-	Language   string           `json:"language"`
-	Contacts   []ContactRequest `json:"contacts" binding:"required,min=1"`
+	CampaignID    string           `json:"campaign_id" binding:"required"`
+	Language      string           `json:"language"`
+	Contacts      []ContactRequest `json:"contacts"`
+	ContactListID string           `json:"contact_list_id"`
+	DefaultRegion string           `json:"default_region"` // ISO country of inline numbers written without a country code
 }
 
 // ContactRequest represents a single contact in bulk call request
 type ContactRequest struct {
-	PhoneNumber string `json:"phone_number" binding:"required"`
-	Name        string `json:"name"`
+	PhoneNumber string            `json:"phone_number" binding:"required"`
+	Name        string            `json:"name"`
+	Fields      map[string]string `json:"fields,omitempty"` // custom template variables such as amount or due_date
 }
 
 // CallStatusUpdate represents webhook data from Twilio
@@ -72,10 +290,118 @@ type CallStatusUpdate struct {
 	CallDuration string `form:"CallDuration" json:"call_duration"`
 	From         string `form:"From" json:"from"`
 	To           string `form:"To" json:"to"`
+	AnsweredBy   string `form:"AnsweredBy" json:"answered_by"` // machine detection result, e.g. human or machine_end_beep
 }
 
 // IVRInput represents user input during IVR call
 type IVRInput struct {
-	CallSid string `form:"CallSid" json:"call_sid"`
-	Digits  string `form:"Digits" json:"digits"`
+	CallSid      string  `form:"CallSid" json:"call_sid"`
+	Digits       string  `form:"Digits" json:"digits"`
+	SpeechResult string  `form:"SpeechResult" json:"speech_result"` // transcript when the caller spoke
+	Confidence   float64 `form:"Confidence" json:"confidence"`
+}
+
+// DNCEntry represents a phone number on the do-not-call list
+type DNCEntry struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	PhoneNumber string              `bson:"phone_number" json:"phone_number"` // normalized phone number
+	Source      string              `bson:"source" json:"source"`             // opt_out, manual, import
+	Reason      string              `bson:"reason,omitempty" json:"reason,omitempty"`
+	CampaignID  *primitive.ObjectID `bson:"campaign_id,omitempty" json:"campaign_id,omitempty"` // campaign the caller opted out of
+	CallID      *primitive.ObjectID `bson:"call_id,omitempty" json:"call_id,omitempty"`         // call the caller opted out on
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// DNCRequest represents a request to add a number to the do-not-call list
+type DNCRequest struct {
+	PhoneNumber   string `json:"phone_number" binding:"required"`
+	Reason        string `json:"reason"`
+	DefaultRegion string `json:"default_region"` // ISO country of a number written without a country code
+}
+
+// DialJob represents a bulk call request that is dialed in the background
+type DialJob struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	CampaignID    primitive.ObjectID  `bson:"campaign_id" json:"campaign_id"`
+	Language      string              `bson:"language" json:"language"`
+	Status        string              `bson:"status" json:"status"` // queued, running, completed, failed
+	Contacts      []DialJobContact    `bson:"contacts" json:"contacts,omitempty"`
+	Total         int                 `bson:"total" json:"total"`
+	Processed     int                 `bson:"processed" json:"processed"`
+	SuccessCount  int                 `bson:"success_count" json:"success_count"`
+	FailCount     int                 `bson:"fail_count" json:"fail_count"`
+	DNCCount      int                 `bson:"dnc_count" json:"dnc_count"`
+	HeldCount     int                 `bson:"held_count" json:"held_count"` // calls held until the recipient's calling window opens
+	ErrorMessage  string              `bson:"error_message,omitempty" json:"error_message,omitempty"`
+	ContactListID *primitive.ObjectID `bson:"contact_list_id,omitempty" json:"contact_list_id,omitempty"` // list the contacts were taken from
+	CreatedBy     string              `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt     time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time           `bson:"updated_at" json:"updated_at"`
+	StartedAt     *time.Time          `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	CompletedAt   *time.Time          `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+}
+
+// DialJobContact tracks a single contact within a dial job
+type DialJobContact struct {
+	PhoneNumber  string              `bson:"phone_number" json:"phone_number"`
+	Name         string              `bson:"name" json:"name"`
+	Fields       map[string]string   `bson:"fields,omitempty" json:"fields,omitempty"`
+	Status       string              `bson:"status" json:"status"` // pending, dialing, initiated, scheduled, failed, dnc
+	CallID       *primitive.ObjectID `bson:"call_id,omitempty" json:"call_id,omitempty"`
+	ErrorMessage string              `bson:"error_message,omitempty" json:"error_message,omitempty"`
+}
+
+// ContactList is a stored, validated list of contacts that can be dialed by any campaign
+type ContactList struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name         string             `bson:"name" json:"name"`
+	Description  string             `bson:"description,omitempty" json:"description,omitempty"`
+	SourceFile   string             `bson:"source_file,omitempty" json:"source_file,omitempty"`
+	Contacts     []ListContact      `bson:"contacts" json:"contacts,omitempty"`
+	Fields       []string           `bson:"fields,omitempty" json:"fields,omitempty"` // custom field names of the contacts
+	Total        int                `bson:"total" json:"total"`
+	InvalidCount int                `bson:"invalid_count" json:"invalid_count"` // rows rejected at upload, including duplicates
+	CreatedBy    string             `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// ListContact is a single contact of a contact list
+type ListContact struct {
+	PhoneNumber string            `bson:"phone_number" json:"phone_number"` // normalized phone number
+	Name        string            `bson:"name,omitempty" json:"name,omitempty"`
+	Fields      map[string]string `bson:"fields,omitempty" json:"fields,omitempty"`
+}
+
+// ContactRowError reports an uploaded row that was not added to a contact list
+type ContactRowError struct {
+	Line  int    `json:"line"`
+	Value string `json:"value,omitempty"`
+	Error string `json:"error"`
+}
+
+// CallEvent is a real-time change of a call, streamed to dashboards
+type CallEvent struct {
+	Type        string             `json:"type"` // inbound, initiated, scheduled, ringing, answered, machine_detected, voicemail_left, digit_pressed, speech_received, input_collected, message_recorded, completed, failed, retry_scheduled
+	CampaignID  primitive.ObjectID `json:"campaign_id"`
+	CallID      primitive.ObjectID `json:"call_id"`
+	PhoneNumber string             `json:"phone_number,omitempty"`
+	Status      string             `json:"status,omitempty"`  // call status after the change
+	Outcome     string             `json:"outcome,omitempty"` // Twilio status for finished attempts
+	Digits      string             `json:"digits,omitempty"`
+	Details     string             `json:"details,omitempty"`
+	Timestamp   time.Time          `json:"timestamp"`
+}
+
+// LanguagePack holds the IVR messages of one language. Codes without a pack
+// use the pack of their fallback chain, e.g. pt-BR → pt → en.
+type LanguagePack struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Code      string             `bson:"code" json:"code"`                             // language tag such as "en" or "pt-BR"
+	Name      string             `bson:"name" json:"name"`                             // display name, e.g. "Português (Brasil)"
+	Fallback  string             `bson:"fallback,omitempty" json:"fallback,omitempty"` // next pack in the chain; defaults to the parent tag, then "en"
+	Strings   map[string]string  `bson:"strings" json:"strings"`                       // message key → text; every key is required
+	UpdatedBy string             `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
 }
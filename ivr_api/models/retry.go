@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// ShouldRetry reports whether a call that ended with outcome after the given
+// number of attempts should be dialed again
+func (p *RetryPolicy) ShouldRetry(outcome string, attempts int) bool {
+	if p == nil || attempts >= p.MaxAttempts {
+		return false
+	}
+	for _, retryable := range p.RetryOn {
+		if retryable == outcome {
+			return true
+		}
+	}
+	return false
+}
+
+// NextAttempt returns when the next attempt should be placed
+func (p *RetryPolicy) NextAttempt(now time.Time) time.Time {
+	return now.Add(time.Duration(p.BackoffMinutes) * time.Minute)
+}
@@ -1,52 +1,156 @@
 package routes
 
 import (
+	"context"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/prabhatkumar/ivrcalling/config"
 	"github.com/prabhatkumar/ivrcalling/database"
 	"github.com/prabhatkumar/ivrcalling/handlers"
+	"github.com/prabhatkumar/ivrcalling/middleware"
+	"github.com/prabhatkumar/ivrcalling/models"
 	"github.com/prabhatkumar/ivrcalling/services"
 )
 
-func SetupRoutes(router *gin.Engine, db *database.MongoDB, cfg *config.Config) {
+// SetupRoutes registers all routes and starts the background services they rely on.
+// Background services stop when ctx is cancelled.
+func SetupRoutes(ctx context.Context, router *gin.Engine, db *database.MongoDB, cfg *config.Config) {
 	// Enable CORS for frontend
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:5173"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 	}))
 
-	twilioService := services.NewTwilioService(cfg)
-	campaignHandler := handlers.NewCampaignHandler(db)
-	callHandler := handlers.NewCallHandler(db, twilioService)
-	webhookHandler := handlers.NewWebhookHandler(db)
+	provider := services.NewTelephonyProvider(cfg)
+	authService := services.NewAuthService(db, cfg)
+	dncService := services.NewDNCService(db)
+	eventBus := services.NewEventBus()
+	dialer := services.NewDialer(db, provider, dncService, eventBus)
+	dialQueue := services.NewDialQueue(db, dialer, cfg)
+	dialQueue.Start(ctx)
+	callScheduler := services.NewCallScheduler(db, dialQueue, dialer, dncService, eventBus)
+	callScheduler.Start(ctx)
+	languagePacks := services.NewLanguagePackService(db)
+	languagePacks.Start(ctx)
+	recordingService := services.NewRecordingService(db, provider, cfg)
+	recordingService.Start(ctx)
+	businessHours := services.NewBusinessHoursService(db)
+	inbound := services.NewInboundService(db, eventBus)
+
+	campaignHandler := handlers.NewCampaignHandler(db, businessHours, inbound)
+	callHandler := handlers.NewCallHandler(db, dncService, dialer, dialQueue, eventBus)
+	jobHandler := handlers.NewJobHandler(db)
+	webhookHandler := handlers.NewWebhookHandler(db, dncService, eventBus, dialer, provider, businessHours, inbound)
+	eventHandler := handlers.NewEventHandler(db, eventBus)
+	analyticsHandler := handlers.NewAnalyticsHandler(db, services.NewAnalyticsService(db))
+	contactListHandler := handlers.NewContactListHandler(db)
+	dncHandler := handlers.NewDNCHandler(db, dncService)
+	authHandler := handlers.NewAuthHandler(db, authService)
+	languagePackHandler := handlers.NewLanguagePackHandler(db, languagePacks)
+	recordingHandler := handlers.NewRecordingHandler(db, recordingService)
+	calendarHandler := handlers.NewCalendarHandler(db)
+
+	authenticate := middleware.Authenticate(cfg, authService)
+	readOnly := middleware.RequireRole(models.RoleReadOnly)
+	campaignManager := middleware.RequireRole(models.RoleCampaignManager)
+	admin := middleware.RequireRole(models.RoleAdmin)
 
 	api := router.Group("/api")
 	{
-		campaigns := api.Group("/campaigns")
+		campaigns := api.Group("/campaigns", authenticate)
+		{
+			campaigns.POST("", campaignManager, campaignHandler.CreateCampaign)
+			campaigns.GET("", readOnly, campaignHandler.ListCampaigns)
+			campaigns.GET("/:id", readOnly, campaignHandler.GetCampaign)
+			campaigns.PUT("/:id", campaignManager, campaignHandler.UpdateCampaign)
+			campaigns.DELETE("/:id", campaignManager, campaignHandler.DeleteCampaign)
+			campaigns.GET("/:id/calls", readOnly, callHandler.GetCampaignCalls)
+			campaigns.GET("/:id/analytics", readOnly, analyticsHandler.GetCampaignAnalytics)
+		}
+
+		// EventSource cannot send headers, so this stream also accepts a bearer token in the query string
+		api.GET("/campaigns/:id/events", middleware.QueryCredentials(), authenticate, readOnly, eventHandler.StreamCampaignEvents)
+
+		calls := api.Group("/calls", authenticate)
+		{
+			calls.POST("/bulk", campaignManager, callHandler.InitiateBulkCalls)
+			calls.GET("/:id", readOnly, callHandler.GetCallStatus)
+		}
+
+		jobs := api.Group("/jobs", authenticate, readOnly)
+		{
+			jobs.GET("/:id", jobHandler.GetDialJob)
+		}
+
+		recordings := api.Group("/recordings", authenticate)
+		{
+			recordings.GET("", readOnly, recordingHandler.ListRecordings)
+			recordings.GET("/:id", readOnly, recordingHandler.GetRecording)
+			recordings.GET("/:id/media", campaignManager, recordingHandler.StreamRecording)
+			recordings.DELETE("/:id", admin, recordingHandler.DeleteRecording)
+		}
+
+		contactLists := api.Group("/contact-lists", authenticate)
+		{
+			contactLists.POST("", campaignManager, contactListHandler.UploadContactList)
+			contactLists.GET("", readOnly, contactListHandler.ListContactLists)
+			contactLists.GET("/:id", readOnly, contactListHandler.GetContactList)
+			contactLists.DELETE("/:id", campaignManager, contactListHandler.DeleteContactList)
+		}
+
+		dnc := api.Group("/dnc", authenticate)
+		{
+			dnc.GET("", readOnly, dncHandler.ListDNC)
+			dnc.POST("", campaignManager, dncHandler.AddDNC)
+			dnc.POST("/import", campaignManager, dncHandler.ImportDNC)
+			dnc.GET("/:phone", readOnly, dncHandler.GetDNC)
+			dnc.DELETE("/:phone", campaignManager, dncHandler.DeleteDNC)
+		}
+
+		calendars := api.Group("/business-calendars", authenticate)
+		{
+			calendars.GET("", readOnly, calendarHandler.ListCalendars)
+			calendars.GET("/:id", readOnly, calendarHandler.GetCalendar)
+			calendars.POST("", campaignManager, calendarHandler.CreateCalendar)
+			calendars.PUT("/:id", campaignManager, calendarHandler.UpdateCalendar)
+			calendars.DELETE("/:id", campaignManager, calendarHandler.DeleteCalendar)
+		}
+
+		languagePackRoutes := api.Group("/language-packs", authenticate)
 		{
-			campaigns.POST("", campaignHandler.CreateCampaign)
-			campaigns.GET("", campaignHandler.ListCampaigns)
-			campaigns.GET("/:id", campaignHandler.GetCampaign)
-			campaigns.PUT("/:id", campaignHandler.UpdateCampaign)
-			campaigns.DELETE("/:id", campaignHandler.DeleteCampaign)
-			campaigns.GET("/:id/calls", callHandler.GetCampaignCalls)
+			languagePackRoutes.GET("", readOnly, languagePackHandler.ListLanguagePacks)
+			languagePackRoutes.GET("/:code", readOnly, languagePackHandler.GetLanguagePack)
+			languagePackRoutes.POST("", admin, languagePackHandler.CreateLanguagePack)
+			languagePackRoutes.PUT("/:code", admin, languagePackHandler.UpdateLanguagePack)
+			languagePackRoutes.DELETE("/:code", admin, languagePackHandler.DeleteLanguagePack)
 		}
 
-		calls := api.Group("/calls")
+		auth := api.Group("/auth", authenticate)
 		{
-			calls.POST("/bulk", callHandler.InitiateBulkCalls)
-			calls.GET("/:id", callHandler.GetCallStatus)
+			auth.GET("/me", authHandler.GetCurrentPrincipal)
+			auth.POST("/token", authHandler.IssueToken)
+			auth.GET("/keys", admin, authHandler.ListAPIKeys)
+			auth.POST("/keys", admin, authHandler.CreateAPIKey)
+			auth.DELETE("/keys/:id", admin, authHandler.DeleteAPIKey)
 		}
 
-		webhook := api.Group("/webhook")
+		webhook := api.Group("/webhook", middleware.TwilioSignature(cfg))
 		{
 			webhook.POST("/voice", webhookHandler.HandleVoiceWebhook)
 			webhook.POST("/gather", webhookHandler.HandleGatherWebhook)
+			webhook.POST("/collect", webhookHandler.HandleCollectWebhook)
+			webhook.POST("/record", webhookHandler.HandleRecordWebhook)
+			webhook.POST("/transcription", webhookHandler.HandleTranscriptionWebhook)
+			webhook.POST("/forward", webhookHandler.HandleForwardWebhook)
+			webhook.POST("/whisper", webhookHandler.HandleWhisperWebhook)
 			webhook.POST("/status", callHandler.HandleStatusWebhook)
+			webhook.POST("/amd", webhookHandler.HandleMachineDetectionWebhook)
+			webhook.POST("/voicemail", webhookHandler.HandleVoicemailWebhook)
+			webhook.POST("/recording", recordingHandler.HandleRecordingWebhook)
 			webhook.POST("/optout", webhookHandler.HandleOptOutConfirm)
 		}
 
@@ -60,6 +164,7 @@ func SetupRoutes(router *gin.Engine, db *database.MongoDB, cfg *config.Config) {
 		api.GET("/languages", func(c *gin.Context) {
 			c.JSON(200, gin.H{
 				"languages": services.GetSupportedLanguages(),
+				"voices":    services.Voices,
 			})
 		})
 	}
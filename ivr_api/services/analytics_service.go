@@ -0,0 +1,415 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/prabhatkumar/ivrcalling/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// bucketFormats are the $dateToString formats used to group calls by time
+var bucketFormats = map[string]string{
+	"hour": "%Y-%m-%dT%H:00:00%z",
+	"day":  "%Y-%m-%dT00:00:00%z",
+}
+
+// AnalyticsQuery selects the calls of a campaign to analyse
+type AnalyticsQuery struct {
+	From     *time.Time
+	To       *time.Time
+	Bucket   string // "", "hour" or "day"
+	Timezone string // IANA zone for bucket boundaries, default UTC
+}
+
+// CampaignStats is the funnel and duration summary of a set of calls.
+// Rates after the answer rate are relative to answered calls.
+type CampaignStats struct {
+	Start *time.Time `json:"start,omitempty"` // bucket start, omitted for the overall summary
+
+	TotalCalls  int `json:"total_calls"`
+	Answered    int `json:"answered"`
+	ReachedMenu int `json:"reached_menu"`
+	PressedKey  int `json:"pressed_key"`
+	Forwarded   int `json:"forwarded"`
+	OptedOut    int `json:"opted_out"`
+
+	AnsweredBy AnsweredByStats `json:"answered_by"`
+	Voicemails int             `json:"voicemails_left"`
+
+	AnswerRate      float64 `json:"answer_rate"`
+	ReachedMenuRate float64 `json:"reached_menu_rate"`
+	ForwardRate     float64 `json:"forward_rate"`
+	OptOutRate      float64 `json:"opt_out_rate"`
+	MachineRate     float64 `json:"machine_rate"`
+
+	Duration   DurationStats `json:"duration"`
+	KeyPresses []KeyPress    `json:"key_presses"`
+
+	durations []durationCount
+}
+
+// durationCount is how many answered calls lasted a given number of seconds.
+// Durations are grouped into these counts inside the pipeline so the result
+// stays small however many calls a campaign makes.
+type durationCount struct {
+	Seconds int
+	Count   int
+}
+
+// AnsweredByStats counts calls by answering machine detection result.
+// Calls of campaigns without detection are not counted.
+type AnsweredByStats struct {
+	Human   int `json:"human" bson:"human"`
+	Machine int `json:"machine" bson:"machine"`
+	Fax     int `json:"fax" bson:"fax"`
+	Unknown int `json:"unknown" bson:"unknown"`
+}
+
+// DurationStats summarises the duration in seconds of answered calls
+type DurationStats struct {
+	Average float64 `json:"average"`
+	Median  float64 `json:"median"`
+	P95     float64 `json:"p95"`
+}
+
+// KeyPress counts how often a key triggered an action
+type KeyPress struct {
+	Digit      string  `json:"digit"`
+	ActionType string  `json:"action_type"`
+	Count      int     `json:"count"`
+	Share      float64 `json:"share"` // of all action key presses
+}
+
+// CampaignAnalytics is the result of an analytics query
+type CampaignAnalytics struct {
+	Summary CampaignStats   `json:"summary"`
+	Buckets []CampaignStats `json:"buckets,omitempty"`
+}
+
+// AnalyticsService computes campaign analytics with aggregation pipelines
+type AnalyticsService struct {
+	db *database.MongoDB
+}
+
+func NewAnalyticsService(db *database.MongoDB) *AnalyticsService {
+	return &AnalyticsService{db: db}
+}
+
+// CampaignAnalytics computes the call funnel, key press distribution and
+// duration statistics of a campaign's outbound calls, optionally bucketed by
+// hour or day. Inbound calls are always answered and would inflate the rates.
+func (s *AnalyticsService) CampaignAnalytics(ctx context.Context, campaignID primitive.ObjectID, query AnalyticsQuery) (*CampaignAnalytics, error) {
+	match := bson.M{"campaign_id": campaignID, "direction": bson.M{"$ne": CallInbound}}
+	if query.From != nil || query.To != nil {
+		createdAt := bson.M{}
+		if query.From != nil {
+			createdAt["$gte"] = *query.From
+		}
+		if query.To != nil {
+			createdAt["$lt"] = *query.To
+		}
+		match["created_at"] = createdAt
+	}
+
+	var bucketKey interface{} = "all"
+	if query.Bucket != "" {
+		format, ok := bucketFormats[query.Bucket]
+		if !ok {
+			return nil, fmt.Errorf("bucket must be 'hour' or 'day'")
+		}
+		timezone := query.Timezone
+		if timezone == "" {
+			timezone = "UTC"
+		}
+		bucketKey = bson.M{"$dateToString": bson.M{"format": format, "date": "$created_at", "timezone": timezone}}
+	}
+
+	hasEvent := func(event string) bson.M {
+		return bson.M{"$cond": bson.A{bson.M{"$in": bson.A{event, "$events"}}, 1, 0}}
+	}
+	answeredBy := func(result string) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$answered_by", result}}, 1, 0}}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "call_logs",
+			"localField":   "_id",
+			"foreignField": "call_id",
+			"as":           "logs",
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"bucket":      bucketKey,
+			"duration":    1,
+			"answered_by": 1,
+			"events":      "$logs.event",
+			"answered": bson.M{"$or": bson.A{
+				bson.M{"$in": bson.A{"$status", bson.A{"in-progress", "completed"}}},
+				bson.M{"$eq": bson.A{"$outcome", "completed"}},
+				bson.M{"$gt": bson.A{"$duration", 0}},
+			}},
+			// Actions triggered by a key press, with the key taken from the
+			// log's user_input or, for older logs, from its details text
+			"actions": bson.M{"$map": bson.M{
+				"input": bson.M{"$filter": bson.M{
+					"input": "$logs",
+					"as":    "log",
+					"cond":  bson.M{"$regexMatch": bson.M{"input": "$$log.event", "regex": "^action_.+_executed$"}},
+				}},
+				"as": "log",
+				"in": bson.M{
+					"event": "$$log.event",
+					"digit": bson.M{"$ifNull": bson.A{
+						"$$log.user_input",
+						bson.M{"$arrayElemAt": bson.A{
+							bson.M{"$ifNull": bson.A{
+								bson.M{"$let": bson.M{
+									"vars": bson.M{"match": bson.M{"$regexFind": bson.M{"input": "$$log.details", "regex": "pressed (\\S+)"}}},
+									"in":   "$$match.captures",
+								}},
+								bson.A{""},
+							}},
+							0,
+						}},
+					}},
+				},
+			}},
+		}}},
+		{{Key: "$facet", Value: bson.M{
+			"funnel": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":          "$bucket",
+					"total_calls":  bson.M{"$sum": 1},
+					"answered":     bson.M{"$sum": bson.M{"$cond": bson.A{"$answered", 1, 0}}},
+					"reached_menu": bson.M{"$sum": hasEvent("menu_played")},
+					"pressed_key":  bson.M{"$sum": hasEvent("input_received")},
+					"forwarded":    bson.M{"$sum": hasEvent("action_forward_executed")},
+					"opted_out":    bson.M{"$sum": hasEvent("opted_out")},
+					"voicemails":   bson.M{"$sum": hasEvent("voicemail_left")},
+					"human":        answeredBy(AnsweredByHuman),
+					"machine":      answeredBy(AnsweredByMachine),
+					"fax":          answeredBy(AnsweredByFax),
+					"unknown":      answeredBy(AnsweredByUnknown),
+				}},
+			},
+			"durations": bson.A{
+				bson.M{"$match": bson.M{"answered": true, "duration": bson.M{"$gt": 0}}},
+				bson.M{"$group": bson.M{
+					"_id":   bson.M{"bucket": "$bucket", "seconds": "$duration"},
+					"count": bson.M{"$sum": 1},
+				}},
+			},
+			"key_presses": bson.A{
+				bson.M{"$unwind": "$actions"},
+				bson.M{"$group": bson.M{
+					"_id": bson.M{
+						"bucket": "$bucket",
+						"digit":  "$actions.digit",
+						"event":  "$actions.event",
+					},
+					"count": bson.M{"$sum": 1},
+				}},
+			},
+		}}},
+	}
+
+	cursor, err := s.db.Collection("calls").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate campaign analytics: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var facets []struct {
+		Funnel []struct {
+			Bucket      string `bson:"_id"`
+			TotalCalls  int    `bson:"total_calls"`
+			Answered    int    `bson:"answered"`
+			ReachedMenu int    `bson:"reached_menu"`
+			PressedKey  int    `bson:"pressed_key"`
+			Forwarded   int    `bson:"forwarded"`
+			OptedOut    int    `bson:"opted_out"`
+			Voicemails  int    `bson:"voicemails"`
+
+			AnsweredBy AnsweredByStats `bson:",inline"`
+		} `bson:"funnel"`
+		KeyPresses []struct {
+			ID struct {
+				Bucket string `bson:"bucket"`
+				Digit  string `bson:"digit"`
+				Event  string `bson:"event"`
+			} `bson:"_id"`
+			Count int `bson:"count"`
+		} `bson:"key_presses"`
+		Durations []struct {
+			ID struct {
+				Bucket  string `bson:"bucket"`
+				Seconds int    `bson:"seconds"`
+			} `bson:"_id"`
+			Count int `bson:"count"`
+		} `bson:"durations"`
+	}
+	if err := cursor.All(ctx, &facets); err != nil {
+		return nil, fmt.Errorf("failed to decode campaign analytics: %w", err)
+	}
+
+	result := &CampaignAnalytics{}
+	summary := &result.Summary
+	buckets := map[string]*CampaignStats{}
+
+	if len(facets) > 0 {
+		for _, row := range facets[0].Funnel {
+			stats := &CampaignStats{
+				TotalCalls:  row.TotalCalls,
+				Answered:    row.Answered,
+				ReachedMenu: row.ReachedMenu,
+				PressedKey:  row.PressedKey,
+				Forwarded:   row.Forwarded,
+				OptedOut:    row.OptedOut,
+				Voicemails:  row.Voicemails,
+				AnsweredBy:  row.AnsweredBy,
+			}
+			if query.Bucket != "" {
+				if start, err := time.Parse("2006-01-02T15:04:05-0700", row.Bucket); err == nil {
+					stats.Start = &start
+				}
+			}
+			buckets[row.Bucket] = stats
+
+			summary.TotalCalls += stats.TotalCalls
+			summary.Answered += stats.Answered
+			summary.ReachedMenu += stats.ReachedMenu
+			summary.PressedKey += stats.PressedKey
+			summary.Forwarded += stats.Forwarded
+			summary.OptedOut += stats.OptedOut
+			summary.Voicemails += stats.Voicemails
+			summary.AnsweredBy.Human += stats.AnsweredBy.Human
+			summary.AnsweredBy.Machine += stats.AnsweredBy.Machine
+			summary.AnsweredBy.Fax += stats.AnsweredBy.Fax
+			summary.AnsweredBy.Unknown += stats.AnsweredBy.Unknown
+		}
+
+		for _, row := range facets[0].Durations {
+			count := durationCount{Seconds: row.ID.Seconds, Count: row.Count}
+			if stats, ok := buckets[row.ID.Bucket]; ok {
+				stats.durations = append(stats.durations, count)
+			}
+			summary.durations = append(summary.durations, count)
+		}
+
+		for _, row := range facets[0].KeyPresses {
+			press := KeyPress{Digit: row.ID.Digit, ActionType: actionTypeFromEvent(row.ID.Event), Count: row.Count}
+			if stats, ok := buckets[row.ID.Bucket]; ok {
+				stats.KeyPresses = addKeyPress(stats.KeyPresses, press)
+			}
+			summary.KeyPresses = addKeyPress(summary.KeyPresses, press)
+		}
+	}
+
+	summary.finish()
+	if query.Bucket != "" {
+		result.Buckets = make([]CampaignStats, 0, len(buckets))
+		for _, stats := range buckets {
+			stats.finish()
+			result.Buckets = append(result.Buckets, *stats)
+		}
+		sort.Slice(result.Buckets, func(i, j int) bool {
+			a, b := result.Buckets[i].Start, result.Buckets[j].Start
+			return a != nil && b != nil && a.Before(*b)
+		})
+	}
+
+	return result, nil
+}
+
+// finish derives the rates, duration statistics and key press shares
+func (s *CampaignStats) finish() {
+	s.AnswerRate = ratio(s.Answered, s.TotalCalls)
+	s.ReachedMenuRate = ratio(s.ReachedMenu, s.Answered)
+	s.ForwardRate = ratio(s.Forwarded, s.Answered)
+	s.OptOutRate = ratio(s.OptedOut, s.Answered)
+	s.MachineRate = ratio(s.AnsweredBy.Machine, s.Answered)
+
+	if len(s.durations) > 0 {
+		sort.Slice(s.durations, func(i, j int) bool { return s.durations[i].Seconds < s.durations[j].Seconds })
+		sum, calls := 0, 0
+		for _, d := range s.durations {
+			sum += d.Seconds * d.Count
+			calls += d.Count
+		}
+		s.Duration = DurationStats{
+			Average: round2(float64(sum) / float64(calls)),
+			Median:  percentile(s.durations, calls, 50),
+			P95:     percentile(s.durations, calls, 95),
+		}
+	}
+
+	presses := 0
+	for _, press := range s.KeyPresses {
+		presses += press.Count
+	}
+	for i := range s.KeyPresses {
+		s.KeyPresses[i].Share = ratio(s.KeyPresses[i].Count, presses)
+	}
+	sort.Slice(s.KeyPresses, func(i, j int) bool {
+		if s.KeyPresses[i].Count != s.KeyPresses[j].Count {
+			return s.KeyPresses[i].Count > s.KeyPresses[j].Count
+		}
+		return s.KeyPresses[i].Digit < s.KeyPresses[j].Digit
+	})
+	if s.KeyPresses == nil {
+		s.KeyPresses = []KeyPress{}
+	}
+}
+
+func addKeyPress(presses []KeyPress, press KeyPress) []KeyPress {
+	for i := range presses {
+		if presses[i].Digit == press.Digit && presses[i].ActionType == press.ActionType {
+			presses[i].Count += press.Count
+			return presses
+		}
+	}
+	return append(presses, press)
+}
+
+// actionTypeFromEvent turns "action_forward_executed" into "forward"
+func actionTypeFromEvent(event string) string {
+	const prefix, suffix = "action_", "_executed"
+	if len(event) > len(prefix)+len(suffix) {
+		return event[len(prefix) : len(event)-len(suffix)]
+	}
+	return event
+}
+
+// percentile returns the nearest-rank percentile of total calls counted
+// in durations, sorted by seconds
+func percentile(sorted []durationCount, total int, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(total)))
+	if rank < 1 {
+		rank = 1
+	}
+	for _, d := range sorted {
+		rank -= d.Count
+		if rank <= 0 {
+			return float64(d.Seconds)
+		}
+	}
+	return float64(sorted[len(sorted)-1].Seconds)
+}
+
+func ratio(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return round2(float64(part) / float64(total))
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prabhatkumar/ivrcalling/config"
+	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// apiKeyPrefix marks keys issued by this service
+const apiKeyPrefix = "ivr_"
+
+// ErrInvalidCredentials is returned for unknown API keys and invalid or expired tokens
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// ErrTokensDisabled is returned when bearer tokens are used without a JWT secret
+var ErrTokensDisabled = errors.New("JWT authentication is not configured")
+
+// AuthService issues and verifies API keys and JWT bearer tokens
+type AuthService struct {
+	db          *database.MongoDB
+	adminAPIKey string
+	jwtSecret   []byte
+	tokenTTL    time.Duration
+}
+
+type jwtClaims struct {
+	Subject   string `json:"sub"`
+	Name      string `json:"name,omitempty"`
+	Role      string `json:"role"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+func NewAuthService(db *database.MongoDB, cfg *config.Config) *AuthService {
+	ttl := time.Duration(cfg.JWTTTLMinutes) * time.Minute
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+
+	return &AuthService{
+		db:          db,
+		adminAPIKey: cfg.AdminAPIKey,
+		jwtSecret:   []byte(cfg.JWTSecret),
+		tokenTTL:    ttl,
+	}
+}
+
+// CreateAPIKey generates and stores a new API key. The plain key is returned
+// only here; afterwards just its hash is known.
+func (s *AuthService) CreateAPIKey(ctx context.Context, name, role, createdBy string) (string, *models.APIKey, error) {
+	if !models.ValidRole(role) {
+		return "", nil, fmt.Errorf("invalid role '%s'", role)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key := apiKeyPrefix + hex.EncodeToString(secret)
+
+	apiKey := &models.APIKey{
+		Name:      name,
+		Prefix:    key[:len(apiKeyPrefix)+8],
+		KeyHash:   hashAPIKey(key),
+		Role:      role,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+
+	result, err := s.db.Collection("api_keys").InsertOne(ctx, apiKey)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to store API key: %w", err)
+	}
+	apiKey.ID = result.InsertedID.(primitive.ObjectID)
+
+	return key, apiKey, nil
+}
+
+// AuthenticateAPIKey resolves an API key to its principal
+func (s *AuthService) AuthenticateAPIKey(ctx context.Context, key string) (*models.Principal, error) {
+	if s.adminAPIKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(s.adminAPIKey)) == 1 {
+		return &models.Principal{Subject: "bootstrap-admin", Name: "Bootstrap admin", Role: models.RoleAdmin, Method: "api_key"}, nil
+	}
+
+	var apiKey models.APIKey
+	now := time.Now()
+	err := s.db.Collection("api_keys").FindOneAndUpdate(
+		ctx,
+		bson.M{"key_hash": hashAPIKey(key)},
+		bson.M{"$set": bson.M{"last_used_at": now}},
+	).Decode(&apiKey)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	return &models.Principal{Subject: "key:" + apiKey.ID.Hex(), Name: apiKey.Name, Role: apiKey.Role, Method: "api_key"}, nil
+}
+
+// IssueToken signs a short-lived HS256 token for the principal
+func (s *AuthService) IssueToken(principal *models.Principal) (string, time.Time, error) {
+	if len(s.jwtSecret) == 0 {
+		return "", time.Time{}, ErrTokensDisabled
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.tokenTTL)
+	claims := jwtClaims{
+		Subject:   principal.Subject,
+		Name:      principal.Name,
+		Role:      principal.Role,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + s.sign(unsigned), expiresAt, nil
+}
+
+// AuthenticateToken verifies a bearer token. Tokens issued for a stored API key
+// stop working as soon as the key is deleted, and always carry the key's current role.
+func (s *AuthService) AuthenticateToken(ctx context.Context, token string) (*models.Principal, error) {
+	if len(s.jwtSecret) == 0 {
+		return nil, ErrTokensDisabled
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidCredentials
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || json.Unmarshal(headerJSON, &header) != nil || header.Alg != "HS256" {
+		return nil, ErrInvalidCredentials
+	}
+
+	if !hmac.Equal([]byte(s.sign(parts[0]+"."+parts[1])), []byte(parts[2])) {
+		return nil, ErrInvalidCredentials
+	}
+
+	var claims jwtClaims
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || json.Unmarshal(payload, &claims) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrInvalidCredentials
+	}
+
+	principal := &models.Principal{Subject: claims.Subject, Name: claims.Name, Role: claims.Role, Method: "jwt"}
+
+	if keyID, ok := strings.CutPrefix(claims.Subject, "key:"); ok {
+		objID, err := primitive.ObjectIDFromHex(keyID)
+		if err != nil {
+			return nil, ErrInvalidCredentials
+		}
+		var apiKey models.APIKey
+		err = s.db.Collection("api_keys").FindOne(ctx, bson.M{"_id": objID}).Decode(&apiKey)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, ErrInvalidCredentials
+			}
+			return nil, err
+		}
+		principal.Role = apiKey.Role
+	}
+
+	if !models.ValidRole(principal.Role) {
+		return nil, ErrInvalidCredentials
+	}
+	return principal, nil
+}
+
+func (s *AuthService) sign(data string) string {
+	mac := hmac.New(sha256.New, s.jwtSecret)
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
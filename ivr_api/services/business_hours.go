@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// holidayDateLayout is the format of holiday dates
+const holidayDateLayout = "2006-01-02"
+
+// ValidateBusinessCalendar checks a business calendar and lower-cases its weekday names
+func ValidateBusinessCalendar(calendar *models.BusinessCalendar) error {
+	calendar.Name = strings.TrimSpace(calendar.Name)
+	if calendar.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if calendar.Timezone == "" {
+		return fmt.Errorf("timezone is required")
+	}
+	if _, err := time.LoadLocation(calendar.Timezone); err != nil {
+		return fmt.Errorf("timezone '%s' is not a valid IANA time zone", calendar.Timezone)
+	}
+
+	hours := make(map[string][]models.TimeWindow, len(calendar.Hours))
+	for day, windows := range calendar.Hours {
+		name := strings.ToLower(day)
+		if _, ok := weekdayNames[name]; !ok {
+			return fmt.Errorf("hours day '%s' is invalid (use mon, tue, wed, thu, fri, sat, sun)", day)
+		}
+		if _, ok := hours[name]; ok {
+			return fmt.Errorf("hours day '%s' is listed twice", name)
+		}
+		if err := ValidateTimeWindows(windows); err != nil {
+			return fmt.Errorf("hours %s %v", name, err)
+		}
+		hours[name] = windows
+	}
+	calendar.Hours = hours
+
+	seen := map[string]bool{}
+	for i, holiday := range calendar.Holidays {
+		if _, err := time.Parse(holidayDateLayout, holiday.Date); err != nil {
+			return fmt.Errorf("holiday %d date '%s' is not in YYYY-MM-DD format", i+1, holiday.Date)
+		}
+		if seen[holiday.Date] {
+			return fmt.Errorf("holiday %d date %s is listed twice", i+1, holiday.Date)
+		}
+		seen[holiday.Date] = true
+		if err := ValidateTimeWindows(holiday.Windows); err != nil {
+			return fmt.Errorf("holiday %s %v", holiday.Date, err)
+		}
+	}
+	return nil
+}
+
+// IsOpen reports whether a business calendar is open at t. Holidays replace
+// the weekly hours of their date.
+func IsOpen(calendar *models.BusinessCalendar, t time.Time) bool {
+	zone, err := time.LoadLocation(calendar.Timezone)
+	if err != nil {
+		zone = time.UTC
+	}
+	local := t.In(zone)
+
+	date := local.Format(holidayDateLayout)
+	for _, holiday := range calendar.Holidays {
+		if holiday.Date == date {
+			return len(holiday.Windows) > 0 && inWindows(holiday.Windows, local)
+		}
+	}
+
+	windows := calendar.Hours[weekdayName(local.Weekday())]
+	return len(windows) > 0 && inWindows(windows, local)
+}
+
+// ActionAvailable reports whether an action can be chosen, given which
+// business calendars are open. Actions without a calendar, or whose calendar
+// no longer exists, are always available.
+func ActionAvailable(action *models.IVRAction, open map[primitive.ObjectID]bool) bool {
+	if action.ActionType != "forward" || action.Forward == nil || action.Forward.CalendarID == nil {
+		return true
+	}
+	isOpen, known := open[*action.Forward.CalendarID]
+	return !known || isOpen
+}
+
+// AfterHoursAction returns the action chosen instead of an unavailable one,
+// or nil when the option has no after-hours alternative on its menu level
+func AfterHoursAction(action *models.IVRAction, node *models.MenuNode) *models.IVRAction {
+	if action.Forward == nil || action.Forward.AfterHoursAction == "" {
+		return nil
+	}
+	return node.FindAction(action.Forward.AfterHoursAction)
+}
+
+// CalendarIDs returns the business calendars used by a list of actions and their sub-menus
+func CalendarIDs(actions []models.IVRAction) []primitive.ObjectID {
+	var ids []primitive.ObjectID
+	seen := map[primitive.ObjectID]bool{}
+	var walk func(actions []models.IVRAction)
+	walk = func(actions []models.IVRAction) {
+		for _, action := range actions {
+			if action.Forward != nil && action.Forward.CalendarID != nil && !seen[*action.Forward.CalendarID] {
+				seen[*action.Forward.CalendarID] = true
+				ids = append(ids, *action.Forward.CalendarID)
+			}
+			if action.SubMenu != nil {
+				walk(action.SubMenu.Actions)
+			}
+		}
+	}
+	walk(actions)
+	return ids
+}
+
+func weekdayName(day time.Weekday) string {
+	for name, weekday := range weekdayNames {
+		if weekday == day {
+			return name
+		}
+	}
+	return ""
+}
+
+// BusinessHoursService loads the business calendars campaigns route forward actions by
+type BusinessHoursService struct {
+	db *database.MongoDB
+}
+
+func NewBusinessHoursService(db *database.MongoDB) *BusinessHoursService {
+	return &BusinessHoursService{db: db}
+}
+
+// Availability returns whether each business calendar used by the campaign
+// is open at now. Calendars that cannot be loaded are left out, which keeps
+// their actions available.
+func (s *BusinessHoursService) Availability(ctx context.Context, campaign *models.Campaign, now time.Time) map[primitive.ObjectID]bool {
+	ids := CalendarIDs(campaign.Actions)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	cursor, err := s.db.Collection("business_calendars").Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		log.Printf("✗ Failed to load business calendars of campaign %s: %v", campaign.ID.Hex(), err)
+		return nil
+	}
+	var calendars []models.BusinessCalendar
+	if err := cursor.All(ctx, &calendars); err != nil {
+		log.Printf("✗ Failed to decode business calendars of campaign %s: %v", campaign.ID.Hex(), err)
+		return nil
+	}
+
+	open := make(map[primitive.ObjectID]bool, len(calendars))
+	for i := range calendars {
+		open[calendars[i].ID] = IsOpen(&calendars[i], now)
+	}
+	return open
+}
+
+// CheckCalendars reports the first business calendar used by the actions that does not exist
+func (s *BusinessHoursService) CheckCalendars(ctx context.Context, actions []models.IVRAction) error {
+	ids := CalendarIDs(actions)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	cursor, err := s.db.Collection("business_calendars").Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return fmt.Errorf("failed to load business calendars: %w", err)
+	}
+	var calendars []models.BusinessCalendar
+	if err := cursor.All(ctx, &calendars); err != nil {
+		return fmt.Errorf("failed to decode business calendars: %w", err)
+	}
+
+	found := make(map[primitive.ObjectID]bool, len(calendars))
+	for _, calendar := range calendars {
+		found[calendar.ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return fmt.Errorf("business calendar %s does not exist", id.Hex())
+		}
+	}
+	return nil
+}
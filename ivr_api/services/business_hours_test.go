@@ -0,0 +1,100 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prabhatkumar/ivrcalling/models"
+)
+
+func TestIsOpen(t *testing.T) {
+	kolkata := mustLoadLocation(t, "Asia/Kolkata")
+
+	office := &models.BusinessCalendar{
+		Timezone: "Asia/Kolkata",
+		Hours: map[string][]models.TimeWindow{
+			"mon": {{Start: "09:00", End: "13:00"}, {Start: "14:00", End: "18:00"}},
+			"tue": {{Start: "09:00", End: "18:00"}},
+			"sat": {{Start: "10:00", End: "14:00"}},
+			"sun": {},
+		},
+		Holidays: []models.Holiday{
+			{Date: "2024-03-05", Name: "Founders day"},
+			{Date: "2024-03-09", Name: "Half day", Windows: []models.TimeWindow{{Start: "10:00", End: "12:00"}}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		calendar *models.BusinessCalendar
+		at       time.Time
+		want     bool
+	}{
+		{"inside first window", office, time.Date(2024, 3, 4, 9, 0, 0, 0, kolkata), true},
+		{"lunch break", office, time.Date(2024, 3, 4, 13, 30, 0, 0, kolkata), false},
+		{"window end is exclusive", office, time.Date(2024, 3, 4, 18, 0, 0, 0, kolkata), false},
+		{"checked in the calendar's zone", office, time.Date(2024, 3, 4, 4, 0, 0, 0, time.UTC), true},
+		{"day left out is closed", office, time.Date(2024, 3, 6, 10, 0, 0, 0, kolkata), false},
+		{"day without windows is closed", office, time.Date(2024, 3, 10, 10, 0, 0, 0, kolkata), false},
+		{"holiday closes the day", office, time.Date(2024, 3, 5, 10, 0, 0, 0, kolkata), false},
+		{"holiday reduced hours", office, time.Date(2024, 3, 9, 11, 0, 0, 0, kolkata), true},
+		{"outside holiday reduced hours", office, time.Date(2024, 3, 9, 13, 0, 0, 0, kolkata), false},
+		{"regular hours after the holiday", office, time.Date(2024, 3, 16, 13, 0, 0, 0, kolkata), true},
+		{"invalid timezone uses UTC", &models.BusinessCalendar{Timezone: "Mars/Olympus", Hours: map[string][]models.TimeWindow{"mon": {{Start: "09:00", End: "10:00"}}}}, time.Date(2024, 3, 4, 9, 30, 0, 0, time.UTC), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsOpen(tt.calendar, tt.at); got != tt.want {
+				t.Errorf("IsOpen at %s = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateBusinessCalendar(t *testing.T) {
+	tests := []struct {
+		name     string
+		calendar models.BusinessCalendar
+		wantErr  string
+	}{
+		{"valid", models.BusinessCalendar{Name: " Office ", Timezone: "Asia/Kolkata", Hours: map[string][]models.TimeWindow{"Mon": {{Start: "09:00", End: "18:00"}}}, Holidays: []models.Holiday{{Date: "2024-12-25"}}}, ""},
+		{"missing name", models.BusinessCalendar{Name: "  ", Timezone: "UTC"}, "name is required"},
+		{"missing timezone", models.BusinessCalendar{Name: "Office"}, "timezone is required"},
+		{"invalid timezone", models.BusinessCalendar{Name: "Office", Timezone: "Mars/Olympus"}, "timezone 'Mars/Olympus' is not a valid IANA time zone"},
+		{"unknown day", models.BusinessCalendar{Name: "Office", Timezone: "UTC", Hours: map[string][]models.TimeWindow{"monday": nil}}, "hours day 'monday' is invalid"},
+		{"day listed twice", models.BusinessCalendar{Name: "Office", Timezone: "UTC", Hours: map[string][]models.TimeWindow{"mon": nil, "MON": nil}}, "is listed twice"},
+		{"invalid window", models.BusinessCalendar{Name: "Office", Timezone: "UTC", Hours: map[string][]models.TimeWindow{"mon": {{Start: "18:00", End: "09:00"}}}}, "hours mon window 1 must end after it starts"},
+		{"holiday date format", models.BusinessCalendar{Name: "Office", Timezone: "UTC", Holidays: []models.Holiday{{Date: "25/12/2024"}}}, "holiday 1 date '25/12/2024' is not in YYYY-MM-DD format"},
+		{"holiday listed twice", models.BusinessCalendar{Name: "Office", Timezone: "UTC", Holidays: []models.Holiday{{Date: "2024-12-25"}, {Date: "2024-12-25"}}}, "holiday 2 date 2024-12-25 is listed twice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBusinessCalendar(&tt.calendar)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateBusinessCalendar returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateBusinessCalendar error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateBusinessCalendarNormalizes(t *testing.T) {
+	calendar := &models.BusinessCalendar{Name: " Office ", Timezone: "UTC", Hours: map[string][]models.TimeWindow{"FRI": {{Start: "09:00", End: "17:00"}}}}
+	if err := ValidateBusinessCalendar(calendar); err != nil {
+		t.Fatalf("ValidateBusinessCalendar returned error: %v", err)
+	}
+	if calendar.Name != "Office" {
+		t.Errorf("Name = %q, want %q", calendar.Name, "Office")
+	}
+	if _, ok := calendar.Hours["fri"]; !ok || len(calendar.Hours) != 1 {
+		t.Errorf("Hours = %v, want only the fri key", calendar.Hours)
+	}
+}
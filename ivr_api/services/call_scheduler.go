@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// staleClaimAge is how long a call may stay claimed before it is considered
+// abandoned by a scheduler that stopped before placing it
+const staleClaimAge = 10 * time.Minute
+
+// CallScheduler redials calls whose status is "scheduled" once their
+// next_attempt_at has passed
+type CallScheduler struct {
+	db         *database.MongoDB
+	dialQueue  *DialQueue
+	dialer     *Dialer
+	dncService *DNCService
+	events     *EventBus
+	interval   time.Duration
+}
+
+func NewCallScheduler(db *database.MongoDB, dialQueue *DialQueue, dialer *Dialer, dncService *DNCService, events *EventBus) *CallScheduler {
+	return &CallScheduler{
+		db:         db,
+		dialQueue:  dialQueue,
+		dialer:     dialer,
+		dncService: dncService,
+		events:     events,
+		interval:   30 * time.Second,
+	}
+}
+
+// Start runs the scheduler in the background until ctx is done. Every run
+// first puts back calls whose claim has gone stale.
+func (s *CallScheduler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			s.releaseStaleClaims(ctx)
+			s.runDue(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	log.Printf("✓ Call scheduler started - checking every %s", s.interval)
+}
+
+// runDue claims and redials every scheduled call that is due. A call whose
+// do-not-call or calling window check fails is put back and the run stops, so
+// nothing is dialed unchecked while the database is unavailable. Calls of a
+// campaign at its live call cap are left for the next run, so a busy campaign
+// does not hold up the redials of the others.
+func (s *CallScheduler) runDue(ctx context.Context) {
+	var deferred []primitive.ObjectID // campaigns at their live call cap during this run
+	for {
+		call, ok := s.claimDueCall(ctx, deferred)
+		if !ok {
+			return
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		blocked, err := s.dncService.IsBlocked(checkCtx, call.PhoneNumber)
+		cancel()
+		if err != nil {
+			log.Printf("Failed to check do-not-call list for call %s: %v", call.ID.Hex(), err)
+			s.reschedule(call)
+			return
+		}
+		if blocked {
+			s.cancelCall(call, "Number was added to the do-not-call list")
+			continue
+		}
+
+		// The calling window may have closed while the call was waiting
+		campaign, err := s.dialQueue.LoadCampaign(ctx, call.CampaignID)
+		if err != nil {
+			log.Printf("Failed to load campaign %s for scheduled call %s: %v", call.CampaignID.Hex(), call.ID.Hex(), err)
+			s.reschedule(call)
+			return
+		}
+		now := time.Now()
+		next, allowed := s.dialer.NextAllowedTime(campaign, call.PhoneNumber, now)
+		if !allowed {
+			s.cancelCall(call, "Campaign schedule has no calling window left")
+			continue
+		}
+		if next.After(now) {
+			log.Printf("Call %s is outside the recipient's calling window - moved to %s", call.ID.Hex(), next.Format(time.RFC3339))
+			s.rescheduleAt(call, next)
+			continue
+		}
+
+		log.Printf("Redialing scheduled call %s to %s (attempt %d)", call.ID.Hex(), call.PhoneNumber, call.Attempts+1)
+		err = s.dialQueue.Redial(ctx, call)
+		if errors.Is(err, ErrCampaignAtCapacity) {
+			log.Printf("Campaign %s is at its live call cap - its scheduled calls wait for the next run", call.CampaignID.Hex())
+			s.reschedule(call)
+			deferred = append(deferred, call.CampaignID)
+			continue
+		}
+		// A call that was not placed is still claimed, for example when shutting
+		// down or when live calls cannot be counted - leave it for the next run
+		if err != nil && s.reschedule(call) {
+			return
+		}
+	}
+}
+
+// releaseStaleClaims puts calls claimed longer than staleClaimAge ago back in
+// the scheduled state, so a scheduler that stopped between claiming a call and
+// placing it does not leave the call claimed forever
+func (s *CallScheduler) releaseStaleClaims(ctx context.Context) {
+	releaseCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.db.Collection("calls").UpdateMany(
+		releaseCtx,
+		bson.M{
+			"status":     "claimed",
+			"claimed_at": bson.M{"$lt": time.Now().Add(-staleClaimAge)},
+		},
+		bson.M{
+			"$set":   bson.M{"status": "scheduled", "updated_at": time.Now()},
+			"$unset": bson.M{"claimed_at": ""},
+		},
+	)
+	if err != nil {
+		log.Printf("Failed to release stale call claims: %v", err)
+	} else if result.ModifiedCount > 0 {
+		log.Printf("Released %d stale call claims", result.ModifiedCount)
+	}
+}
+
+// claimDueCall atomically moves the oldest due scheduled call to claimed,
+// skipping the calls of the given campaigns
+func (s *CallScheduler) claimDueCall(ctx context.Context, skipCampaigns []primitive.ObjectID) (*models.Call, bool) {
+	claimCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"status":          "scheduled",
+		"next_attempt_at": bson.M{"$lte": now},
+	}
+	if len(skipCampaigns) > 0 {
+		filter["campaign_id"] = bson.M{"$nin": skipCampaigns}
+	}
+
+	var call models.Call
+	err := s.db.Collection("calls").FindOneAndUpdate(
+		claimCtx,
+		filter,
+		bson.M{"$set": bson.M{"status": "claimed", "claimed_at": now, "updated_at": now}},
+		options.FindOneAndUpdate().SetSort(bson.M{"next_attempt_at": 1}).SetReturnDocument(options.After),
+	).Decode(&call)
+	if err != nil {
+		return nil, false
+	}
+	return &call, true
+}
+
+// reschedule puts a claimed call back in the scheduled state and reports
+// whether the call was still claimed
+func (s *CallScheduler) reschedule(call *models.Call) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := s.db.Collection("calls").UpdateOne(
+		ctx,
+		bson.M{"_id": call.ID, "status": "claimed"},
+		bson.M{
+			"$set":   bson.M{"status": "scheduled", "updated_at": time.Now()},
+			"$unset": bson.M{"claimed_at": ""},
+		},
+	)
+	if err != nil {
+		// The claim expires on its own; treat the call as still claimed
+		log.Printf("Failed to put call %s back in the schedule: %v", call.ID.Hex(), err)
+		return true
+	}
+	return result.MatchedCount > 0
+}
+
+// rescheduleAt puts a claimed call back in the scheduled state for a later time
+func (s *CallScheduler) rescheduleAt(call *models.Call, next time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s.db.Collection("calls").UpdateOne(
+		ctx,
+		bson.M{"_id": call.ID, "status": "claimed"},
+		bson.M{
+			"$set":   bson.M{"status": "scheduled", "next_attempt_at": next, "updated_at": time.Now()},
+			"$unset": bson.M{"claimed_at": ""},
+		},
+	)
+	s.db.Collection("call_logs").InsertOne(ctx, models.CallLog{
+		CallID:    call.ID,
+		Event:     "held_for_schedule",
+		Details:   fmt.Sprintf("Outside the calling window - call held until %s", next.Format(time.RFC3339)),
+		CreatedAt: time.Now(),
+	})
+
+	call.Status = "scheduled"
+	s.events.PublishCall("scheduled", call, "Held until "+next.Format(time.RFC3339))
+}
+
+// cancelCall settles a scheduled call that must not be dialed any more
+func (s *CallScheduler) cancelCall(call *models.Call, reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	log.Printf("Cancelling scheduled call %s: %s", call.ID.Hex(), reason)
+	s.db.Collection("calls").UpdateOne(
+		ctx,
+		bson.M{"_id": call.ID},
+		bson.M{
+			"$set":   bson.M{"status": "failed", "error_message": reason, "updated_at": time.Now()},
+			"$unset": bson.M{"next_attempt_at": "", "claimed_at": ""},
+		},
+	)
+	s.db.Collection("call_logs").InsertOne(ctx, models.CallLog{
+		CallID:    call.ID,
+		Event:     "retry_cancelled",
+		Details:   reason,
+		CreatedAt: time.Now(),
+	})
+
+	call.Status = "failed"
+	s.events.PublishCall("failed", call, reason)
+}
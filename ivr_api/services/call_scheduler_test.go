@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prabhatkumar/ivrcalling/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCallSchedulerClaims(t *testing.T) {
+	ctx := context.Background()
+	queue, db := newTestDialQueue(t, 10)
+	scheduler := NewCallScheduler(db, queue, queue.dialer, NewDNCService(db), NewEventBus())
+
+	insert := func(call models.Call) primitive.ObjectID {
+		t.Helper()
+		call.CreatedAt = time.Now()
+		call.UpdatedAt = time.Now()
+		result, err := db.Collection("calls").InsertOne(ctx, call)
+		if err != nil {
+			t.Fatalf("failed to insert call: %v", err)
+		}
+		return result.InsertedID.(primitive.ObjectID)
+	}
+	find := func(id primitive.ObjectID) models.Call {
+		t.Helper()
+		var call models.Call
+		if err := db.Collection("calls").FindOne(ctx, bson.M{"_id": id}).Decode(&call); err != nil {
+			t.Fatalf("failed to find call: %v", err)
+		}
+		return call
+	}
+
+	due := time.Now().Add(-time.Minute)
+	dueID := insert(models.Call{PhoneNumber: "+14155550123", Status: "scheduled", NextAttemptAt: &due})
+	call, ok := scheduler.claimDueCall(ctx, nil)
+	if !ok || call.ID != dueID {
+		t.Fatalf("claimDueCall = %v, %v, want the due call", call, ok)
+	}
+	if claimed := find(dueID); claimed.Status != "claimed" || claimed.ClaimedAt == nil {
+		t.Errorf("claimed call: status %s, claimed_at %v, want claimed with a timestamp", claimed.Status, claimed.ClaimedAt)
+	}
+	if _, ok := scheduler.claimDueCall(ctx, nil); ok {
+		t.Error("a claimed call was claimed again")
+	}
+
+	staleAt := time.Now().Add(-staleClaimAge - time.Minute)
+	recentAt := time.Now().Add(-time.Minute)
+	staleID := insert(models.Call{PhoneNumber: "+14155550124", Status: "claimed", ClaimedAt: &staleAt, NextAttemptAt: &due})
+	recentID := insert(models.Call{PhoneNumber: "+14155550125", Status: "claimed", ClaimedAt: &recentAt, NextAttemptAt: &due})
+
+	scheduler.releaseStaleClaims(ctx)
+	if stale := find(staleID); stale.Status != "scheduled" || stale.ClaimedAt != nil {
+		t.Errorf("stale claim: status %s, claimed_at %v, want scheduled without a claim", stale.Status, stale.ClaimedAt)
+	}
+	if recent := find(recentID); recent.Status != "claimed" {
+		t.Errorf("recent claim: status %s, want it to stay claimed", recent.Status)
+	}
+}
+
+func TestCallSchedulerDefersCampaignsAtCapacity(t *testing.T) {
+	ctx := context.Background()
+	queue, db := newTestDialQueue(t, 1)
+	scheduler := NewCallScheduler(db, queue, queue.dialer, NewDNCService(db), NewEventBus())
+
+	insertCampaign := func(name string) primitive.ObjectID {
+		t.Helper()
+		result, err := db.Collection("campaigns").InsertOne(ctx, models.Campaign{Name: name, IsActive: true, CreatedAt: time.Now()})
+		if err != nil {
+			t.Fatalf("failed to insert campaign: %v", err)
+		}
+		return result.InsertedID.(primitive.ObjectID)
+	}
+	insertCall := func(campaignID primitive.ObjectID, status string, nextAttempt time.Time) primitive.ObjectID {
+		t.Helper()
+		result, err := db.Collection("calls").InsertOne(ctx, models.Call{
+			CampaignID: campaignID, PhoneNumber: "+14155550123", Status: status, Language: "en",
+			NextAttemptAt: &nextAttempt, CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("failed to insert call: %v", err)
+		}
+		return result.InsertedID.(primitive.ObjectID)
+	}
+	status := func(id primitive.ObjectID) string {
+		t.Helper()
+		var call models.Call
+		if err := db.Collection("calls").FindOne(ctx, bson.M{"_id": id}).Decode(&call); err != nil {
+			t.Fatalf("failed to find call: %v", err)
+		}
+		return call.Status
+	}
+
+	busy := insertCampaign("Busy")
+	idle := insertCampaign("Idle")
+	insertCall(busy, "in-progress", time.Now())
+	// The capped campaign's calls are due first
+	busyFirst := insertCall(busy, "scheduled", time.Now().Add(-3*time.Minute))
+	busySecond := insertCall(busy, "scheduled", time.Now().Add(-2*time.Minute))
+	idleCall := insertCall(idle, "scheduled", time.Now().Add(-time.Minute))
+
+	done := make(chan struct{})
+	go func() {
+		scheduler.runDue(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("runDue waited on the campaign at its live call cap")
+	}
+
+	if got := status(idleCall); got != "initiated" {
+		t.Errorf("call of the idle campaign: status %s, want initiated", got)
+	}
+	for _, id := range []primitive.ObjectID{busyFirst, busySecond} {
+		if got := status(id); got != "scheduled" {
+			t.Errorf("call of the capped campaign: status %s, want scheduled", got)
+		}
+	}
+	if queue.inFlight[busy] != 0 || queue.inFlight[idle] != 0 {
+		t.Errorf("in flight after the run = %v, want no reserved slots", queue.inFlight)
+	}
+}
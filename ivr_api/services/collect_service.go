@@ -0,0 +1,268 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/prabhatkumar/ivrcalling/models"
+)
+
+// Limits of "collect" actions
+const (
+	MaxCollectLength         = 32
+	DefaultCollectRetries    = 2
+	MaxCollectRetries        = 5
+	DefaultCollectFinishKey  = "#"
+	collectTimeoutSeconds    = 10
+	collectWebhookTimeout    = 10 * time.Second
+	collectedValueMaskedText = "****"
+)
+
+// collectHTTPClient only connects to public addresses over https, so a
+// campaign's webhook_url cannot reach services inside the network. The address
+// is checked when dialing, after DNS resolution, which also covers redirects.
+var collectHTTPClient = &http.Client{
+	Timeout: collectWebhookTimeout,
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: collectWebhookTimeout, Control: dialPublicOnly}).DialContext,
+		TLSHandshakeTimeout: collectWebhookTimeout,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme != "https" {
+			return fmt.Errorf("redirect to non-https URL %s", req.URL.Redacted())
+		}
+		if len(via) >= 5 {
+			return fmt.Errorf("stopped after %d redirects", len(via))
+		}
+		return nil
+	},
+}
+
+// nonPublicNetworks are ranges not covered by the net.IP predicates in isPublicIP
+var nonPublicNetworks = func() []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range []string{"0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "64:ff9b::/96"} {
+		_, network, _ := net.ParseCIDR(cidr)
+		networks = append(networks, network)
+	}
+	return networks
+}()
+
+// isPublicIP reports whether ip is a globally routable unicast address
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// dialPublicOnly refuses connections to loopback, private, link-local and
+// other non-public addresses
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("webhook address %s is not public", host)
+	}
+	return nil
+}
+
+// ValidateCollectSettings checks the settings of a "collect" action
+func ValidateCollectSettings(settings *models.CollectSettings) error {
+	if !fieldNamePattern.MatchString(settings.Name) {
+		return fmt.Errorf("collect.name must contain only lowercase letters, digits and underscores")
+	}
+	if settings.Name == VariableName || settings.Name == VariablePhoneNumber {
+		return fmt.Errorf("collect.name cannot be '%s', it is a built-in variable", settings.Name)
+	}
+	if settings.MaxLength < 1 || settings.MaxLength > MaxCollectLength {
+		return fmt.Errorf("collect.max_length must be between 1 and %d", MaxCollectLength)
+	}
+	if settings.MinLength < 0 || settings.MinLength > settings.MaxLength {
+		return fmt.Errorf("collect.min_length must be between 0 and max_length")
+	}
+	switch settings.FinishOnKey {
+	case "", "#", "*":
+	default:
+		return fmt.Errorf("collect.finish_on_key must be # or *")
+	}
+	if settings.Pattern != "" {
+		if _, err := regexp.Compile(settings.Pattern); err != nil {
+			return fmt.Errorf("collect.pattern is not a valid regular expression: %v", err)
+		}
+	}
+	if settings.MaxRetries < 0 || settings.MaxRetries > MaxCollectRetries {
+		return fmt.Errorf("collect.max_retries must be between 0 and %d", MaxCollectRetries)
+	}
+	for label, text := range map[string]string{"invalid_message": settings.InvalidMessage, "success_message": settings.SuccessMessage} {
+		if err := ValidateMessage(text); err != nil {
+			return fmt.Errorf("collect.%s: %v", label, err)
+		}
+	}
+	if settings.WebhookURL != "" {
+		if err := validateWebhookURL(settings.WebhookURL); err != nil {
+			return fmt.Errorf("collect.webhook_url %v", err)
+		}
+	}
+	return nil
+}
+
+// validateWebhookURL requires an absolute https URL whose host is not a
+// loopback, private or link-local address. Host names are resolved when the
+// webhook is sent, by collectHTTPClient.
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return fmt.Errorf("must be an absolute https URL")
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("must not point to localhost")
+	}
+	if ip := net.ParseIP(host); ip != nil && !isPublicIP(ip) {
+		return fmt.Errorf("must not point to a private, loopback or link-local address")
+	}
+	return nil
+}
+
+// CollectFinishKey returns the key that ends the input
+func CollectFinishKey(settings *models.CollectSettings) string {
+	if settings.FinishOnKey == "" {
+		return DefaultCollectFinishKey
+	}
+	return settings.FinishOnKey
+}
+
+// CollectRetries returns how many times the caller is prompted again after an invalid value
+func CollectRetries(settings *models.CollectSettings) int {
+	if settings.MaxRetries == 0 {
+		return DefaultCollectRetries
+	}
+	return settings.MaxRetries
+}
+
+// ValidateCollectedValue checks the digits a caller keyed in. The pattern
+// must match the whole value.
+func ValidateCollectedValue(settings *models.CollectSettings, value string) error {
+	minLength := max(settings.MinLength, 1)
+	switch {
+	case len(value) < minLength:
+		return fmt.Errorf("expected at least %d digits, got %d", minLength, len(value))
+	case len(value) > settings.MaxLength:
+		return fmt.Errorf("expected at most %d digits, got %d", settings.MaxLength, len(value))
+	}
+	if settings.Pattern != "" {
+		pattern, err := regexp.Compile("^(?:" + settings.Pattern + ")$")
+		if err != nil || !pattern.MatchString(value) {
+			return fmt.Errorf("value does not match the expected format")
+		}
+	}
+	return nil
+}
+
+// StoresCollectedValue reports whether a collected value is kept on the call.
+// Sensitive values are only posted to the action's webhook_url.
+func StoresCollectedValue(settings *models.CollectSettings) bool {
+	return !settings.Sensitive
+}
+
+// DisplayCollectedValue returns the value as it may appear in logs
+func DisplayCollectedValue(settings *models.CollectSettings, value string) string {
+	if settings.Sensitive {
+		return collectedValueMaskedText
+	}
+	return value
+}
+
+// CollectSettingsByName returns the settings of the values collected by a
+// list of actions and their sub-menus, keyed by name
+func CollectSettingsByName(actions []models.IVRAction) map[string]*models.CollectSettings {
+	names := map[string]*models.CollectSettings{}
+	var walk func(actions []models.IVRAction)
+	walk = func(actions []models.IVRAction) {
+		for _, action := range actions {
+			if action.ActionType == "collect" && action.Collect != nil {
+				names[action.Collect.Name] = action.Collect
+			}
+			if action.SubMenu != nil {
+				walk(action.SubMenu.Actions)
+			}
+		}
+	}
+	walk(actions)
+	return names
+}
+
+// CollectedPayload is posted to the webhook_url of a "collect" action
+type CollectedPayload struct {
+	Event       string            `json:"event"` // input_collected
+	CallID      string            `json:"call_id"`
+	CampaignID  string            `json:"campaign_id"`
+	PhoneNumber string            `json:"phone_number"`
+	Name        string            `json:"name"`
+	Value       string            `json:"value"`
+	Collected   map[string]string `json:"collected"` // every value collected on the call so far
+	Timestamp   time.Time         `json:"timestamp"`
+}
+
+// NotifyCollected posts a collected value to the action's webhook in the
+// background. Failures are logged; the call goes on regardless.
+func NotifyCollected(settings *models.CollectSettings, call *models.Call, value string) {
+	if settings.WebhookURL == "" {
+		return
+	}
+
+	payload := CollectedPayload{
+		Event:       "input_collected",
+		CallID:      call.ID.Hex(),
+		CampaignID:  call.CampaignID.Hex(),
+		PhoneNumber: call.PhoneNumber,
+		Name:        settings.Name,
+		Value:       value,
+		Collected:   call.Collected,
+		Timestamp:   time.Now(),
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("✗ Failed to encode collected value webhook: %v", err)
+		return
+	}
+
+	go func() {
+		resp, err := collectHTTPClient.Post(settings.WebhookURL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Printf("✗ Collected value webhook to %s failed: %v", settings.WebhookURL, err)
+			return
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= 300 {
+			log.Printf("✗ Collected value webhook to %s returned %s", settings.WebhookURL, resp.Status)
+			return
+		}
+		log.Printf("✓ Collected value '%s' of call %s sent to %s", settings.Name, call.ID.Hex(), settings.WebhookURL)
+	}()
+}
+
+// collectLabel turns a collected value name such as "order_number" into the
+// words used in the menu, "order number"
+func collectLabel(name string) string {
+	return strings.ReplaceAll(name, "_", " ")
+}
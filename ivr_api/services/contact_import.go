@@ -0,0 +1,228 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/prabhatkumar/ivrcalling/models"
+	"github.com/prabhatkumar/ivrshared/phone"
+)
+
+// MaxContactListSize limits the contacts of one list so it fits in a single document
+const MaxContactListSize = 50000
+
+// Header names recognised when no column mapping is given
+var (
+	phoneHeaders = []string{"phone_number", "phone", "number", "phonenumber", "mobile", "mobile_number", "telephone"}
+	nameHeaders  = []string{"name", "customer_name", "full_name", "contact_name"}
+)
+
+// ContactColumnMapping tells which columns hold the contact fields. Columns are
+// given as header names or as 1-based column numbers; empty means auto-detect.
+type ContactColumnMapping struct {
+	Phone         string
+	Name          string
+	HasHeader     *bool    // nil detects a header row from the first row
+	DefaultRegion string   // ISO country of numbers written without a country code
+	Required      []string // template variables every contact must have a value for
+}
+
+// ContactImport is the result of parsing uploaded contact rows
+type ContactImport struct {
+	Contacts       []models.ListContact
+	Fields         []string // custom field names taken from the other header columns
+	InvalidRows    []models.ContactRowError
+	DuplicateCount int
+}
+
+// ParseContacts validates, normalizes and deduplicates contact rows. With a
+// header row, columns other than the phone and name become custom fields
+// named after their normalized header. rows holds one entry per line of the
+// file, blank lines included, so line numbers in errors are 1-based and match
+// the file; the header is the first row that is not blank.
+func ParseContacts(rows [][]string, mapping ContactColumnMapping) (*ContactImport, error) {
+	first := 0
+	for first < len(rows) && isBlankRow(rows[first]) {
+		first++
+	}
+	if first == len(rows) {
+		return nil, fmt.Errorf("the file has no rows")
+	}
+
+	hasHeader := false
+	if mapping.HasHeader != nil {
+		hasHeader = *mapping.HasHeader
+	} else {
+		// A known phone header, or a phone column mapped by name, means the first row is a header
+		hasHeader = findColumn(rows[first], phoneHeaders) >= 0 || (mapping.Phone != "" && !isNumeric(mapping.Phone))
+	}
+
+	var header []string
+	if hasHeader {
+		header = rows[first]
+	}
+
+	phoneCol, err := resolveColumn(header, mapping.Phone, phoneHeaders, 0)
+	if err != nil {
+		return nil, fmt.Errorf("phone column: %w", err)
+	}
+	nameCol, err := resolveColumn(header, mapping.Name, nameHeaders, -1)
+	if err != nil {
+		return nil, fmt.Errorf("name column: %w", err)
+	}
+	if nameCol < 0 && header == nil && mapping.Name == "" && phoneCol == 0 {
+		// Without a header, a second column is taken as the name
+		nameCol = 1
+	}
+
+	// Remaining header columns hold custom fields
+	fieldCols := map[int]string{}
+	fields := []string{}
+	for i, cell := range header {
+		name := NormalizeFieldName(cell)
+		if i == phoneCol || i == nameCol || name == "" {
+			continue
+		}
+		if name == VariableName || name == VariablePhoneNumber {
+			return nil, fmt.Errorf("column '%s' clashes with the built-in '%s' variable", cell, name)
+		}
+		fieldCols[i] = name
+		fields = append(fields, name)
+	}
+	if missing := MissingVariables(mapping.Required, stringSet(fields)); len(missing) > 0 {
+		return nil, fmt.Errorf("the file has no column for the campaign variables: %s", strings.Join(missing, ", "))
+	}
+
+	result := &ContactImport{
+		Contacts:    []models.ListContact{},
+		Fields:      fields,
+		InvalidRows: []models.ContactRowError{},
+	}
+	seen := map[string]int{} // normalized number -> line it first appeared on
+
+	for i, row := range rows {
+		line := i + 1
+		if hasHeader && i == first {
+			continue
+		}
+		if isBlankRow(row) {
+			continue
+		}
+
+		if phoneCol >= len(row) || strings.TrimSpace(row[phoneCol]) == "" {
+			result.InvalidRows = append(result.InvalidRows, models.ContactRowError{Line: line, Error: "missing phone number"})
+			continue
+		}
+
+		raw := strings.TrimSpace(row[phoneCol])
+		number, err := phone.ParseDialable(raw, mapping.DefaultRegion)
+		if err != nil {
+			result.InvalidRows = append(result.InvalidRows, models.ContactRowError{Line: line, Value: raw, Error: phoneErrorReason(err)})
+			continue
+		}
+
+		if first, ok := seen[number.E164]; ok {
+			result.DuplicateCount++
+			result.InvalidRows = append(result.InvalidRows, models.ContactRowError{Line: line, Value: raw, Error: fmt.Sprintf("duplicate of line %d", first)})
+			continue
+		}
+
+		if len(result.Contacts) >= MaxContactListSize {
+			return nil, fmt.Errorf("a contact list can hold at most %d contacts", MaxContactListSize)
+		}
+		contact := models.ListContact{PhoneNumber: number.E164}
+		if nameCol >= 0 && nameCol < len(row) {
+			contact.Name = strings.TrimSpace(row[nameCol])
+		}
+		// Empty cells are left out so they count as missing values
+		for col, name := range fieldCols {
+			if col < len(row) && strings.TrimSpace(row[col]) != "" {
+				if contact.Fields == nil {
+					contact.Fields = map[string]string{}
+				}
+				contact.Fields[name] = strings.TrimSpace(row[col])
+			}
+		}
+		if missing := MissingVariables(mapping.Required, contact.Fields); len(missing) > 0 {
+			result.InvalidRows = append(result.InvalidRows, models.ContactRowError{Line: line, Value: raw, Error: "missing values for " + strings.Join(missing, ", ")})
+			continue
+		}
+
+		seen[number.E164] = line
+		result.Contacts = append(result.Contacts, contact)
+	}
+
+	return result, nil
+}
+
+// resolveColumn finds a column by explicit mapping, by known header names or by default index
+func resolveColumn(header []string, mapped string, known []string, defaultCol int) (int, error) {
+	mapped = strings.TrimSpace(mapped)
+	if mapped != "" {
+		if n, err := strconv.Atoi(mapped); err == nil {
+			if n < 1 {
+				return 0, fmt.Errorf("column numbers start at 1")
+			}
+			return n - 1, nil
+		}
+		if header == nil {
+			return 0, fmt.Errorf("column '%s' given by name but the file has no header row", mapped)
+		}
+		if col := findColumn(header, []string{mapped}); col >= 0 {
+			return col, nil
+		}
+		return 0, fmt.Errorf("column '%s' not found in the header row", mapped)
+	}
+
+	if header != nil {
+		if col := findColumn(header, known); col >= 0 {
+			return col, nil
+		}
+	}
+	return defaultCol, nil
+}
+
+func findColumn(header []string, names []string) int {
+	for i, cell := range header {
+		normalized := strings.ToLower(strings.TrimSpace(cell))
+		for _, name := range names {
+			if normalized == strings.ToLower(name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isBlankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func isNumeric(value string) bool {
+	_, err := strconv.Atoi(strings.TrimSpace(value))
+	return err == nil
+}
+
+// phoneErrorReason drops the repeated input from a phone number error
+func phoneErrorReason(err error) string {
+	var phoneErr *phone.Error
+	if errors.As(err, &phoneErr) {
+		return phoneErr.Reason
+	}
+	return err.Error()
+}
+
+func stringSet(values []string) map[string]string {
+	set := make(map[string]string, len(values))
+	for _, value := range values {
+		set[value] = value
+	}
+	return set
+}
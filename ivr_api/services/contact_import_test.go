@@ -0,0 +1,41 @@
+package services
+
+import "testing"
+
+func TestParseContactsLineNumbers(t *testing.T) {
+	// Line 1 is blank, the header is on line 2 and lines 4 and 5 are blank
+	rows := [][]string{
+		nil,
+		{"phone_number", "name"},
+		{"+14155550100", "Asha"},
+		nil,
+		{"", ""},
+		{"12", "Lee"},
+		{"+1 415 555 0100", "Asha again"},
+	}
+
+	imported, err := ParseContacts(rows, ContactColumnMapping{})
+	if err != nil {
+		t.Fatalf("ParseContacts: %v", err)
+	}
+	if len(imported.Contacts) != 1 || imported.Contacts[0].Name != "Asha" {
+		t.Errorf("contacts = %+v, want only Asha", imported.Contacts)
+	}
+
+	wantLines := []int{6, 7}
+	if len(imported.InvalidRows) != len(wantLines) {
+		t.Fatalf("invalid rows = %+v, want lines %v", imported.InvalidRows, wantLines)
+	}
+	for i, line := range wantLines {
+		if imported.InvalidRows[i].Line != line {
+			t.Errorf("invalid row %d is on line %d, want %d", i, imported.InvalidRows[i].Line, line)
+		}
+	}
+	if imported.InvalidRows[1].Error != "duplicate of line 3" {
+		t.Errorf("duplicate error = %q, want %q", imported.InvalidRows[1].Error, "duplicate of line 3")
+	}
+
+	if _, err := ParseContacts([][]string{nil, {" "}}, ContactColumnMapping{}); err == nil {
+		t.Error("ParseContacts accepted a file of blank rows")
+	}
+}
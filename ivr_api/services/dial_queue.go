@@ -0,0 +1,412 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/prabhatkumar/ivrcalling/config"
+	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrCampaignAtCapacity is returned by Redial when the campaign has no live call slot free
+var ErrCampaignAtCapacity = errors.New("campaign is at its live call cap")
+
+// DialQueue drains persisted dial jobs in the background. Calls are placed by a
+// shared worker pool, throttled to a global calls-per-second rate and capped at
+// a maximum number of live calls per campaign.
+type DialQueue struct {
+	db           *database.MongoDB
+	dialer       *Dialer
+	workers      int
+	interval     time.Duration
+	maxLiveCalls int
+
+	wake     chan struct{}
+	tasks    chan dialTask
+	throttle *time.Ticker // shared by every job and redial
+
+	mu       sync.Mutex
+	inFlight map[primitive.ObjectID]int // campaign ID -> calls with a reserved slot that are not yet placed
+}
+
+type dialTask struct {
+	job      *models.DialJob
+	campaign *models.Campaign
+	index    int
+	done     func()
+}
+
+func NewDialQueue(db *database.MongoDB, dialer *Dialer, cfg *config.Config) *DialQueue {
+	callsPerSecond := cfg.DialCallsPerSecond
+	if callsPerSecond <= 0 {
+		callsPerSecond = 1
+	}
+	workers := cfg.DialWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	interval := time.Duration(float64(time.Second) / callsPerSecond)
+
+	return &DialQueue{
+		db:           db,
+		dialer:       dialer,
+		workers:      workers,
+		interval:     interval,
+		maxLiveCalls: cfg.DialMaxLiveCalls,
+		wake:         make(chan struct{}, 1),
+		tasks:        make(chan dialTask),
+		throttle:     time.NewTicker(interval),
+		inFlight:     make(map[primitive.ObjectID]int),
+	}
+}
+
+// Start launches the worker pool and the job dispatcher. Jobs left running by a
+// previous process are put back in the queue. Everything stops when ctx is done.
+func (q *DialQueue) Start(ctx context.Context) {
+	resetCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	result, err := q.db.Collection("dial_jobs").UpdateMany(
+		resetCtx,
+		bson.M{"status": "running"},
+		bson.M{"$set": bson.M{"status": "queued", "updated_at": time.Now()}},
+	)
+	cancel()
+	if err != nil {
+		log.Printf("Failed to requeue interrupted dial jobs: %v", err)
+	} else if result.ModifiedCount > 0 {
+		log.Printf("Requeued %d interrupted dial jobs", result.ModifiedCount)
+	}
+
+	for i := 0; i < q.workers; i++ {
+		go q.worker(ctx)
+	}
+	go q.dispatch(ctx)
+
+	log.Printf("✓ Dial queue started - workers: %d, rate: 1 call every %s, max live calls per campaign: %d",
+		q.workers, q.interval, q.maxLiveCalls)
+}
+
+// Enqueue signals the dispatcher that a new job has been stored with status "queued"
+func (q *DialQueue) Enqueue(jobID primitive.ObjectID) {
+	log.Printf("Dial job queued - ID: %s", jobID.Hex())
+	select {
+	case q.wake <- struct{}{}:
+	default:
+		// A wake-up is already pending
+	}
+}
+
+// dispatch claims queued jobs and runs each one in its own goroutine. The
+// collection is also polled periodically so no job is left behind if a
+// wake-up is missed.
+func (q *DialQueue) dispatch(ctx context.Context) {
+	defer q.throttle.Stop()
+	poll := time.NewTicker(30 * time.Second)
+	defer poll.Stop()
+
+	for {
+		for {
+			job, err := q.claimJob(ctx)
+			if err != nil {
+				if !errors.Is(err, mongo.ErrNoDocuments) {
+					log.Printf("Failed to claim dial job: %v", err)
+				}
+				break
+			}
+			go q.runJob(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-poll.C:
+		}
+	}
+}
+
+// claimJob atomically moves the oldest queued job to running
+func (q *DialQueue) claimJob(ctx context.Context) (*models.DialJob, error) {
+	claimCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	var job models.DialJob
+	err := q.db.Collection("dial_jobs").FindOneAndUpdate(
+		claimCtx,
+		bson.M{"status": "queued"},
+		bson.M{"$set": bson.M{"status": "running", "started_at": now, "updated_at": now}},
+		options.FindOneAndUpdate().SetSort(bson.M{"created_at": 1}).SetReturnDocument(options.After),
+	).Decode(&job)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// runJob hands every pending contact of the job to the worker pool, respecting
+// the rate limit and the campaign's live call cap, then marks the job completed
+func (q *DialQueue) runJob(ctx context.Context, job *models.DialJob) {
+	log.Printf("=== RUNNING DIAL JOB %s ===", job.ID.Hex())
+	log.Printf("Campaign ID: %s, Contacts: %d, Already processed: %d", job.CampaignID.Hex(), job.Total, job.Processed)
+
+	// The campaign is loaded once so every contact is checked against the same schedule
+	campaign, err := q.LoadCampaign(ctx, job.CampaignID)
+	if err != nil {
+		log.Printf("✗ Dial job %s failed - could not load campaign: %v", job.ID.Hex(), err)
+		q.failJob(job, "Failed to load campaign: "+err.Error())
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := range job.Contacts {
+		switch job.Contacts[i].Status {
+		case "pending":
+		case "dialing":
+			// The process stopped while this contact was being dialed; we cannot
+			// tell whether the call went out, so do not risk calling twice
+			q.finishContact(job, i, nil, errors.New("interrupted by server restart"))
+			continue
+		default:
+			continue
+		}
+
+		// Contacts outside their calling window are only stored as scheduled,
+		// so they take neither a throttle tick nor a live call slot
+		now := time.Now()
+		if next, allowed := q.dialer.NextAllowedTime(campaign, job.Contacts[i].PhoneNumber, now); !allowed || next.After(now) {
+			q.updateContactStatus(job, i, "dialing")
+			dialCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			call, err := q.dialer.Dial(dialCtx, campaign, job, job.Contacts[i])
+			cancel()
+			q.finishContact(job, i, call, err)
+			continue
+		}
+
+		// The live call slot reserved here is released by the worker
+		if err := q.waitForTurn(ctx, job.CampaignID); err != nil {
+			return
+		}
+
+		q.updateContactStatus(job, i, "dialing")
+		wg.Add(1)
+
+		select {
+		case <-ctx.Done():
+			q.addInFlight(job.CampaignID, -1)
+			return
+		case q.tasks <- dialTask{job: job, campaign: campaign, index: i, done: wg.Done}:
+		}
+	}
+
+	wg.Wait()
+
+	finishCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	q.db.Collection("dial_jobs").UpdateOne(
+		finishCtx,
+		bson.M{"_id": job.ID},
+		bson.M{"$set": bson.M{"status": "completed", "completed_at": now, "updated_at": now}},
+	)
+	log.Printf("✓ Dial job %s completed", job.ID.Hex())
+}
+
+// LoadCampaign loads the campaign calls are placed for
+func (q *DialQueue) LoadCampaign(ctx context.Context, campaignID primitive.ObjectID) (*models.Campaign, error) {
+	findCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var campaign models.Campaign
+	if err := q.db.Collection("campaigns").FindOne(findCtx, bson.M{"_id": campaignID}).Decode(&campaign); err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// failJob marks a job that cannot be run as failed
+func (q *DialQueue) failJob(job *models.DialJob, message string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	q.db.Collection("dial_jobs").UpdateOne(
+		ctx,
+		bson.M{"_id": job.ID},
+		bson.M{"$set": bson.M{"status": "failed", "error_message": message, "completed_at": now, "updated_at": now}},
+	)
+}
+
+// Redial places an existing call record again, subject to the same rate limit
+// and live call cap as dial jobs. It does not wait for capacity: when the
+// campaign is at its cap, ErrCampaignAtCapacity is returned and nothing is placed.
+func (q *DialQueue) Redial(ctx context.Context, call *models.Call) error {
+	reserved, err := q.tryReserve(ctx, call.CampaignID)
+	if err != nil {
+		return err
+	}
+	if !reserved {
+		return ErrCampaignAtCapacity
+	}
+	defer q.addInFlight(call.CampaignID, -1)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-q.throttle.C:
+	}
+	return q.dialer.PlaceCall(ctx, call)
+}
+
+// waitForTurn blocks until the rate limit allows another call and the campaign
+// has capacity for it, then reserves a live call slot for the campaign
+func (q *DialQueue) waitForTurn(ctx context.Context, campaignID primitive.ObjectID) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-q.throttle.C:
+	}
+	return q.waitForCapacity(ctx, campaignID)
+}
+
+// waitForCapacity blocks until the campaign is below its live call cap and
+// reserves a slot by counting the call as in flight. The check and the
+// reservation happen under q.mu, so concurrent callers cannot both take the
+// last slot; the caller releases it with addInFlight(campaignID, -1).
+func (q *DialQueue) waitForCapacity(ctx context.Context, campaignID primitive.ObjectID) error {
+	for {
+		reserved, err := q.tryReserve(ctx, campaignID)
+		if err != nil {
+			log.Printf("Failed to check live calls for campaign %s: %v", campaignID.Hex(), err)
+		} else if reserved {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// worker places calls handed over by running jobs
+func (q *DialQueue) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case task := <-q.tasks:
+			contact := task.job.Contacts[task.index]
+
+			dialCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			call, err := q.dialer.Dial(dialCtx, task.campaign, task.job, contact)
+			cancel()
+
+			q.addInFlight(task.job.CampaignID, -1)
+			q.finishContact(task.job, task.index, call, err)
+			task.done()
+		}
+	}
+}
+
+// finishContact stores the outcome of a contact and bumps the job counters
+func (q *DialQueue) finishContact(job *models.DialJob, index int, call *models.Call, dialErr error) {
+	prefix := fmt.Sprintf("contacts.%d.", index)
+	set := bson.M{"updated_at": time.Now()}
+	inc := bson.M{"processed": 1}
+
+	if call != nil {
+		set[prefix+"call_id"] = call.ID
+	}
+
+	switch {
+	case dialErr == nil && call != nil && call.Status == "scheduled":
+		// Held until the recipient's calling window opens; the call scheduler places it
+		set[prefix+"status"] = "scheduled"
+		inc["held_count"] = 1
+	case dialErr == nil:
+		set[prefix+"status"] = "initiated"
+		inc["success_count"] = 1
+	case errors.Is(dialErr, ErrDoNotCall):
+		log.Printf("Skipping %s - number is on the do-not-call list", job.Contacts[index].PhoneNumber)
+		set[prefix+"status"] = "dnc"
+		inc["dnc_count"] = 1
+	default:
+		set[prefix+"status"] = "failed"
+		set[prefix+"error_message"] = dialErr.Error()
+		inc["fail_count"] = 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := q.db.Collection("dial_jobs").UpdateOne(ctx, bson.M{"_id": job.ID}, bson.M{"$set": set, "$inc": inc})
+	if err != nil {
+		log.Printf("Failed to update dial job %s: %v", job.ID.Hex(), err)
+	}
+}
+
+func (q *DialQueue) updateContactStatus(job *models.DialJob, index int, status string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := q.db.Collection("dial_jobs").UpdateOne(
+		ctx,
+		bson.M{"_id": job.ID},
+		bson.M{"$set": bson.M{fmt.Sprintf("contacts.%d.status", index): status, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		log.Printf("Failed to update dial job %s: %v", job.ID.Hex(), err)
+	}
+}
+
+func (q *DialQueue) addInFlight(campaignID primitive.ObjectID, delta int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.inFlight[campaignID] += delta
+	if q.inFlight[campaignID] <= 0 {
+		delete(q.inFlight, campaignID)
+	}
+}
+
+// tryReserve reserves a live call slot for the campaign if it is below its cap
+func (q *DialQueue) tryReserve(ctx context.Context, campaignID primitive.ObjectID) (bool, error) {
+	if q.maxLiveCalls <= 0 {
+		q.addInFlight(campaignID, 1)
+		return true, nil
+	}
+
+	countCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	live, err := q.dialer.LiveCalls(countCtx, campaignID)
+	if err != nil {
+		return false, err
+	}
+	return q.reserveSlot(campaignID, live), nil
+}
+
+// reserveSlot counts another call in flight if the campaign's live and in
+// flight calls stay below the cap
+func (q *DialQueue) reserveSlot(campaignID primitive.ObjectID, live int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if live+q.inFlight[campaignID] >= q.maxLiveCalls {
+		return false
+	}
+	q.inFlight[campaignID]++
+	return true
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prabhatkumar/ivrcalling/config"
+	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTestDialQueue(t *testing.T, maxLiveCalls int) (*DialQueue, *database.MongoDB) {
+	t.Helper()
+	db := database.NewMemoryDB()
+	dialer := NewDialer(db, NewFakeProvider("+14155550000"), NewDNCService(db), NewEventBus())
+	cfg := &config.Config{DialCallsPerSecond: 1000, DialWorkers: 1, DialMaxLiveCalls: maxLiveCalls}
+	return NewDialQueue(db, dialer, cfg), db
+}
+
+func TestWaitForCapacityReservesSlots(t *testing.T) {
+	queue, db := newTestDialQueue(t, 3)
+	campaignID := primitive.NewObjectID()
+
+	// One call of the campaign is already live
+	_, err := db.Collection("calls").InsertOne(context.Background(), models.Call{
+		CampaignID: campaignID, Status: "in-progress", CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("failed to insert call: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	var mu sync.Mutex
+	reserved := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if queue.waitForCapacity(ctx, campaignID) == nil {
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if reserved != 2 {
+		t.Errorf("reserved %d slots, want 2 below a cap of 3 with one live call", reserved)
+	}
+
+	// A released slot can be taken again
+	queue.addInFlight(campaignID, -1)
+	if err := queue.waitForCapacity(context.Background(), campaignID); err != nil {
+		t.Errorf("waitForCapacity after a release: %v", err)
+	}
+}
@@ -0,0 +1,306 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrDoNotCall is returned when a number is on the do-not-call list
+var ErrDoNotCall = errors.New("phone number is on the do-not-call list")
+
+// ErrOutsideSchedule is returned when the campaign schedule will not allow the call any more
+var ErrOutsideSchedule = errors.New("campaign schedule does not allow calling this number")
+
+// Dialer creates call records and places them through the telephony provider
+type Dialer struct {
+	db         *database.MongoDB
+	provider   TelephonyProvider
+	dncService *DNCService
+	events     *EventBus
+}
+
+func NewDialer(db *database.MongoDB, provider TelephonyProvider, dncService *DNCService, events *EventBus) *Dialer {
+	return &Dialer{
+		db:         db,
+		provider:   provider,
+		dncService: dncService,
+		events:     events,
+	}
+}
+
+// Dial creates a call record for the contact and places the call. When the
+// campaign schedule does not allow calling the recipient right now, the call is
+// stored as "scheduled" for the next allowed time instead of being placed.
+// The returned call is non-nil whenever a call record was created, even if placing it failed.
+func (d *Dialer) Dial(ctx context.Context, campaign *models.Campaign, job *models.DialJob, contact models.DialJobContact) (*models.Call, error) {
+	// The list may have changed since the job was queued
+	blocked, err := d.dncService.IsBlocked(ctx, contact.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, ErrDoNotCall
+	}
+
+	now := time.Now()
+	nextAllowed, ok := d.NextAllowedTime(campaign, contact.PhoneNumber, now)
+	if !ok {
+		return nil, ErrOutsideSchedule
+	}
+
+	call := &models.Call{
+		CampaignID:   campaign.ID,
+		PhoneNumber:  contact.PhoneNumber,
+		CustomerName: contact.Name,
+		Direction:    CallOutbound,
+		Fields:       contact.Fields,
+		Status:       "pending",
+		Language:     job.Language,
+		CreatedBy:    job.CreatedBy,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if nextAllowed.After(now) {
+		call.Status = "scheduled"
+		call.NextAttemptAt = &nextAllowed
+	}
+
+	result, err := d.db.Collection("calls").InsertOne(ctx, call)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create call record: %w", err)
+	}
+	call.ID = result.InsertedID.(primitive.ObjectID)
+
+	log.Printf("Call record created - ID: %s, Phone: %s, Name: %s", call.ID.Hex(), contact.PhoneNumber, contact.Name)
+
+	if call.Status == "scheduled" {
+		log.Printf("Holding call %s until %s - outside the recipient's calling window", call.ID.Hex(), nextAllowed.Format(time.RFC3339))
+		d.db.Collection("call_logs").InsertOne(ctx, models.CallLog{
+			CallID:    call.ID,
+			Event:     "held_for_schedule",
+			Details:   fmt.Sprintf("Outside the calling window - call held until %s", nextAllowed.Format(time.RFC3339)),
+			CreatedAt: time.Now(),
+		})
+		d.events.PublishCall("scheduled", call, "Held until "+nextAllowed.Format(time.RFC3339))
+		return call, nil
+	}
+
+	return call, d.PlaceCall(ctx, call)
+}
+
+// NextAllowedTime returns the earliest time at or after t when the campaign's
+// schedule allows calling the number in its local time
+func (d *Dialer) NextAllowedTime(campaign *models.Campaign, phoneNumber string, t time.Time) (time.Time, bool) {
+	if campaign.Schedule == nil {
+		return t, true
+	}
+	zones := TimezonesForPhone(phoneNumber, campaign.Schedule.DefaultTimezone)
+	return NextAllowedCallTime(campaign.Schedule, zones, t)
+}
+
+// PlaceCall places an existing call record through the telephony provider and
+// records the attempt. A call that cannot be placed is handed to CompleteAttempt
+// with a "failed" outcome so the campaign's retry policy applies to it too.
+func (d *Dialer) PlaceCall(ctx context.Context, call *models.Call) error {
+	call.Attempts++
+	attempt := models.CallAttempt{
+		CallID:        call.ID,
+		CampaignID:    call.CampaignID,
+		AttemptNumber: call.Attempts,
+		StartedAt:     time.Now(),
+	}
+
+	providerCall, err := d.provider.MakeCall(call.PhoneNumber, call.Language, call.ID.Hex(), d.callOptions(ctx, call.CampaignID))
+	if err != nil {
+		log.Printf("Failed to initiate call for %s (attempt %d): %v", call.PhoneNumber, call.Attempts, err)
+
+		now := time.Now()
+		attempt.Outcome = "failed"
+		attempt.ErrorMessage = err.Error()
+		attempt.EndedAt = &now
+		d.db.Collection("call_attempts").InsertOne(ctx, attempt)
+
+		d.db.Collection("calls").UpdateOne(
+			ctx,
+			bson.M{"_id": call.ID},
+			bson.M{
+				"$set": bson.M{
+					"attempts":      call.Attempts,
+					"error_message": err.Error(),
+					"updated_at":    time.Now(),
+				},
+				"$unset": bson.M{"claimed_at": ""},
+			},
+		)
+		call.ErrorMessage = err.Error()
+		d.CompleteAttempt(ctx, call, "failed")
+		return err
+	}
+
+	attempt.TwilioCallSID = providerCall.SID
+	d.db.Collection("call_attempts").InsertOne(ctx, attempt)
+
+	// Update call with provider SID
+	d.db.Collection("calls").UpdateOne(
+		ctx,
+		bson.M{"_id": call.ID},
+		bson.M{
+			"$set": bson.M{
+				"status":          "initiated",
+				"twilio_call_sid": providerCall.SID,
+				"attempts":        call.Attempts,
+				"updated_at":      time.Now(),
+			},
+			"$unset": bson.M{"next_attempt_at": "", "outcome": "", "answered_by": "", "claimed_at": ""},
+		},
+	)
+	call.Status = "initiated"
+	call.TwilioCallSID = providerCall.SID
+	call.AnsweredBy = ""
+
+	log.Printf("✓ Call initiated successfully - SID: %s, attempt: %d", providerCall.SID, call.Attempts)
+
+	// Create call log
+	callLog := models.CallLog{
+		CallID:    call.ID,
+		Event:     "initiated",
+		Details:   fmt.Sprintf("Call initiated to %s (attempt %d)", call.PhoneNumber, call.Attempts),
+		CreatedAt: time.Now(),
+	}
+	d.db.Collection("call_logs").InsertOne(ctx, callLog)
+	d.events.PublishCall("initiated", call, callLog.Details)
+
+	return nil
+}
+
+// callOptions returns the campaign settings a call is placed with. A campaign
+// that cannot be loaded is dialed without them rather than not at all.
+func (d *Dialer) callOptions(ctx context.Context, campaignID primitive.ObjectID) CallOptions {
+	var campaign models.Campaign
+	if err := d.db.Collection("campaigns").FindOne(ctx, bson.M{"_id": campaignID}).Decode(&campaign); err != nil {
+		log.Printf("Failed to load campaign %s for call options: %v", campaignID.Hex(), err)
+		return CallOptions{}
+	}
+	return CallOptions{MachineDetection: campaign.MachineDetection, Recording: campaign.Recording}
+}
+
+// RecordAnsweredBy stores the answering machine detection result of the
+// call's current attempt and returns it normalized to human, machine, fax or
+// unknown. Machines and fax lines are published as machine_detected events.
+func (d *Dialer) RecordAnsweredBy(ctx context.Context, call *models.Call, answeredBy string) string {
+	result := NormalizeAnsweredBy(answeredBy)
+	if call.AnsweredBy == result {
+		return result
+	}
+
+	d.db.Collection("calls").UpdateOne(
+		ctx,
+		bson.M{"_id": call.ID},
+		bson.M{"$set": bson.M{"answered_by": result, "updated_at": time.Now()}},
+	)
+	call.AnsweredBy = result
+
+	details := fmt.Sprintf("Answered by: %s (%s)", result, answeredBy)
+	d.db.Collection("call_logs").InsertOne(ctx, models.CallLog{
+		CallID:    call.ID,
+		Event:     "answered_by_" + result,
+		Details:   details,
+		CreatedAt: time.Now(),
+	})
+	if result == AnsweredByMachine || result == AnsweredByFax {
+		d.events.PublishCall("machine_detected", call, details)
+	}
+
+	log.Printf("Call %s answered by %s (%s)", call.ID.Hex(), result, answeredBy)
+	return result
+}
+
+// CompleteAttempt stores the final outcome of the call's current attempt and
+// either schedules a redial according to the campaign's retry policy or
+// settles the call as completed or failed. Inbound calls are never redialed.
+// It returns the call's new status.
+func (d *Dialer) CompleteAttempt(ctx context.Context, call *models.Call, outcome string) string {
+	newStatus := "failed"
+	if outcome == "completed" {
+		newStatus = "completed"
+	}
+
+	update := bson.M{
+		"outcome":    outcome,
+		"updated_at": time.Now(),
+	}
+
+	var campaign models.Campaign
+	err := d.db.Collection("campaigns").FindOne(ctx, bson.M{"_id": call.CampaignID}).Decode(&campaign)
+	if err != nil {
+		log.Printf("Failed to load campaign %s for retry policy: %v", call.CampaignID.Hex(), err)
+	} else if call.Direction != CallInbound && campaign.RetryPolicy.ShouldRetry(outcome, call.Attempts) {
+		// Redials also have to wait for the recipient's calling window
+		next, allowed := d.NextAllowedTime(&campaign, call.PhoneNumber, campaign.RetryPolicy.NextAttempt(time.Now()))
+		if allowed {
+			newStatus = "scheduled"
+			update["next_attempt_at"] = next
+
+			log.Printf("Call %s ended with '%s' - retry %d/%d scheduled for %s",
+				call.ID.Hex(), outcome, call.Attempts+1, campaign.RetryPolicy.MaxAttempts, next.Format(time.RFC3339))
+
+			d.db.Collection("call_logs").InsertOne(ctx, models.CallLog{
+				CallID:    call.ID,
+				Event:     "retry_scheduled",
+				Details:   fmt.Sprintf("Attempt %d ended with %s - next attempt at %s", call.Attempts, outcome, next.Format(time.RFC3339)),
+				CreatedAt: time.Now(),
+			})
+		} else {
+			log.Printf("Call %s ended with '%s' - not retrying, the campaign schedule has no calling window left", call.ID.Hex(), outcome)
+		}
+	}
+	update["status"] = newStatus
+
+	d.db.Collection("calls").UpdateOne(ctx, bson.M{"_id": call.ID}, bson.M{"$set": update})
+	call.Status = newStatus
+	call.Outcome = outcome
+
+	eventType := newStatus
+	if newStatus == "scheduled" {
+		eventType = "retry_scheduled"
+	}
+	d.events.PublishCall(eventType, call, "")
+
+	return newStatus
+}
+
+// FinishAttemptRecord stores the outcome and duration on the attempt record of a provider call
+func (d *Dialer) FinishAttemptRecord(ctx context.Context, callSid string, outcome string, duration int) {
+	now := time.Now()
+	_, err := d.db.Collection("call_attempts").UpdateOne(
+		ctx,
+		bson.M{"twilio_call_sid": callSid},
+		bson.M{"$set": bson.M{"outcome": outcome, "duration": duration, "ended_at": now}},
+	)
+	if err != nil {
+		log.Printf("Failed to update call attempt %s: %v", callSid, err)
+	}
+}
+
+// LiveCalls counts calls of a campaign that are currently ringing or connected.
+// Calls that have not been updated for an hour are ignored so that a lost
+// status callback cannot block a campaign forever.
+func (d *Dialer) LiveCalls(ctx context.Context, campaignID primitive.ObjectID) (int, error) {
+	count, err := d.db.Collection("calls").CountDocuments(ctx, bson.M{
+		"campaign_id": campaignID,
+		"status":      bson.M{"$in": []string{"initiated", "in-progress"}},
+		"updated_at":  bson.M{"$gte": time.Now().Add(-time.Hour)},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count live calls: %w", err)
+	}
+	return int(count), nil
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/models"
+	"github.com/prabhatkumar/ivrshared/phone"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DNCService manages the persistent do-not-call list
+type DNCService struct {
+	db *database.MongoDB
+}
+
+func NewDNCService(db *database.MongoDB) *DNCService {
+	return &DNCService{db: db}
+}
+
+// NormalizePhoneNumber strips formatting so the same number always maps to the same key.
+// "+1 (415) 555-0100" and "001-415-555-0100" both become "+14155550100", and so
+// does "(415) 555-0100" with defaultRegion "US". Numbers that are not valid
+// in the region only have their formatting removed.
+func NormalizePhoneNumber(raw string, defaultRegion string) string {
+	if e164, err := phone.Normalize(raw, defaultRegion); err == nil {
+		return e164
+	}
+
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "00") {
+		raw = "+" + raw[2:]
+	}
+
+	var b strings.Builder
+	for i, r := range raw {
+		if r == '+' && i == 0 {
+			b.WriteRune(r)
+			continue
+		}
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// ParseDNCNumber parses a number to put on the do-not-call list. Only numbers
+// the dialer accepts are taken, so every entry can match a contact.
+func ParseDNCNumber(raw string, defaultRegion string) (string, error) {
+	number, err := phone.ParseDialable(raw, defaultRegion)
+	if err != nil {
+		return "", err
+	}
+	return number.E164, nil
+}
+
+// IsBlocked reports whether the number is on the do-not-call list
+func (s *DNCService) IsBlocked(ctx context.Context, phoneNumber string) (bool, error) {
+	count, err := s.db.Collection("dnc").CountDocuments(ctx, bson.M{"phone_number": NormalizePhoneNumber(phoneNumber, "")})
+	if err != nil {
+		return false, fmt.Errorf("failed to check do-not-call list: %w", err)
+	}
+	return count > 0, nil
+}
+
+// dncLookupBatch is how many numbers BlockedNumbers checks per query
+const dncLookupBatch = 1000
+
+// BlockedNumbers returns the set of normalized numbers from phoneNumbers that are on the list.
+// Large lists are checked in batches to keep each $in query small.
+func (s *DNCService) BlockedNumbers(ctx context.Context, phoneNumbers []string) (map[string]bool, error) {
+	normalized := make([]string, 0, len(phoneNumbers))
+	for _, phone := range phoneNumbers {
+		normalized = append(normalized, NormalizePhoneNumber(phone, ""))
+	}
+
+	blocked := map[string]bool{}
+	for start := 0; start < len(normalized); start += dncLookupBatch {
+		end := start + dncLookupBatch
+		if end > len(normalized) {
+			end = len(normalized)
+		}
+
+		cursor, err := s.db.Collection("dnc").Find(
+			ctx,
+			bson.M{"phone_number": bson.M{"$in": normalized[start:end]}},
+			options.Find().SetProjection(bson.M{"phone_number": 1}),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check do-not-call list: %w", err)
+		}
+
+		var entries []models.DNCEntry
+		if err := cursor.All(ctx, &entries); err != nil {
+			return nil, fmt.Errorf("failed to decode do-not-call entries: %w", err)
+		}
+		for _, entry := range entries {
+			blocked[entry.PhoneNumber] = true
+		}
+	}
+	return blocked, nil
+}
+
+// Add puts a number on the list. It returns false if the number was already present.
+func (s *DNCService) Add(ctx context.Context, entry models.DNCEntry) (bool, error) {
+	entry.PhoneNumber = NormalizePhoneNumber(entry.PhoneNumber, "")
+	if entry.PhoneNumber == "" {
+		return false, fmt.Errorf("phone number is empty")
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	result, err := s.db.Collection("dnc").UpdateOne(
+		ctx,
+		bson.M{"phone_number": entry.PhoneNumber},
+		bson.M{"$setOnInsert": entry},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to add number to do-not-call list: %w", err)
+	}
+	return result.UpsertedCount > 0, nil
+}
+
+// Get returns the list entry for a number
+func (s *DNCService) Get(ctx context.Context, phoneNumber string) (*models.DNCEntry, error) {
+	var entry models.DNCEntry
+	err := s.db.Collection("dnc").FindOne(ctx, bson.M{"phone_number": NormalizePhoneNumber(phoneNumber, "")}).Decode(&entry)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Remove takes a number off the list. It returns mongo.ErrNoDocuments if it was not listed.
+func (s *DNCService) Remove(ctx context.Context, phoneNumber string) error {
+	result, err := s.db.Collection("dnc").DeleteOne(ctx, bson.M{"phone_number": NormalizePhoneNumber(phoneNumber, "")})
+	if err != nil {
+		return fmt.Errorf("failed to remove number from do-not-call list: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
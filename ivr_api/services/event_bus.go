@@ -0,0 +1,86 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/prabhatkumar/ivrcalling/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// eventBufferSize is how many events a subscriber may fall behind before events are dropped for it
+const eventBufferSize = 64
+
+// EventBus fans call events out to in-process subscribers of a campaign.
+// Publishing never blocks: a subscriber that cannot keep up loses events
+// rather than slowing down webhooks and the dialer.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers map[primitive.ObjectID]map[chan models.CallEvent]struct{}
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[primitive.ObjectID]map[chan models.CallEvent]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving the events of a campaign and a function
+// that must be called to unsubscribe
+func (b *EventBus) Subscribe(campaignID primitive.ObjectID) (<-chan models.CallEvent, func()) {
+	ch := make(chan models.CallEvent, eventBufferSize)
+
+	b.mu.Lock()
+	if b.subscribers[campaignID] == nil {
+		b.subscribers[campaignID] = make(map[chan models.CallEvent]struct{})
+	}
+	b.subscribers[campaignID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			delete(b.subscribers[campaignID], ch)
+			if len(b.subscribers[campaignID]) == 0 {
+				delete(b.subscribers, campaignID)
+			}
+			close(ch)
+		})
+	}
+	return ch, unsubscribe
+}
+
+// Publish delivers an event to every subscriber of its campaign
+func (b *EventBus) Publish(event models.CallEvent) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers[event.CampaignID] {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("Dropping %s event for call %s - subscriber is not keeping up", event.Type, event.CallID.Hex())
+		}
+	}
+}
+
+// PublishCall publishes an event built from the current state of a call
+func (b *EventBus) PublishCall(eventType string, call *models.Call, details string) {
+	b.Publish(models.CallEvent{
+		Type:        eventType,
+		CampaignID:  call.CampaignID,
+		CallID:      call.ID,
+		PhoneNumber: call.PhoneNumber,
+		Status:      call.Status,
+		Outcome:     call.Outcome,
+		Details:     details,
+	})
+}
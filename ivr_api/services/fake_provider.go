@@ -0,0 +1,201 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+)
+
+// FakeProvider is an in-memory TelephonyProvider for local development and tests.
+// It never talks to a carrier; calls are kept in memory and their status can be
+// advanced with SetStatus to simulate what Twilio would report via webhooks.
+type FakeProvider struct {
+	phoneNumber string
+
+	mu          sync.Mutex
+	calls       map[string]*ProviderCall
+	callIDs     map[string]string // SID -> our call record ID
+	options     map[string]CallOptions
+	redirects   map[string][]string // SID -> webhook paths the call was redirected to
+	recordings  map[string][]byte   // recording SID -> audio
+	failNumbers map[string]error
+	seq         uint64
+}
+
+func NewFakeProvider(phoneNumber string) *FakeProvider {
+	return &FakeProvider{
+		phoneNumber: phoneNumber,
+		calls:       make(map[string]*ProviderCall),
+		callIDs:     make(map[string]string),
+		options:     make(map[string]CallOptions),
+		redirects:   make(map[string][]string),
+		recordings:  make(map[string][]byte),
+		failNumbers: make(map[string]error),
+	}
+}
+
+// MakeCall records a queued call and returns a generated SID
+func (p *FakeProvider) MakeCall(toNumber string, language string, callID string, options CallOptions) (*ProviderCall, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err, ok := p.failNumbers[toNumber]; ok {
+		return nil, fmt.Errorf("failed to create call: %w", err)
+	}
+
+	p.seq++
+	sid := fmt.Sprintf("CAFAKE%026d", p.seq)
+	call := &ProviderCall{
+		SID:    sid,
+		Status: "queued",
+		To:     toNumber,
+		From:   p.phoneNumber,
+	}
+	p.calls[sid] = call
+	p.callIDs[sid] = callID
+	p.options[sid] = options
+
+	log.Printf("✓ Fake call created - SID: %s, To: %s, Call ID: %s, Language: %s", sid, toNumber, callID, language)
+
+	copied := *call
+	return &copied, nil
+}
+
+// GetCallDetails returns the in-memory state of a fake call
+func (p *FakeProvider) GetCallDetails(callSid string) (*ProviderCall, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	call, ok := p.calls[callSid]
+	if !ok {
+		return nil, fmt.Errorf("failed to fetch call: call %s not found", callSid)
+	}
+
+	copied := *call
+	return &copied, nil
+}
+
+// HangupCall marks a fake call as completed
+func (p *FakeProvider) HangupCall(callSid string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	call, ok := p.calls[callSid]
+	if !ok {
+		return fmt.Errorf("failed to hang up call: call %s not found", callSid)
+	}
+
+	call.Status = "completed"
+	return nil
+}
+
+// RedirectCall records the webhook path a fake call was moved to
+func (p *FakeProvider) RedirectCall(callSid string, path string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.calls[callSid]; !ok {
+		return fmt.Errorf("failed to redirect call: call %s not found", callSid)
+	}
+
+	p.redirects[callSid] = append(p.redirects[callSid], path)
+	return nil
+}
+
+// FetchRecording returns the audio stored with AddRecording
+func (p *FakeProvider) FetchRecording(ctx context.Context, recordingSid string) (io.ReadCloser, string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	audio, ok := p.recordings[recordingSid]
+	if !ok {
+		return nil, "", fmt.Errorf("failed to fetch recording %s: %w", recordingSid, ErrRecordingNotFound)
+	}
+	return io.NopCloser(bytes.NewReader(audio)), "audio/mpeg", nil
+}
+
+// DeleteRecording forgets a fake recording
+func (p *FakeProvider) DeleteRecording(recordingSid string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.recordings[recordingSid]; !ok {
+		return fmt.Errorf("failed to delete recording %s: %w", recordingSid, ErrRecordingNotFound)
+	}
+	delete(p.recordings, recordingSid)
+	return nil
+}
+
+// AddRecording stores audio for a recording SID, as if Twilio had recorded it
+func (p *FakeProvider) AddRecording(recordingSid string, audio []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.recordings[recordingSid] = audio
+}
+
+// FailNumber makes every future MakeCall to the number return err
+func (p *FakeProvider) FailNumber(toNumber string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.failNumbers[toNumber] = err
+}
+
+// SetStatus changes the status (and duration) of a fake call
+func (p *FakeProvider) SetStatus(callSid string, status string, duration int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	call, ok := p.calls[callSid]
+	if !ok {
+		return fmt.Errorf("call %s not found", callSid)
+	}
+
+	call.Status = status
+	call.Duration = duration
+	return nil
+}
+
+// Calls returns a snapshot of every call placed through the provider, in the
+// order they were placed
+func (p *FakeProvider) Calls() []ProviderCall {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	calls := make([]ProviderCall, 0, len(p.calls))
+	for _, call := range p.calls {
+		calls = append(calls, *call)
+	}
+	// SIDs carry the zero-padded sequence number
+	sort.Slice(calls, func(i, j int) bool { return calls[i].SID < calls[j].SID })
+	return calls
+}
+
+// CallID returns the call record ID a fake call was placed for
+func (p *FakeProvider) CallID(callSid string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.callIDs[callSid]
+}
+
+// Options returns the options a fake call was placed with
+func (p *FakeProvider) Options(callSid string) CallOptions {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.options[callSid]
+}
+
+// Redirects returns the webhook paths a fake call was redirected to
+func (p *FakeProvider) Redirects(callSid string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]string(nil), p.redirects[callSid]...)
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prabhatkumar/ivrcalling/models"
+	"github.com/prabhatkumar/ivrshared/phone"
+)
+
+// Dial strategies of a forward action's ring group
+const (
+	ForwardSequential   = "sequential"
+	ForwardSimultaneous = "simultaneous"
+)
+
+// Fallbacks of a forward action nobody answered, besides the key of another action
+const (
+	ForwardFallbackMenu   = "menu"
+	ForwardFallbackHangup = "hangup"
+)
+
+// Limits of forward actions
+const (
+	MaxForwardNumbers = 10 // Twilio dials at most 10 numbers at once
+	MinForwardTimeout = 5
+	MaxForwardTimeout = 600
+)
+
+// ValidateForwardSettings checks the settings of a "forward" action and
+// normalizes its numbers to E.164. siblings are the actions on the same menu
+// level, which the fallback may name.
+func ValidateForwardSettings(action *models.IVRAction, siblings []models.IVRAction) error {
+	settings := action.Forward
+	if settings == nil {
+		return nil
+	}
+
+	if len(settings.Numbers)+1 > MaxForwardNumbers {
+		return fmt.Errorf("forward.numbers can hold at most %d numbers besides forward_phone", MaxForwardNumbers-1)
+	}
+	for i, raw := range settings.Numbers {
+		number, err := phone.ParseDialable(raw, "")
+		if err != nil {
+			return fmt.Errorf("forward.numbers[%d]: %v", i, err)
+		}
+		settings.Numbers[i] = number.E164
+	}
+
+	switch settings.Strategy {
+	case "", ForwardSequential, ForwardSimultaneous:
+	default:
+		return fmt.Errorf("forward.strategy must be '%s' or '%s'", ForwardSequential, ForwardSimultaneous)
+	}
+
+	if settings.Timeout != 0 && (settings.Timeout < MinForwardTimeout || settings.Timeout > MaxForwardTimeout) {
+		return fmt.Errorf("forward.timeout must be between %d and %d seconds", MinForwardTimeout, MaxForwardTimeout)
+	}
+
+	if settings.CallerID != "" {
+		number, err := phone.ParseDialable(settings.CallerID, "")
+		if err != nil {
+			return fmt.Errorf("forward.caller_id: %v", err)
+		}
+		settings.CallerID = number.E164
+	}
+
+	for label, text := range map[string]string{
+		"whisper":             settings.Whisper,
+		"unavailable_message": settings.UnavailableMessage,
+		"after_hours_message": settings.AfterHoursMessage,
+	} {
+		if err := ValidateMessage(text); err != nil {
+			return fmt.Errorf("forward.%s: %v", label, err)
+		}
+	}
+
+	switch fallback := strings.TrimSpace(settings.Fallback); fallback {
+	case "", ForwardFallbackMenu, ForwardFallbackHangup:
+	default:
+		if fallback == action.ActionInput {
+			return fmt.Errorf("forward.fallback cannot be the forward action itself")
+		}
+		target := siblingAction(siblings, fallback)
+		if target == nil {
+			return fmt.Errorf("forward.fallback must be '%s', '%s' or the key of another action on the same menu level", ForwardFallbackMenu, ForwardFallbackHangup)
+		}
+		if target.ActionType == "forward" {
+			// Fallbacks chaining forward actions could ring forever; use numbers instead
+			return fmt.Errorf("forward.fallback cannot be another forward action, add its numbers to forward.numbers instead")
+		}
+		settings.Fallback = fallback
+	}
+
+	if settings.CalendarID == nil && (settings.AfterHoursAction != "" || settings.AfterHoursMessage != "") {
+		return fmt.Errorf("forward.after_hours_action and after_hours_message need a forward.calendar_id")
+	}
+	if key := strings.TrimSpace(settings.AfterHoursAction); key != "" {
+		if key == action.ActionInput {
+			return fmt.Errorf("forward.after_hours_action cannot be the forward action itself")
+		}
+		if siblingAction(siblings, key) == nil {
+			return fmt.Errorf("forward.after_hours_action must be the key of another action on the same menu level")
+		}
+		settings.AfterHoursAction = key
+	}
+	return nil
+}
+
+// siblingAction returns the action bound to key among the actions of a menu level
+func siblingAction(siblings []models.IVRAction, key string) *models.IVRAction {
+	for i := range siblings {
+		if strings.TrimSpace(siblings[i].ActionInput) == key {
+			return &siblings[i]
+		}
+	}
+	return nil
+}
+
+// ForwardNumbers returns the numbers a forward action dials, in order
+func ForwardNumbers(action *models.IVRAction) []string {
+	numbers := []string{action.ForwardPhone}
+	if action.Forward != nil {
+		numbers = append(numbers, action.Forward.Numbers...)
+	}
+	return numbers
+}
+
+// ForwardsSimultaneously reports whether all numbers of a forward action ring at once
+func ForwardsSimultaneously(action *models.IVRAction) bool {
+	return action.Forward != nil && action.Forward.Strategy == ForwardSimultaneous
+}
+
+// ForwardFallback returns what happens when nobody answered a forward action:
+// ForwardFallbackMenu, ForwardFallbackHangup or the key of another action
+func ForwardFallback(action *models.IVRAction) string {
+	if action.Forward == nil || action.Forward.Fallback == "" {
+		return ForwardFallbackMenu
+	}
+	return action.Forward.Fallback
+}
+
+// ForwardConnected reports whether a DialCallStatus means an agent answered
+func ForwardConnected(dialCallStatus string) bool {
+	return dialCallStatus == "completed" || dialCallStatus == "answered"
+}
+
+// ForwardOutcomeEvent returns the call log event of a forward outcome, such
+// as forward_completed or forward_no_answer
+func ForwardOutcomeEvent(dialCallStatus string) string {
+	if dialCallStatus == "" {
+		dialCallStatus = "failed"
+	}
+	return "forward_" + strings.ReplaceAll(dialCallStatus, "-", "_")
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/models"
+	"github.com/prabhatkumar/ivrshared/phone"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Directions of a call
+const (
+	CallOutbound = "outbound"
+	CallInbound  = "inbound"
+)
+
+// ErrNoInboundCampaign is returned when no active campaign answers the dialed number
+var ErrNoInboundCampaign = errors.New("no active campaign answers this number")
+
+// ErrPhoneNumberInUse is returned when another campaign already answers a number
+var ErrPhoneNumberInUse = errors.New("phone number is already used by another campaign")
+
+// ValidatePhoneNumbers normalizes the inbound numbers of a campaign to E.164
+func ValidatePhoneNumbers(numbers []string) error {
+	seen := map[string]bool{}
+	for i, raw := range numbers {
+		number, err := phone.ParseDialable(raw, "")
+		if err != nil {
+			return fmt.Errorf("phone_numbers[%d]: %v", i, err)
+		}
+		if seen[number.E164] {
+			return fmt.Errorf("phone_numbers[%d]: %s is listed twice", i, number.E164)
+		}
+		seen[number.E164] = true
+		numbers[i] = number.E164
+	}
+	return nil
+}
+
+// InboundService answers calls to campaign phone numbers
+type InboundService struct {
+	db     *database.MongoDB
+	events *EventBus
+}
+
+func NewInboundService(db *database.MongoDB, events *EventBus) *InboundService {
+	return &InboundService{
+		db:     db,
+		events: events,
+	}
+}
+
+// CheckPhoneNumbers returns ErrPhoneNumberInUse when a campaign other than
+// campaignID already answers one of the numbers
+func (s *InboundService) CheckPhoneNumbers(ctx context.Context, campaignID primitive.ObjectID, numbers []string) error {
+	if len(numbers) == 0 {
+		return nil
+	}
+
+	var other models.Campaign
+	err := s.db.Collection("campaigns").FindOne(ctx, bson.M{
+		"_id":           bson.M{"$ne": campaignID},
+		"phone_numbers": bson.M{"$in": numbers},
+	}).Decode(&other)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check phone numbers: %w", err)
+	}
+	return fmt.Errorf("%w: campaign %s (%s)", ErrPhoneNumberInUse, other.ID.Hex(), other.Name)
+}
+
+// Answer creates the inbound call record of a call to a campaign number and
+// returns it with the campaign. Callers that were called before keep the name,
+// fields and language of their latest outbound call. Twilio asking for the
+// call's TwiML again gets the call record created the first time, with
+// created set to false.
+func (s *InboundService) Answer(ctx context.Context, callSid, from, to string) (call *models.Call, campaign *models.Campaign, created bool, err error) {
+	if number, err := phone.ParseDialable(to, ""); err == nil {
+		to = number.E164
+	}
+	// Withheld numbers arrive as e.g. "anonymous" and are kept as they are
+	if number, err := phone.ParseDialable(from, ""); err == nil {
+		from = number.E164
+	}
+
+	campaign = &models.Campaign{}
+	err = s.db.Collection("campaigns").FindOne(ctx, bson.M{"phone_numbers": to, "is_active": true}).Decode(campaign)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil, false, ErrNoInboundCampaign
+	}
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to find campaign for %s: %w", to, err)
+	}
+
+	var existing models.Call
+	if err := s.db.Collection("calls").FindOne(ctx, bson.M{"twilio_call_sid": callSid}).Decode(&existing); err == nil {
+		return &existing, campaign, false, nil
+	}
+
+	now := time.Now()
+	call = &models.Call{
+		CampaignID:    campaign.ID,
+		PhoneNumber:   from,
+		Direction:     CallInbound,
+		Status:        "in-progress",
+		TwilioCallSID: callSid,
+		Language:      campaign.Language,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	details := fmt.Sprintf("Inbound call from %s to %s", from, to)
+	if known := s.knownCaller(ctx, campaign.ID, from); known != nil {
+		call.CustomerName = known.CustomerName
+		call.Fields = known.Fields
+		if known.Language != "" {
+			call.Language = known.Language
+		}
+		details = fmt.Sprintf("Inbound call from %s (%s) to %s", from, known.CustomerName, to)
+	}
+	if call.Language == "" {
+		call.Language = "en"
+	}
+
+	result, err := s.db.Collection("calls").InsertOne(ctx, call)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to create inbound call record: %w", err)
+	}
+	call.ID = result.InsertedID.(primitive.ObjectID)
+
+	log.Printf("✓ %s - campaign %s, call %s", details, campaign.Name, call.ID.Hex())
+	s.db.Collection("call_logs").InsertOne(ctx, models.CallLog{
+		CallID:    call.ID,
+		Event:     "inbound",
+		Details:   details,
+		CreatedAt: now,
+	})
+	s.events.PublishCall("inbound", call, details)
+
+	return call, campaign, true, nil
+}
+
+// knownCaller returns the latest outbound call to a number, preferring calls
+// of the campaign that was called, or nil for unknown callers
+func (s *InboundService) knownCaller(ctx context.Context, campaignID primitive.ObjectID, number string) *models.Call {
+	latest := options.FindOne().SetSort(bson.M{"created_at": -1})
+	for _, filter := range []bson.M{
+		{"phone_number": number, "direction": bson.M{"$ne": CallInbound}, "campaign_id": campaignID},
+		{"phone_number": number, "direction": bson.M{"$ne": CallInbound}},
+	} {
+		var call models.Call
+		if err := s.db.Collection("calls").FindOne(ctx, filter, latest).Decode(&call); err == nil {
+			return &call
+		}
+	}
+	return nil
+}
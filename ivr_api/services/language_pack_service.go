@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var languageCodePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// cachedPack is a loaded language pack
+type cachedPack struct {
+	name     string
+	fallback string
+	strings  LanguageStrings
+}
+
+// packCache holds the loaded language packs used to generate TwiML
+type packCache struct {
+	mu    sync.RWMutex
+	packs map[string]cachedPack
+}
+
+// languageCache starts with the built-in languages so TwiML can be generated
+// before the packs are loaded from the database
+var languageCache = newPackCache()
+
+func newPackCache() *packCache {
+	packs := make(map[string]cachedPack, len(builtinLanguages))
+	for code, ls := range builtinLanguages {
+		packs[code] = cachedPack{name: builtinLanguageNames[code], strings: ls}
+	}
+	return &packCache{packs: packs}
+}
+
+func (c *packCache) get(code string) (cachedPack, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	pack, ok := c.packs[code]
+	return pack, ok
+}
+
+func (c *packCache) codes() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	codes := make([]string, 0, len(c.packs))
+	for code := range c.packs {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+func (c *packCache) replace(packs map[string]cachedPack) {
+	c.mu.Lock()
+	c.packs = packs
+	c.mu.Unlock()
+}
+
+// CanonicalLanguageCode formats a language tag as "pt-BR": the language in
+// lower case, two-letter regions in upper case and four-letter scripts in title case
+func CanonicalLanguageCode(code string) string {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(strings.ReplaceAll(code, "_", "-"))), "-")
+	for i := 1; i < len(parts); i++ {
+		switch len(parts[i]) {
+		case 2:
+			parts[i] = strings.ToUpper(parts[i])
+		case 4:
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// LanguageChain returns the codes tried for a language, most specific first.
+// Each step follows the pack's fallback or else drops the last subtag, and
+// every chain ends with the default language: pt-BR → pt → en.
+func LanguageChain(lang string) []string {
+	var chain []string
+	seen := map[string]bool{}
+	code := CanonicalLanguageCode(lang)
+	for code != "" && !seen[code] {
+		seen[code] = true
+		chain = append(chain, code)
+
+		if pack, ok := languageCache.get(code); ok && pack.fallback != "" {
+			code = pack.fallback
+		} else if i := strings.LastIndex(code, "-"); i > 0 {
+			code = code[:i]
+		} else {
+			code = ""
+		}
+	}
+	if !seen[DefaultLanguage] {
+		chain = append(chain, DefaultLanguage)
+	}
+	return chain
+}
+
+// ValidateLanguagePack normalizes the code and fallback of a pack and checks
+// that it has a name and a non-empty text for every key
+func ValidateLanguagePack(pack *models.LanguagePack) error {
+	pack.Code = CanonicalLanguageCode(pack.Code)
+	if !languageCodePattern.MatchString(strings.ToLower(pack.Code)) {
+		return fmt.Errorf("code must be a language tag such as 'en' or 'pt-BR'")
+	}
+	pack.Name = strings.TrimSpace(pack.Name)
+	if pack.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if pack.Fallback != "" {
+		pack.Fallback = CanonicalLanguageCode(pack.Fallback)
+		if pack.Fallback == pack.Code {
+			return fmt.Errorf("a language pack cannot fall back to itself")
+		}
+	}
+
+	known := make(map[string]bool, len(LanguageKeys))
+	var missing []string
+	for _, k := range LanguageKeys {
+		known[k.Key] = true
+		if strings.TrimSpace(pack.Strings[k.Key]) == "" {
+			missing = append(missing, k.Key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing strings: %s", strings.Join(missing, ", "))
+	}
+	for key := range pack.Strings {
+		if !known[key] {
+			return fmt.Errorf("unknown string '%s'", key)
+		}
+	}
+	if strings.Count(pack.Strings["welcome"], "%s") != 1 {
+		return fmt.Errorf("welcome must contain %%s exactly once, where the customer's name is spoken")
+	}
+	return nil
+}
+
+// LanguagePackService stores language packs in MongoDB and keeps the cache
+// used by GetLanguageStrings in sync with them
+type LanguagePackService struct {
+	db       *database.MongoDB
+	interval time.Duration
+}
+
+func NewLanguagePackService(db *database.MongoDB) *LanguagePackService {
+	return &LanguagePackService{
+		db:       db,
+		interval: time.Minute,
+	}
+}
+
+// Start seeds an empty collection with the built-in languages, loads the packs
+// and reloads them in the background until ctx is done, picking up changes
+// made through other instances
+func (s *LanguagePackService) Start(ctx context.Context) {
+	if err := s.seed(ctx); err != nil {
+		log.Printf("✗ Failed to seed language packs: %v", err)
+	}
+	if err := s.Reload(ctx); err != nil {
+		log.Printf("✗ Failed to load language packs, using built-in languages: %v", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Reload(ctx); err != nil {
+					log.Printf("✗ Failed to reload language packs: %v", err)
+				}
+			}
+		}
+	}()
+
+	log.Printf("✓ Language packs loaded: %s", strings.Join(GetSupportedLanguages(), ", "))
+}
+
+// Reload replaces the cached packs with the ones in the database
+func (s *LanguagePackService) Reload(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := s.db.Collection("language_packs").Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var packs []models.LanguagePack
+	if err := cursor.All(ctx, &packs); err != nil {
+		return err
+	}
+	if len(packs) == 0 {
+		// Keep speaking the built-in languages rather than nothing
+		return fmt.Errorf("no language packs found")
+	}
+
+	cached := make(map[string]cachedPack, len(packs))
+	for _, pack := range packs {
+		cached[pack.Code] = cachedPack{
+			name:     pack.Name,
+			fallback: pack.Fallback,
+			strings:  LanguageStringsFromMap(pack.Strings),
+		}
+	}
+	languageCache.replace(cached)
+	return nil
+}
+
+// seed inserts the built-in languages when no pack exists yet
+func (s *LanguagePackService) seed(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	count, err := s.db.Collection("language_packs").CountDocuments(ctx, bson.M{})
+	if err != nil || count > 0 {
+		return err
+	}
+
+	now := time.Now()
+	docs := make([]interface{}, 0, len(builtinLanguages))
+	for code, ls := range builtinLanguages {
+		docs = append(docs, models.LanguagePack{
+			Code:      code,
+			Name:      builtinLanguageNames[code],
+			Strings:   ls.Map(),
+			UpdatedBy: "system",
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+	if _, err := s.db.Collection("language_packs").InsertMany(ctx, docs); err != nil {
+		return err
+	}
+	log.Printf("✓ Seeded %d built-in language packs", len(docs))
+	return nil
+}
@@ -1,6 +1,14 @@
 package services
 
-// LanguageStrings contains all IVR messages for different languages
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultLanguage ends every fallback chain
+const DefaultLanguage = "en"
+
+// LanguageStrings contains all IVR messages of one language
 type LanguageStrings struct {
 	Welcome         string
 	MainMenu        string
@@ -16,7 +24,55 @@ type LanguageStrings struct {
 	TransferMessage string
 }
 
-var Languages = map[string]LanguageStrings{
+// LanguageKeys lists the message keys of a language pack and the field each fills
+var LanguageKeys = []struct {
+	Key   string
+	Field func(*LanguageStrings) *string
+}{
+	{"welcome", func(s *LanguageStrings) *string { return &s.Welcome }},
+	{"main_menu", func(s *LanguageStrings) *string { return &s.MainMenu }},
+	{"press_for_info", func(s *LanguageStrings) *string { return &s.PressForInfo }},
+	{"press_to_opt_out", func(s *LanguageStrings) *string { return &s.PressToOptOut }},
+	{"press_to_repeat", func(s *LanguageStrings) *string { return &s.PressToRepeat }},
+	{"thank_you", func(s *LanguageStrings) *string { return &s.ThankYou }},
+	{"goodbye", func(s *LanguageStrings) *string { return &s.Goodbye }},
+	{"invalid_input", func(s *LanguageStrings) *string { return &s.InvalidInput }},
+	{"product_info", func(s *LanguageStrings) *string { return &s.ProductInfo }},
+	{"offer_details", func(s *LanguageStrings) *string { return &s.OfferDetails }},
+	{"opt_out_confirm", func(s *LanguageStrings) *string { return &s.OptOutConfirm }},
+	{"transfer_message", func(s *LanguageStrings) *string { return &s.TransferMessage }},
+}
+
+// LanguageStringsFromMap builds LanguageStrings from the strings of a language pack
+func LanguageStringsFromMap(values map[string]string) LanguageStrings {
+	var ls LanguageStrings
+	for _, k := range LanguageKeys {
+		*k.Field(&ls) = values[k.Key]
+	}
+	return ls
+}
+
+// Map returns the strings keyed like a language pack
+func (ls LanguageStrings) Map() map[string]string {
+	values := make(map[string]string, len(LanguageKeys))
+	for _, k := range LanguageKeys {
+		values[k.Key] = *k.Field(&ls)
+	}
+	return values
+}
+
+// builtinLanguageNames names the built-in languages
+var builtinLanguageNames = map[string]string{
+	"en": "English",
+	"es": "Español",
+	"fr": "Français",
+	"de": "Deutsch",
+	"hi": "हिन्दी",
+}
+
+// builtinLanguages seeds an empty language_packs collection. English is also
+// the last resort when no pack can be loaded.
+var builtinLanguages = map[string]LanguageStrings{
 	"en": { // English
 		Welcome:         "Hello %s, welcome to our marketing campaign.",
 		MainMenu:        "Press 1 for product information. Press 2 for special offers. Press 3 to opt out. Press 9 to repeat this menu.",
@@ -89,20 +145,117 @@ var Languages = map[string]LanguageStrings{
 	},
 }
 
-// GetLanguageStrings returns the language strings for the given language code
+// GetLanguageStrings returns the strings of the first loaded language pack
+// in the fallback chain of the given language code
 func GetLanguageStrings(lang string) LanguageStrings {
-	if strings, ok := Languages[lang]; ok {
-		return strings
+	for _, code := range LanguageChain(lang) {
+		if pack, ok := languageCache.get(code); ok {
+			return pack.strings
+		}
 	}
-	// Default to English if language not found
-	return Languages["en"]
+	return builtinLanguages[DefaultLanguage]
 }
 
-// GetSupportedLanguages returns a list of all supported language codes
+// GetSupportedLanguages returns the codes of all loaded language packs
 func GetSupportedLanguages() []string {
-	langs := make([]string, 0, len(Languages))
-	for lang := range Languages {
-		langs = append(langs, lang)
+	return languageCache.codes()
+}
+
+// Voice engines
+const (
+	VoiceEngineNeural   = "neural"
+	VoiceEngineStandard = "standard"
+)
+
+// Voice is a text-to-speech voice offered by Twilio
+type Voice struct {
+	Name   string `json:"name"`   // provider voice name used in <Say voice>, e.g. Polly.Joanna-Neural
+	Locale string `json:"locale"` // language attribute of <Say>, e.g. en-US
+	Gender string `json:"gender"` // female or male
+	Engine string `json:"engine"` // neural or standard
+}
+
+// Voices is the voice catalogue per language code. The first voice of each
+// language is its default.
+var Voices = map[string][]Voice{
+	"en": {
+		{Name: "Polly.Joanna-Neural", Locale: "en-US", Gender: "female", Engine: VoiceEngineNeural},
+		{Name: "Polly.Matthew-Neural", Locale: "en-US", Gender: "male", Engine: VoiceEngineNeural},
+		{Name: "Polly.Amy-Neural", Locale: "en-GB", Gender: "female", Engine: VoiceEngineNeural},
+		{Name: "Polly.Brian-Neural", Locale: "en-GB", Gender: "male", Engine: VoiceEngineNeural},
+		{Name: "Polly.Kajal-Neural", Locale: "en-IN", Gender: "female", Engine: VoiceEngineNeural},
+		{Name: "Polly.Aditi", Locale: "en-IN", Gender: "female", Engine: VoiceEngineStandard},
+		{Name: "alice", Locale: "en-US", Gender: "female", Engine: VoiceEngineStandard},
+	},
+	"es": {
+		{Name: "Polly.Lucia-Neural", Locale: "es-ES", Gender: "female", Engine: VoiceEngineNeural},
+		{Name: "Polly.Sergio-Neural", Locale: "es-ES", Gender: "male", Engine: VoiceEngineNeural},
+		{Name: "Polly.Lupe-Neural", Locale: "es-US", Gender: "female", Engine: VoiceEngineNeural},
+		{Name: "Polly.Mia-Neural", Locale: "es-MX", Gender: "female", Engine: VoiceEngineNeural},
+		{Name: "alice", Locale: "es-ES", Gender: "female", Engine: VoiceEngineStandard},
+	},
+	"fr": {
+		{Name: "Polly.Lea-Neural", Locale: "fr-FR", Gender: "female", Engine: VoiceEngineNeural},
+		{Name: "Polly.Remi-Neural", Locale: "fr-FR", Gender: "male", Engine: VoiceEngineNeural},
+		{Name: "Polly.Mathieu", Locale: "fr-FR", Gender: "male", Engine: VoiceEngineStandard},
+		{Name: "alice", Locale: "fr-FR", Gender: "female", Engine: VoiceEngineStandard},
+	},
+	"de": {
+		{Name: "Polly.Vicki-Neural", Locale: "de-DE", Gender: "female", Engine: VoiceEngineNeural},
+		{Name: "Polly.Daniel-Neural", Locale: "de-DE", Gender: "male", Engine: VoiceEngineNeural},
+		{Name: "Polly.Hans", Locale: "de-DE", Gender: "male", Engine: VoiceEngineStandard},
+		{Name: "alice", Locale: "de-DE", Gender: "female", Engine: VoiceEngineStandard},
+	},
+	"hi": {
+		{Name: "Polly.Kajal-Neural", Locale: "hi-IN", Gender: "female", Engine: VoiceEngineNeural},
+		{Name: "Polly.Aditi", Locale: "hi-IN", Gender: "female", Engine: VoiceEngineStandard},
+	},
+}
+
+// GetVoices returns the voices available for a language: the catalogue of
+// the first language in its fallback chain that has one
+func GetVoices(lang string) []Voice {
+	for _, code := range LanguageChain(lang) {
+		if voices := Voices[code]; len(voices) > 0 {
+			return voices
+		}
+	}
+	return Voices[DefaultLanguage]
+}
+
+// DefaultVoice returns the default voice of a language
+func DefaultVoice(lang string) Voice {
+	return GetVoices(lang)[0]
+}
+
+// FindVoice looks up a voice by name in the catalogue of a language
+func FindVoice(lang, name string) (Voice, bool) {
+	for _, voice := range GetVoices(lang) {
+		if strings.EqualFold(voice.Name, name) {
+			return voice, true
+		}
+	}
+	return Voice{}, false
+}
+
+// ResolveVoice returns the named voice when the language offers it, and the
+// language's default voice otherwise
+func ResolveVoice(lang, name string) Voice {
+	if name != "" {
+		if voice, ok := FindVoice(lang, name); ok {
+			return voice
+		}
+	}
+	return DefaultVoice(lang)
+}
+
+// ValidateVoice checks that a campaign voice is offered for the campaign's language
+func ValidateVoice(lang, name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, ok := FindVoice(lang, name); !ok {
+		return fmt.Errorf("voice '%s' is not available for language '%s'", name, lang)
 	}
-	return langs
+	return nil
 }
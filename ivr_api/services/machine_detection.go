@@ -0,0 +1,79 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prabhatkumar/ivrcalling/models"
+)
+
+// Limits of answering machine detection
+const (
+	MinMachineDetectionTimeout = 3
+	MaxMachineDetectionTimeout = 59
+)
+
+// Normalized results of answering machine detection stored on calls
+const (
+	AnsweredByHuman   = "human"
+	AnsweredByMachine = "machine"
+	AnsweredByFax     = "fax"
+	AnsweredByUnknown = "unknown"
+)
+
+// ValidateMachineDetection checks a campaign's answering machine detection settings
+func ValidateMachineDetection(settings *models.MachineDetection) error {
+	if settings == nil {
+		return nil
+	}
+	if settings.TimeoutSeconds != 0 &&
+		(settings.TimeoutSeconds < MinMachineDetectionTimeout || settings.TimeoutSeconds > MaxMachineDetectionTimeout) {
+		return fmt.Errorf("machine_detection.timeout_seconds must be between %d and %d",
+			MinMachineDetectionTimeout, MaxMachineDetectionTimeout)
+	}
+	if err := ValidateMessage(settings.VoicemailMessage); err != nil {
+		return fmt.Errorf("machine_detection.voicemail_message: %v", err)
+	}
+	return nil
+}
+
+// MachineDetectionEnabled reports whether calls should detect answering machines
+func MachineDetectionEnabled(settings *models.MachineDetection) bool {
+	return settings != nil && settings.Enabled
+}
+
+// LeavesVoicemail reports whether a message is left on answering machines
+func LeavesVoicemail(settings *models.MachineDetection) bool {
+	return MachineDetectionEnabled(settings) && strings.TrimSpace(settings.VoicemailMessage) != ""
+}
+
+// TwilioMachineDetectionMode returns Twilio's MachineDetection value. Leaving
+// a voicemail needs DetectMessageEnd so the message starts after the beep;
+// otherwise Enable reports the result as soon as a machine is recognised.
+func TwilioMachineDetectionMode(settings *models.MachineDetection) string {
+	if LeavesVoicemail(settings) {
+		return "DetectMessageEnd"
+	}
+	return "Enable"
+}
+
+// NormalizeAnsweredBy maps Twilio's AnsweredBy values (human, machine_start,
+// machine_end_beep, fax, ...) to human, machine, fax or unknown
+func NormalizeAnsweredBy(answeredBy string) string {
+	switch {
+	case answeredBy == "human":
+		return AnsweredByHuman
+	case strings.HasPrefix(answeredBy, "machine"):
+		return AnsweredByMachine
+	case answeredBy == "fax":
+		return AnsweredByFax
+	default:
+		return AnsweredByUnknown
+	}
+}
+
+// MachineMessageReady reports whether Twilio's AnsweredBy value means the
+// machine's greeting has ended, so a voicemail message can be left now
+func MachineMessageReady(answeredBy string) bool {
+	return strings.HasPrefix(answeredBy, "machine_end")
+}
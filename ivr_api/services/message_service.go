@@ -0,0 +1,78 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/prabhatkumar/ivrcalling/models"
+)
+
+// Limits of "record" actions; lengths are in seconds
+const (
+	DefaultRecordMaxLength = 120
+	MaxRecordLength        = 3600
+	MaxTranscribedLength   = 120 // Twilio only transcribes recordings up to two minutes
+	DefaultRecordFinishKey = "#"
+	recordSilenceTimeout   = 5
+)
+
+// Transcription statuses of a caller message
+const (
+	TranscriptionPending   = "pending"
+	TranscriptionCompleted = "completed"
+	TranscriptionFailed    = "failed"
+)
+
+// ValidateRecordSettings checks the settings of a "record" action
+func ValidateRecordSettings(settings *models.RecordSettings) error {
+	if settings.MaxLength < 0 || settings.MaxLength > MaxRecordLength {
+		return fmt.Errorf("record.max_length must be between 1 and %d seconds", MaxRecordLength)
+	}
+	if settings.Transcribe && RecordMaxLength(settings) > MaxTranscribedLength {
+		return fmt.Errorf("record.max_length must be at most %d seconds when transcribe is set", MaxTranscribedLength)
+	}
+	switch settings.FinishOnKey {
+	case "", "#", "*":
+	default:
+		return fmt.Errorf("record.finish_on_key must be # or *")
+	}
+	if err := ValidateMessage(settings.SuccessMessage); err != nil {
+		return fmt.Errorf("record.success_message: %v", err)
+	}
+	return nil
+}
+
+// RecordMaxLength returns the longest message in seconds a caller can leave
+func RecordMaxLength(settings *models.RecordSettings) int {
+	if settings.MaxLength == 0 {
+		return DefaultRecordMaxLength
+	}
+	return settings.MaxLength
+}
+
+// RecordFinishKey returns the key that ends the message
+func RecordFinishKey(settings *models.RecordSettings) string {
+	if settings.FinishOnKey == "" {
+		return DefaultRecordFinishKey
+	}
+	return settings.FinishOnKey
+}
+
+// NewCallerMessage builds the message stored on the call from Twilio's
+// <Record> action callback
+func NewCallerMessage(action *models.IVRAction, update models.RecordingStatusUpdate, duration int) models.CallerMessage {
+	message := models.CallerMessage{
+		ActionInput:  action.ActionInput,
+		RecordingSID: update.RecordingSid,
+		RecordingURL: update.RecordingURL,
+		Duration:     duration,
+	}
+	if action.Record.Transcribe {
+		message.TranscriptionStatus = TranscriptionPending
+	}
+	return message
+}
+
+// RecordedMessageDetails describes a recorded message for call logs and events
+func RecordedMessageDetails(message models.CallerMessage) string {
+	return fmt.Sprintf("Caller left a %d second message (%s)", message.Duration, message.RecordingSID)
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/prabhatkumar/ivrcalling/config"
+	"github.com/prabhatkumar/ivrcalling/database"
+	"github.com/prabhatkumar/ivrcalling/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Recording modes of a campaign
+const (
+	RecordCall    = "call"
+	RecordForward = "forward"
+)
+
+// RecordedMessage is the source of recordings left through "record" actions
+const RecordedMessage = "message"
+
+// MaxRecordingRetentionDays caps the retention a campaign can ask for
+const MaxRecordingRetentionDays = 3650
+
+// recordingCallbackPath receives Twilio's recording status callbacks
+const recordingCallbackPath = "/api/webhook/recording"
+
+// ValidateRecordingSettings checks a campaign's recording settings
+func ValidateRecordingSettings(settings *models.RecordingSettings) error {
+	if settings == nil {
+		return nil
+	}
+	if settings.Mode != RecordCall && settings.Mode != RecordForward {
+		return fmt.Errorf("recording.mode must be '%s' or '%s'", RecordCall, RecordForward)
+	}
+	if settings.RetentionDays < 0 || settings.RetentionDays > MaxRecordingRetentionDays {
+		return fmt.Errorf("recording.retention_days must be between 0 and %d", MaxRecordingRetentionDays)
+	}
+	if err := ValidateMessage(settings.ConsentMessage); err != nil {
+		return fmt.Errorf("recording.consent_message: %v", err)
+	}
+	return nil
+}
+
+// RecordsWholeCall reports whether calls are recorded from the moment they are answered
+func RecordsWholeCall(settings *models.RecordingSettings) bool {
+	return settings != nil && settings.Mode == RecordCall
+}
+
+// RecordsForwards reports whether forwarded conversations are recorded
+func RecordsForwards(settings *models.RecordingSettings) bool {
+	return settings != nil && settings.Mode == RecordForward
+}
+
+// RecordingChannels returns Twilio's RecordingChannels value, mono or dual
+func RecordingChannels(settings *models.RecordingSettings) string {
+	if settings != nil && settings.DualChannel {
+		return "dual"
+	}
+	return "mono"
+}
+
+// RecordingService stores the recordings reported by Twilio, proxies their
+// audio and deletes them once their retention has passed
+type RecordingService struct {
+	db            *database.MongoDB
+	provider      TelephonyProvider
+	retentionDays int
+	interval      time.Duration
+}
+
+func NewRecordingService(db *database.MongoDB, provider TelephonyProvider, cfg *config.Config) *RecordingService {
+	return &RecordingService{
+		db:            db,
+		provider:      provider,
+		retentionDays: cfg.RecordingRetentionDays,
+		interval:      time.Hour,
+	}
+}
+
+// Start runs the retention job in the background until ctx is done
+func (s *RecordingService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			s.deleteExpired(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	log.Printf("✓ Recording retention job started - checking every %s", s.interval)
+}
+
+// Store creates or updates the recording described by a status callback.
+// Recordings of calls this service did not place are ignored.
+func (s *RecordingService) Store(ctx context.Context, update models.RecordingStatusUpdate) (*models.Recording, error) {
+	var call models.Call
+	if err := s.db.Collection("calls").FindOne(ctx, bson.M{"twilio_call_sid": update.CallSid}).Decode(&call); err != nil {
+		return nil, fmt.Errorf("call %s not found: %w", update.CallSid, err)
+	}
+
+	retention := s.retentionDays
+	var campaign models.Campaign
+	if err := s.db.Collection("campaigns").FindOne(ctx, bson.M{"_id": call.CampaignID}).Decode(&campaign); err != nil {
+		log.Printf("Failed to load campaign %s for recording retention: %v", call.CampaignID.Hex(), err)
+	} else if campaign.Recording != nil && campaign.Recording.RetentionDays > 0 {
+		retention = campaign.Recording.RetentionDays
+	}
+
+	source := RecordCall
+	switch update.RecordingSource {
+	case "DialVerb":
+		source = RecordForward
+	case "RecordVerb":
+		source = RecordedMessage
+	}
+	channels, _ := strconv.Atoi(update.RecordingChannels)
+	duration, _ := strconv.Atoi(update.RecordingDuration)
+	status := update.RecordingStatus
+	if update.ErrorCode != "" && update.ErrorCode != "0" {
+		status = "failed"
+	}
+
+	now := time.Now()
+	set := bson.M{
+		"call_id":         call.ID,
+		"campaign_id":     call.CampaignID,
+		"twilio_call_sid": update.CallSid,
+		"source":          source,
+		"status":          status,
+		"channels":        channels,
+		"duration":        duration,
+		"updated_at":      now,
+	}
+	if update.RecordingURL != "" {
+		set["media_url"] = update.RecordingURL
+	}
+	setOnInsert := bson.M{"created_at": now}
+	changes := bson.M{"$set": set, "$setOnInsert": setOnInsert}
+	if status == "absent" || status == "failed" {
+		// There is no audio to delete once retention passes
+		changes["$unset"] = bson.M{"expires_at": ""}
+	} else if retention > 0 {
+		setOnInsert["expires_at"] = now.AddDate(0, 0, retention)
+	}
+
+	var recording models.Recording
+	err := s.db.Collection("recordings").FindOneAndUpdate(
+		ctx,
+		bson.M{"recording_sid": update.RecordingSid},
+		changes,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&recording)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store recording: %w", err)
+	}
+
+	return &recording, nil
+}
+
+// Media streams the audio of a recording from the provider until ctx is
+// cancelled. The caller must close the reader.
+func (s *RecordingService) Media(ctx context.Context, recording *models.Recording) (io.ReadCloser, string, error) {
+	return s.provider.FetchRecording(ctx, recording.RecordingSID)
+}
+
+// Delete removes a recording from the provider and from the database.
+// A recording the provider no longer has counts as deleted there.
+func (s *RecordingService) Delete(ctx context.Context, recording *models.Recording) error {
+	if err := s.provider.DeleteRecording(recording.RecordingSID); err != nil && !errors.Is(err, ErrRecordingNotFound) {
+		return err
+	}
+	if _, err := s.db.Collection("recordings").DeleteOne(ctx, bson.M{"_id": recording.ID}); err != nil {
+		return fmt.Errorf("failed to delete recording: %w", err)
+	}
+
+	s.db.Collection("call_logs").InsertOne(ctx, models.CallLog{
+		CallID:    recording.CallID,
+		Event:     "recording_deleted",
+		Details:   fmt.Sprintf("Recording %s deleted", recording.RecordingSID),
+		CreatedAt: time.Now(),
+	})
+	return nil
+}
+
+// deleteExpired deletes every recording whose retention has passed
+func (s *RecordingService) deleteExpired(ctx context.Context) {
+	findCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	cursor, err := s.db.Collection("recordings").Find(findCtx, bson.M{"expires_at": bson.M{"$lte": time.Now()}})
+	if err != nil {
+		log.Printf("Failed to find expired recordings: %v", err)
+		return
+	}
+	var expired []models.Recording
+	if err := cursor.All(findCtx, &expired); err != nil {
+		log.Printf("Failed to decode expired recordings: %v", err)
+		return
+	}
+
+	for i := range expired {
+		if err := s.Delete(findCtx, &expired[i]); err != nil {
+			log.Printf("Failed to delete expired recording %s: %v", expired[i].RecordingSID, err)
+			continue
+		}
+		log.Printf("Deleted recording %s - retention passed", expired[i].RecordingSID)
+	}
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prabhatkumar/ivrcalling/models"
+	"github.com/prabhatkumar/ivrshared/phone"
+)
+
+// scheduleLookahead bounds the search for the next allowed calling time
+const scheduleLookahead = 8 * 24 * time.Hour
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// callingCodeZones maps international calling codes to the time zones used in
+// that country. Countries spanning several zones list all of them so that a
+// calling window is only considered open when it is open everywhere.
+var callingCodeZones = map[string][]string{
+	"1":   {"America/New_York", "America/Chicago", "America/Denver", "America/Los_Angeles"},
+	"7":   {"Europe/Moscow"},
+	"20":  {"Africa/Cairo"},
+	"27":  {"Africa/Johannesburg"},
+	"30":  {"Europe/Athens"},
+	"31":  {"Europe/Amsterdam"},
+	"32":  {"Europe/Brussels"},
+	"33":  {"Europe/Paris"},
+	"34":  {"Europe/Madrid"},
+	"39":  {"Europe/Rome"},
+	"41":  {"Europe/Zurich"},
+	"43":  {"Europe/Vienna"},
+	"44":  {"Europe/London"},
+	"45":  {"Europe/Copenhagen"},
+	"46":  {"Europe/Stockholm"},
+	"47":  {"Europe/Oslo"},
+	"48":  {"Europe/Warsaw"},
+	"49":  {"Europe/Berlin"},
+	"52":  {"America/Mexico_City"},
+	"54":  {"America/Argentina/Buenos_Aires"},
+	"55":  {"America/Sao_Paulo"},
+	"56":  {"America/Santiago"},
+	"57":  {"America/Bogota"},
+	"60":  {"Asia/Kuala_Lumpur"},
+	"61":  {"Australia/Perth", "Australia/Adelaide", "Australia/Sydney"},
+	"62":  {"Asia/Jakarta"},
+	"63":  {"Asia/Manila"},
+	"64":  {"Pacific/Auckland"},
+	"65":  {"Asia/Singapore"},
+	"66":  {"Asia/Bangkok"},
+	"81":  {"Asia/Tokyo"},
+	"82":  {"Asia/Seoul"},
+	"84":  {"Asia/Ho_Chi_Minh"},
+	"86":  {"Asia/Shanghai"},
+	"90":  {"Europe/Istanbul"},
+	"91":  {"Asia/Kolkata"},
+	"92":  {"Asia/Karachi"},
+	"94":  {"Asia/Colombo"},
+	"234": {"Africa/Lagos"},
+	"254": {"Africa/Nairobi"},
+	"351": {"Europe/Lisbon"},
+	"353": {"Europe/Dublin"},
+	"880": {"Asia/Dhaka"},
+	"966": {"Asia/Riyadh"},
+	"971": {"Asia/Dubai"},
+	"977": {"Asia/Kathmandu"},
+}
+
+// TimezonesForPhone returns the time zones a phone number may be in, derived
+// from its country calling code. fallback (an IANA zone name) is used when the
+// country is unknown; UTC is used when there is no fallback either.
+func TimezonesForPhone(phoneNumber string, fallback string) []*time.Location {
+	if number, err := phone.Parse(phoneNumber, ""); err == nil {
+		if names, ok := callingCodeZones[number.CountryCode]; ok {
+			if zones := loadZones(names); len(zones) > 0 {
+				return zones
+			}
+		}
+	}
+
+	if fallback != "" {
+		if zones := loadZones([]string{fallback}); len(zones) > 0 {
+			return zones
+		}
+	}
+	return []*time.Location{time.UTC}
+}
+
+func loadZones(names []string) []*time.Location {
+	zones := make([]*time.Location, 0, len(names))
+	for _, name := range names {
+		if loc, err := time.LoadLocation(name); err == nil {
+			zones = append(zones, loc)
+		}
+	}
+	return zones
+}
+
+// IsCallAllowed reports whether a call may be placed at t to a recipient in
+// all of the given zones. A nil schedule always allows calls.
+func IsCallAllowed(schedule *models.Schedule, zones []*time.Location, t time.Time) bool {
+	if schedule == nil {
+		return true
+	}
+	if schedule.StartDate != nil && t.Before(*schedule.StartDate) {
+		return false
+	}
+	if schedule.EndDate != nil && !t.Before(*schedule.EndDate) {
+		return false
+	}
+
+	for _, zone := range zones {
+		local := t.In(zone)
+		if !weekdayAllowed(schedule.Weekdays, local.Weekday()) {
+			return false
+		}
+		if !inWindows(schedule.Windows, local) {
+			return false
+		}
+	}
+	return true
+}
+
+// NextAllowedCallTime returns the earliest time at or after now when a call to
+// a recipient in the given zones is allowed. ok is false when there is no such
+// time within the next week, e.g. because the schedule has ended.
+func NextAllowedCallTime(schedule *models.Schedule, zones []*time.Location, now time.Time) (next time.Time, ok bool) {
+	if IsCallAllowed(schedule, zones, now) {
+		return now, true
+	}
+
+	// The allowed period can only begin at the start date or when a window
+	// opens in one of the zones, so those are the only candidates to check
+	candidates := []time.Time{}
+	if schedule.StartDate != nil && schedule.StartDate.After(now) {
+		candidates = append(candidates, *schedule.StartDate)
+	}
+
+	searchFrom := now
+	if schedule.StartDate != nil && schedule.StartDate.After(searchFrom) {
+		searchFrom = *schedule.StartDate
+	}
+
+	windows := schedule.Windows
+	if len(windows) == 0 {
+		windows = []models.TimeWindow{{Start: "00:00", End: "24:00"}}
+	}
+
+	for _, zone := range zones {
+		local := searchFrom.In(zone)
+		day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, zone)
+		for d := 0; d <= int(scheduleLookahead/(24*time.Hour)); d++ {
+			date := day.AddDate(0, 0, d)
+			for _, window := range windows {
+				start, err := parseClock(window.Start)
+				if err != nil {
+					continue
+				}
+				candidate := time.Date(date.Year(), date.Month(), date.Day(),
+					int(start/time.Hour), int(start%time.Hour/time.Minute), 0, 0, zone)
+				if candidate.After(now) {
+					candidates = append(candidates, candidate)
+				}
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+	for _, candidate := range candidates {
+		if IsCallAllowed(schedule, zones, candidate) {
+			return candidate, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ValidateSchedule checks a campaign schedule for invalid values
+func ValidateSchedule(schedule *models.Schedule) error {
+	if schedule == nil {
+		return nil
+	}
+	if schedule.StartDate != nil && schedule.EndDate != nil && !schedule.EndDate.After(*schedule.StartDate) {
+		return fmt.Errorf("Schedule end_date must be after start_date")
+	}
+	for _, day := range schedule.Weekdays {
+		if _, ok := weekdayNames[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("Schedule weekday '%s' is invalid (use mon, tue, wed, thu, fri, sat, sun)", day)
+		}
+	}
+	if err := ValidateTimeWindows(schedule.Windows); err != nil {
+		return fmt.Errorf("Schedule %v", err)
+	}
+	if schedule.DefaultTimezone != "" {
+		if _, err := time.LoadLocation(schedule.DefaultTimezone); err != nil {
+			return fmt.Errorf("Schedule default_timezone '%s' is not a valid IANA time zone", schedule.DefaultTimezone)
+		}
+	}
+	return nil
+}
+
+// ValidateTimeWindows checks that each window is a valid same-day "HH:MM" range
+func ValidateTimeWindows(windows []models.TimeWindow) error {
+	for i, window := range windows {
+		start, err := parseClock(window.Start)
+		if err != nil {
+			return fmt.Errorf("window %d has an invalid start time: %v", i+1, err)
+		}
+		end, err := parseClock(window.End)
+		if err != nil {
+			return fmt.Errorf("window %d has an invalid end time: %v", i+1, err)
+		}
+		if end <= start {
+			return fmt.Errorf("window %d must end after it starts", i+1)
+		}
+	}
+	return nil
+}
+
+func weekdayAllowed(weekdays []string, day time.Weekday) bool {
+	if len(weekdays) == 0 {
+		return true
+	}
+	for _, name := range weekdays {
+		if weekdayNames[strings.ToLower(name)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+func inWindows(windows []models.TimeWindow, local time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+
+	sinceMidnight := time.Duration(local.Hour())*time.Hour +
+		time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second
+	for _, window := range windows {
+		start, errStart := parseClock(window.Start)
+		end, errEnd := parseClock(window.End)
+		if errStart != nil || errEnd != nil {
+			continue
+		}
+		if sinceMidnight >= start && sinceMidnight < end {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock parses "HH:MM" into the offset from midnight; "24:00" is allowed as an end of day
+func parseClock(value string) (time.Duration, error) {
+	var hours, minutes int
+	if _, err := fmt.Sscanf(value, "%d:%d", &hours, &minutes); err != nil {
+		return 0, fmt.Errorf("'%s' is not in HH:MM format", value)
+	}
+	if hours < 0 || minutes < 0 || minutes > 59 || hours > 24 || (hours == 24 && minutes != 0) {
+		return 0, fmt.Errorf("'%s' is not a valid time of day", value)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
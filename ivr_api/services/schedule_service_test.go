@@ -0,0 +1,172 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata" // zones must load on hosts without a zoneinfo database
+
+	"github.com/prabhatkumar/ivrcalling/models"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q): %v", name, err)
+	}
+	return loc
+}
+
+func zoneNames(zones []*time.Location) string {
+	names := make([]string, 0, len(zones))
+	for _, zone := range zones {
+		names = append(names, zone.String())
+	}
+	return strings.Join(names, ",")
+}
+
+func TestTimezonesForPhone(t *testing.T) {
+	tests := []struct {
+		name     string
+		phone    string
+		fallback string
+		want     string
+	}{
+		{"single zone country", "+919876543210", "", "Asia/Kolkata"},
+		{"country spanning zones", "+14155550100", "", "America/New_York,America/Chicago,America/Denver,America/Los_Angeles"},
+		{"three digit calling code", "+9779812345678", "", "Asia/Kathmandu"},
+		{"unknown country uses fallback", "+3612345678", "Europe/Budapest", "Europe/Budapest"},
+		{"invalid number uses fallback", "not a number", "Europe/Paris", "Europe/Paris"},
+		{"invalid fallback uses UTC", "+3612345678", "Mars/Olympus", "UTC"},
+		{"no fallback uses UTC", "+3612345678", "", "UTC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := zoneNames(TimezonesForPhone(tt.phone, tt.fallback)); got != tt.want {
+				t.Errorf("TimezonesForPhone(%q, %q) = %s, want %s", tt.phone, tt.fallback, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsCallAllowed(t *testing.T) {
+	kolkata := mustLoadLocation(t, "Asia/Kolkata")
+	newYork := mustLoadLocation(t, "America/New_York")
+	losAngeles := mustLoadLocation(t, "America/Los_Angeles")
+
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	businessHours := &models.Schedule{
+		Weekdays: []string{"mon", "tue", "wed", "thu", "FRI"},
+		Windows:  []models.TimeWindow{{Start: "09:00", End: "12:00"}, {Start: "14:00", End: "18:00"}},
+	}
+
+	tests := []struct {
+		name     string
+		schedule *models.Schedule
+		zones    []*time.Location
+		at       time.Time
+		want     bool
+	}{
+		{"nil schedule", nil, []*time.Location{kolkata}, time.Date(2024, 3, 2, 3, 0, 0, 0, kolkata), true},
+		{"empty schedule", &models.Schedule{}, []*time.Location{kolkata}, time.Date(2024, 3, 2, 3, 0, 0, 0, kolkata), true},
+		{"inside first window", businessHours, []*time.Location{kolkata}, time.Date(2024, 3, 4, 9, 0, 0, 0, kolkata), true},
+		{"window end is exclusive", businessHours, []*time.Location{kolkata}, time.Date(2024, 3, 4, 12, 0, 0, 0, kolkata), false},
+		{"between windows", businessHours, []*time.Location{kolkata}, time.Date(2024, 3, 4, 13, 0, 0, 0, kolkata), false},
+		{"inside second window", businessHours, []*time.Location{kolkata}, time.Date(2024, 3, 4, 17, 59, 0, 0, kolkata), true},
+		{"weekday names are case insensitive", businessHours, []*time.Location{kolkata}, time.Date(2024, 3, 8, 10, 0, 0, 0, kolkata), true},
+		{"weekend", businessHours, []*time.Location{kolkata}, time.Date(2024, 3, 9, 10, 0, 0, 0, kolkata), false},
+		{"open in every zone", businessHours, []*time.Location{newYork, losAngeles}, time.Date(2024, 3, 4, 17, 0, 0, 0, newYork), true},
+		{"closed in one zone", businessHours, []*time.Location{newYork, losAngeles}, time.Date(2024, 3, 4, 10, 0, 0, 0, newYork), false},
+		{"before start date", &models.Schedule{StartDate: &start}, []*time.Location{time.UTC}, start.Add(-time.Second), false},
+		{"at start date", &models.Schedule{StartDate: &start}, []*time.Location{time.UTC}, start, true},
+		{"end date is exclusive", &models.Schedule{EndDate: &end}, []*time.Location{time.UTC}, end, false},
+		{"window until end of day", &models.Schedule{Windows: []models.TimeWindow{{Start: "20:00", End: "24:00"}}}, []*time.Location{time.UTC}, time.Date(2024, 3, 4, 23, 59, 59, 0, time.UTC), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsCallAllowed(tt.schedule, tt.zones, tt.at); got != tt.want {
+				t.Errorf("IsCallAllowed at %s = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextAllowedCallTime(t *testing.T) {
+	kolkata := mustLoadLocation(t, "Asia/Kolkata")
+	newYork := mustLoadLocation(t, "America/New_York")
+	losAngeles := mustLoadLocation(t, "America/Los_Angeles")
+
+	start := time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC)
+	ended := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	weekdays := &models.Schedule{
+		Weekdays: []string{"mon", "tue", "wed", "thu", "fri"},
+		Windows:  []models.TimeWindow{{Start: "09:00", End: "18:00"}},
+	}
+
+	tests := []struct {
+		name     string
+		schedule *models.Schedule
+		zones    []*time.Location
+		now      time.Time
+		want     time.Time
+		ok       bool
+	}{
+		{"already allowed", weekdays, []*time.Location{kolkata}, time.Date(2024, 3, 4, 10, 0, 0, 0, kolkata), time.Date(2024, 3, 4, 10, 0, 0, 0, kolkata), true},
+		{"later today", weekdays, []*time.Location{kolkata}, time.Date(2024, 3, 4, 7, 30, 0, 0, kolkata), time.Date(2024, 3, 4, 9, 0, 0, 0, kolkata), true},
+		{"after hours", weekdays, []*time.Location{kolkata}, time.Date(2024, 3, 4, 19, 0, 0, 0, kolkata), time.Date(2024, 3, 5, 9, 0, 0, 0, kolkata), true},
+		{"over the weekend", weekdays, []*time.Location{kolkata}, time.Date(2024, 3, 8, 18, 0, 0, 0, kolkata), time.Date(2024, 3, 11, 9, 0, 0, 0, kolkata), true},
+		{"latest zone opens last", weekdays, []*time.Location{newYork, losAngeles}, time.Date(2024, 3, 4, 8, 0, 0, 0, newYork), time.Date(2024, 3, 4, 9, 0, 0, 0, losAngeles), true},
+		{"start date", &models.Schedule{StartDate: &start}, []*time.Location{time.UTC}, start.Add(-48 * time.Hour), start, true},
+		{"ended", &models.Schedule{EndDate: &ended}, []*time.Location{time.UTC}, ended.Add(time.Hour), time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := NextAllowedCallTime(tt.schedule, tt.zones, tt.now)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("NextAllowedCallTime from %s = %s, %v, want %s, %v", tt.now, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+
+	tests := []struct {
+		name     string
+		schedule *models.Schedule
+		wantErr  string
+	}{
+		{"nil", nil, ""},
+		{"valid", &models.Schedule{StartDate: &start, EndDate: &end, Weekdays: []string{"Mon", "sat"}, Windows: []models.TimeWindow{{Start: "09:00", End: "24:00"}}, DefaultTimezone: "Asia/Kolkata"}, ""},
+		{"end before start", &models.Schedule{StartDate: &end, EndDate: &start}, "end_date must be after start_date"},
+		{"end equals start", &models.Schedule{StartDate: &start, EndDate: &start}, "end_date must be after start_date"},
+		{"unknown weekday", &models.Schedule{Weekdays: []string{"monday"}}, "weekday 'monday' is invalid"},
+		{"bad clock format", &models.Schedule{Windows: []models.TimeWindow{{Start: "9am", End: "17:00"}}}, "window 1 has an invalid start time"},
+		{"minutes out of range", &models.Schedule{Windows: []models.TimeWindow{{Start: "09:00", End: "17:60"}}}, "window 1 has an invalid end time"},
+		{"past end of day", &models.Schedule{Windows: []models.TimeWindow{{Start: "09:00", End: "24:30"}}}, "window 1 has an invalid end time"},
+		{"empty window", &models.Schedule{Windows: []models.TimeWindow{{Start: "09:00", End: "17:00"}, {Start: "18:00", End: "18:00"}}}, "window 2 must end after it starts"},
+		{"invalid timezone", &models.Schedule{DefaultTimezone: "Mars/Olympus"}, "default_timezone 'Mars/Olympus' is not a valid IANA time zone"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSchedule(tt.schedule)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateSchedule returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateSchedule error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
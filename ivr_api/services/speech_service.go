@@ -0,0 +1,99 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/prabhatkumar/ivrcalling/models"
+)
+
+// DefaultSpeechConfidence is the minimum recognition confidence used when a
+// campaign does not set one
+const DefaultSpeechConfidence = 0.5
+
+// SpeechEnabled reports whether callers may say their choice
+func SpeechEnabled(settings *models.SpeechSettings) bool {
+	return settings != nil && settings.Enabled
+}
+
+// MinSpeechConfidence returns the confidence below which a transcript is ignored
+func MinSpeechConfidence(settings *models.SpeechSettings) float64 {
+	if settings == nil || settings.MinConfidence == 0 {
+		return DefaultSpeechConfidence
+	}
+	return settings.MinConfidence
+}
+
+// ValidateSpeechSettings checks the speech input settings of a campaign
+func ValidateSpeechSettings(settings *models.SpeechSettings) error {
+	if settings == nil {
+		return nil
+	}
+	if settings.MinConfidence < 0 || settings.MinConfidence > 1 {
+		return fmt.Errorf("speech_input.min_confidence must be between 0 and 1")
+	}
+	return nil
+}
+
+// SpeechHints returns the keywords of a menu level, passed to Twilio to
+// improve recognition
+func SpeechHints(actions []models.IVRAction) []string {
+	var hints []string
+	for _, action := range actions {
+		for _, keyword := range action.Keywords {
+			if keyword = strings.TrimSpace(keyword); keyword != "" {
+				hints = append(hints, keyword)
+			}
+		}
+	}
+	return hints
+}
+
+// MatchSpeech finds the action a caller asked for. An action matches when the
+// transcript contains one of its keywords as whole words, or is its key
+// ("2"). The longest matching keyword wins; a tie between actions is
+// ambiguous and matches nothing.
+func MatchSpeech(actions []models.IVRAction, transcript string) *models.IVRAction {
+	spoken := " " + NormalizeSpeech(transcript) + " "
+	if strings.TrimSpace(spoken) == "" {
+		return nil
+	}
+
+	var best *models.IVRAction
+	bestLength, ambiguous := 0, false
+	for i := range actions {
+		action := &actions[i]
+		length := 0
+		if strings.TrimSpace(spoken) == strings.TrimSpace(action.ActionInput) {
+			length = len(action.ActionInput)
+		}
+		for _, keyword := range action.Keywords {
+			keyword = NormalizeSpeech(keyword)
+			if keyword != "" && strings.Contains(spoken, " "+keyword+" ") && len(keyword) > length {
+				length = len(keyword)
+			}
+		}
+
+		switch {
+		case length == 0:
+		case length > bestLength:
+			best, bestLength, ambiguous = action, length, false
+		case length == bestLength && best != action:
+			ambiguous = true
+		}
+	}
+
+	if ambiguous {
+		return nil
+	}
+	return best
+}
+
+// NormalizeSpeech lower-cases a transcript or keyword and reduces it to words
+// separated by single spaces
+func NormalizeSpeech(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r)
+	}), " ")
+}
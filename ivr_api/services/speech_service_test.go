@@ -0,0 +1,91 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prabhatkumar/ivrcalling/models"
+)
+
+func TestMatchSpeech(t *testing.T) {
+	actions := []models.IVRAction{
+		{ActionType: "forward", ActionInput: "1", Keywords: []string{"sales", "buy"}},
+		{ActionType: "forward", ActionInput: "2", Keywords: []string{"support", "technical support"}},
+		{ActionType: "information", ActionInput: "3", Keywords: []string{"hours", "Opening Hours"}},
+		{ActionType: "information", ActionInput: "4", Keywords: []string{"billing"}},
+		{ActionType: "information", ActionInput: "5", Keywords: []string{"payment"}},
+		{ActionType: "end_call", ActionInput: "0"},
+	}
+
+	tests := []struct {
+		name       string
+		transcript string
+		want       string
+	}{
+		{"keyword", "Sales please.", "1"},
+		{"keyword inside a sentence", "I'd like to buy something", "1"},
+		{"whole words only", "I want to buyout my contract", ""},
+		{"key spoken", "2", "2"},
+		{"key without keywords", " 0 ", "0"},
+		{"key inside a sentence does not match", "press 2", ""},
+		{"case and punctuation", "OPENING-hours?", "3"},
+		{"longest keyword wins", "technical support for sales", "2"},
+		{"tie is ambiguous", "billing or payment", ""},
+		{"same action twice is not ambiguous", "sales, I want to buy", "1"},
+		{"no match", "hello", ""},
+		{"empty", "  ...  ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MatchSpeech(actions, tt.transcript)
+			switch {
+			case tt.want == "" && got != nil:
+				t.Errorf("MatchSpeech(%q) = action %s, want no match", tt.transcript, got.ActionInput)
+			case tt.want != "" && got == nil:
+				t.Errorf("MatchSpeech(%q) = no match, want action %s", tt.transcript, tt.want)
+			case tt.want != "" && got.ActionInput != tt.want:
+				t.Errorf("MatchSpeech(%q) = action %s, want action %s", tt.transcript, got.ActionInput, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchSpeechReturnsTheListedAction(t *testing.T) {
+	actions := []models.IVRAction{{ActionType: "forward", ActionInput: "1", Keywords: []string{"sales"}}}
+	if got := MatchSpeech(actions, "sales"); got != &actions[0] {
+		t.Errorf("MatchSpeech returned %p, want the action at %p", got, &actions[0])
+	}
+}
+
+func TestNormalizeSpeech(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Hello, World!", "hello world"},
+		{"  technical   support ", "technical support"},
+		{"order #12-34", "order 12 34"},
+		{"Café crème", "café crème"},
+		{"?!", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeSpeech(tt.text); got != tt.want {
+			t.Errorf("NormalizeSpeech(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSpeechHints(t *testing.T) {
+	actions := []models.IVRAction{
+		{ActionInput: "1", Keywords: []string{" sales ", ""}},
+		{ActionInput: "2"},
+		{ActionInput: "3", Keywords: []string{"support", "help desk"}},
+	}
+
+	want := []string{"sales", "support", "help desk"}
+	if got := SpeechHints(actions); !reflect.DeepEqual(got, want) {
+		t.Errorf("SpeechHints = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ReadSpreadsheet returns the rows of a CSV file or of the first worksheet of
+// an XLSX workbook, chosen by the file name's extension. Row i is line i+1 of
+// the file: blank lines, which both formats leave out, are returned as empty
+// rows, and a CSV record spanning several lines is placed at its first line.
+func ReadSpreadsheet(filename string, data []byte) ([][]string, error) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv", ".txt":
+		return readCSV(data)
+	case ".xlsx":
+		return readXLSX(data)
+	default:
+		return nil, fmt.Errorf("unsupported file type '%s' (use .csv or .xlsx)", path.Ext(filename))
+	}
+}
+
+func readCSV(data []byte) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var rows [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV file: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		for len(rows) < line-1 {
+			rows = append(rows, nil)
+		}
+		rows = append(rows, record)
+	}
+}
+
+// XLSX parts used to locate and read the first worksheet
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxRichText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var sb strings.Builder
+	for _, run := range t.Runs {
+		sb.WriteString(run.Text)
+	}
+	return sb.String()
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Ref   string `xml:"r,attr"` // 1-based row number
+		Cells []struct {
+			Ref    string       `xml:"r,attr"`
+			Type   string       `xml:"t,attr"`
+			Value  string       `xml:"v"`
+			Inline xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readXLSX(data []byte) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid XLSX file: %w", err)
+	}
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	var sharedStrings xlsxSharedStrings
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeZipXML(f, &sharedStrings); err != nil {
+			return nil, fmt.Errorf("invalid XLSX shared strings: %w", err)
+		}
+	}
+
+	f, ok := files[firstSheetPath(files)]
+	if !ok {
+		return nil, fmt.Errorf("invalid XLSX file: no worksheet found")
+	}
+	var sheet xlsxWorksheet
+	if err := decodeZipXML(f, &sheet); err != nil {
+		return nil, fmt.Errorf("invalid XLSX worksheet: %w", err)
+	}
+
+	rows := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		// Empty rows are omitted from the file too, so pad up to the row's number
+		if row.Ref != "" {
+			number, err := strconv.Atoi(row.Ref)
+			if err != nil || number <= len(rows) || number > maxXLSXRows {
+				return nil, fmt.Errorf("invalid XLSX row number %q", row.Ref)
+			}
+			for len(rows) < number-1 {
+				rows = append(rows, nil)
+			}
+		}
+
+		var values []string
+		for i, cell := range row.Cells {
+			// Empty cells are omitted from the file, so place each cell by its reference
+			col := i
+			if cell.Ref != "" {
+				col = columnIndex(cell.Ref)
+			}
+			if col < 0 || col >= maxXLSXColumns {
+				return nil, fmt.Errorf("invalid XLSX cell reference %q", cell.Ref)
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				if idx, err := strconv.Atoi(cell.Value); err == nil && idx >= 0 && idx < len(sharedStrings.Items) {
+					values[col] = sharedStrings.Items[idx].String()
+				}
+			case "inlineStr":
+				values[col] = cell.Inline.String()
+			case "", "n":
+				values[col] = formatXLSXNumber(cell.Value)
+			default:
+				values[col] = cell.Value
+			}
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// firstSheetPath resolves the first sheet of the workbook through its relationships
+func firstSheetPath(files map[string]*zip.File) string {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	var workbook xlsxWorkbook
+	var rels xlsxRelationships
+	wb, okWB := files["xl/workbook.xml"]
+	rel, okRel := files["xl/_rels/workbook.xml.rels"]
+	if !okWB || !okRel || decodeZipXML(wb, &workbook) != nil || decodeZipXML(rel, &rels) != nil || len(workbook.Sheets) == 0 {
+		return fallback
+	}
+
+	for _, r := range rels.Relationships {
+		if r.ID == workbook.Sheets[0].RelID {
+			if strings.HasPrefix(r.Target, "/") {
+				return strings.TrimPrefix(r.Target, "/")
+			}
+			return path.Join("xl", r.Target)
+		}
+	}
+	return fallback
+}
+
+func decodeZipXML(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(io.LimitReader(rc, 100<<20)).Decode(v)
+}
+
+// maxXLSXColumns is the number of columns Excel supports, A to XFD
+const maxXLSXColumns = 16384
+
+// maxXLSXRows is the number of rows Excel supports
+const maxXLSXRows = 1048576
+
+// columnIndex converts the letters of a cell reference such as "C12" to a zero-based
+// column. It returns -1 without letters and maxXLSXColumns past the last column.
+func columnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		if col > maxXLSXColumns {
+			return maxXLSXColumns
+		}
+	}
+	return col - 1
+}
+
+// formatXLSXNumber prints whole numbers without exponent or decimals, since
+// phone numbers typed into Excel are usually stored as numbers
+func formatXLSXNumber(value string) string {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f != float64(int64(f)) {
+		return value
+	}
+	return strconv.FormatInt(int64(f), 10)
+}
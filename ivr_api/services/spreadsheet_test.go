@@ -0,0 +1,258 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// buildXLSX zips the given parts into a workbook
+func buildXLSX(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatalf("failed to add %s: %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("failed to close workbook: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func worksheet(rows string) string {
+	return `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + rows + `</sheetData></worksheet>`
+}
+
+func TestReadSpreadsheetCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		data     string
+		want     [][]string
+		wantErr  string
+	}{
+		{
+			name:     "rows of different lengths",
+			filename: "contacts.csv",
+			data:     "phone_number,name,amount\n+14155550100, Asha\n+14155550101,\"Lee, Sam\",10\n",
+			want:     [][]string{{"phone_number", "name", "amount"}, {"+14155550100", "Asha"}, {"+14155550101", "Lee, Sam", "10"}},
+		},
+		{
+			name:     "txt and upper case extension",
+			filename: "CONTACTS.TXT",
+			data:     "+14155550100",
+			want:     [][]string{{"+14155550100"}},
+		},
+		{
+			name:     "blank lines keep their place",
+			filename: "contacts.csv",
+			data:     "phone_number\n\n+14155550100\n\n\n+14155550101\n",
+			want:     [][]string{{"phone_number"}, nil, {"+14155550100"}, nil, nil, {"+14155550101"}},
+		},
+		{
+			name:     "a record spanning lines is placed at its first line",
+			filename: "contacts.csv",
+			data:     "phone_number,note\n+14155550100,\"two\nlines\"\n+14155550101,one\n",
+			want:     [][]string{{"phone_number", "note"}, {"+14155550100", "two\nlines"}, nil, {"+14155550101", "one"}},
+		},
+		{
+			name:     "bare quote",
+			filename: "contacts.csv",
+			data:     "phone_number,name\n+14155550100,As\"ha\n",
+			wantErr:  "invalid CSV file",
+		},
+		{
+			name:     "unsupported extension",
+			filename: "contacts.xls",
+			data:     "",
+			wantErr:  "unsupported file type '.xls'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadSpreadsheet(tt.filename, []byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ReadSpreadsheet error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadSpreadsheet returned error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadSpreadsheet = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadSpreadsheetXLSX(t *testing.T) {
+	sharedStrings := `<sst><si><t>phone_number</t></si><si><t>name</t></si><si><r><t>As</t></r><r><t>ha</t></r></si></sst>`
+
+	tests := []struct {
+		name    string
+		parts   map[string]string
+		want    [][]string
+		wantErr string
+	}{
+		{
+			name: "shared, inline and numeric cells",
+			parts: map[string]string{
+				"xl/sharedStrings.xml": sharedStrings,
+				"xl/worksheets/sheet1.xml": worksheet(
+					`<row><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>` +
+						`<row><c r="A2"><v>14155550100</v></c><c r="B2" t="s"><v>2</v></c></row>` +
+						`<row><c r="A3" t="n"><v>1.4155550101E10</v></c><c r="B3" t="inlineStr"><is><t>Lee</t></is></c></row>` +
+						`<row><c r="A4" t="str"><v>+14155550102</v></c><c r="B4"><v>12.5</v></c></row>`),
+			},
+			want: [][]string{
+				{"phone_number", "name"},
+				{"14155550100", "Asha"},
+				{"14155550101", "Lee"},
+				{"+14155550102", "12.5"},
+			},
+		},
+		{
+			name: "empty cells are placed by reference",
+			parts: map[string]string{
+				"xl/worksheets/sheet1.xml": worksheet(`<row><c r="C1" t="inlineStr"><is><t>third</t></is></c><c r="AA1"><v>27</v></c></row>`),
+			},
+			want: [][]string{append(append(make([]string, 2), "third", ""), append(make([]string, 22), "27")...)},
+		},
+		{
+			name: "omitted rows are padded by row number",
+			parts: map[string]string{
+				"xl/worksheets/sheet1.xml": worksheet(
+					`<row r="2"><c r="A2" t="inlineStr"><is><t>phone_number</t></is></c></row>` +
+						`<row r="5"><c r="A5"><v>14155550100</v></c></row>` +
+						`<row><c r="A6"><v>14155550101</v></c></row>`),
+			},
+			want: [][]string{nil, {"phone_number"}, nil, nil, {"14155550100"}, {"14155550101"}},
+		},
+		{
+			name: "row numbers out of order",
+			parts: map[string]string{
+				"xl/worksheets/sheet1.xml": worksheet(`<row r="3"><c r="A3"><v>1</v></c></row><row r="2"><c r="A2"><v>2</v></c></row>`),
+			},
+			wantErr: `invalid XLSX row number "2"`,
+		},
+		{
+			name: "row number past the last row",
+			parts: map[string]string{
+				"xl/worksheets/sheet1.xml": worksheet(`<row r="1048577"><c r="A1048577"><v>1</v></c></row>`),
+			},
+			wantErr: `invalid XLSX row number "1048577"`,
+		},
+		{
+			name: "cells without references",
+			parts: map[string]string{
+				"xl/worksheets/sheet1.xml": worksheet(`<row><c t="inlineStr"><is><t>a</t></is></c><c t="inlineStr"><is><t>b</t></is></c></row>`),
+			},
+			want: [][]string{{"a", "b"}},
+		},
+		{
+			name: "first sheet through the workbook relationships",
+			parts: map[string]string{
+				"xl/workbook.xml": `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` +
+					`<sheet name="Contacts" r:id="rId2"/><sheet name="Other" r:id="rId1"/></sheets></workbook>`,
+				"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/>` +
+					`<Relationship Id="rId2" Target="/xl/worksheets/contacts.xml"/></Relationships>`,
+				"xl/worksheets/sheet1.xml":   worksheet(`<row><c r="A1" t="inlineStr"><is><t>other</t></is></c></row>`),
+				"xl/worksheets/contacts.xml": worksheet(`<row><c r="A1" t="inlineStr"><is><t>contacts</t></is></c></row>`),
+			},
+			want: [][]string{{"contacts"}},
+		},
+		{
+			name: "shared string index out of range",
+			parts: map[string]string{
+				"xl/sharedStrings.xml":     sharedStrings,
+				"xl/worksheets/sheet1.xml": worksheet(`<row><c r="A1" t="s"><v>7</v></c></row>`),
+			},
+			want: [][]string{{""}},
+		},
+		{
+			name:    "no worksheet",
+			parts:   map[string]string{"xl/workbook.xml": `<workbook/>`},
+			wantErr: "no worksheet found",
+		},
+		{
+			name: "reference without column letters",
+			parts: map[string]string{
+				"xl/worksheets/sheet1.xml": worksheet(`<row><c r="12"><v>1</v></c></row>`),
+			},
+			wantErr: `invalid XLSX cell reference "12"`,
+		},
+		{
+			name: "reference past the last column",
+			parts: map[string]string{
+				"xl/worksheets/sheet1.xml": worksheet(`<row><c r="XFDXFDXFD1"><v>1</v></c></row>`),
+			},
+			wantErr: `invalid XLSX cell reference "XFDXFDXFD1"`,
+		},
+		{
+			name: "malformed worksheet",
+			parts: map[string]string{
+				"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row>`,
+			},
+			wantErr: "invalid XLSX worksheet",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadSpreadsheet("contacts.xlsx", buildXLSX(t, tt.parts))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ReadSpreadsheet error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadSpreadsheet returned error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadSpreadsheet = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadSpreadsheetNotAZip(t *testing.T) {
+	if _, err := ReadSpreadsheet("contacts.xlsx", []byte("phone_number\n+14155550100")); err == nil || !strings.Contains(err.Error(), "invalid XLSX file") {
+		t.Errorf("ReadSpreadsheet error = %v, want an invalid XLSX file error", err)
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want int
+	}{
+		{"A1", 0},
+		{"C12", 2},
+		{"Z3", 25},
+		{"AA1", 26},
+		{"XFD1048576", maxXLSXColumns - 1},
+		{"XFE1", maxXLSXColumns},
+		{"XFDXFDXFD1", maxXLSXColumns},
+		{"12", -1},
+		{"", -1},
+	}
+
+	for _, tt := range tests {
+		if got := columnIndex(tt.ref); got != tt.want {
+			t.Errorf("columnIndex(%q) = %d, want %d", tt.ref, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+
+	"github.com/prabhatkumar/ivrcalling/config"
+	"github.com/prabhatkumar/ivrcalling/models"
+)
+
+// ProviderCall is the carrier-neutral view of a call returned by a TelephonyProvider
+type ProviderCall struct {
+	SID      string
+	Status   string
+	To       string
+	From     string
+	Duration int // in seconds
+}
+
+// CallOptions are the campaign settings applied to an outbound call
+type CallOptions struct {
+	MachineDetection *models.MachineDetection
+	Recording        *models.RecordingSettings
+}
+
+// ErrRecordingNotFound is returned when the carrier has no recording with the given SID
+var ErrRecordingNotFound = errors.New("recording not found")
+
+// TelephonyProvider places and controls calls on a carrier
+type TelephonyProvider interface {
+	// MakeCall initiates an outbound IVR call for the given call record
+	MakeCall(toNumber string, language string, callID string, options CallOptions) (*ProviderCall, error)
+	// GetCallDetails retrieves the current state of a call from the carrier
+	GetCallDetails(callSid string) (*ProviderCall, error)
+	// HangupCall terminates a call that is queued, ringing or in progress
+	HangupCall(callSid string) error
+	// RedirectCall moves a call in progress to the TwiML served at a webhook
+	// path such as /api/webhook/voicemail
+	RedirectCall(callSid string, path string) error
+	// FetchRecording streams the audio of a recording and returns its content
+	// type. The download stops when ctx is cancelled.
+	FetchRecording(ctx context.Context, recordingSid string) (io.ReadCloser, string, error)
+	// DeleteRecording removes a recording from the carrier, returning
+	// ErrRecordingNotFound if it is already gone
+	DeleteRecording(recordingSid string) error
+}
+
+// NewTelephonyProvider returns the provider selected by TELEPHONY_PROVIDER
+func NewTelephonyProvider(cfg *config.Config) TelephonyProvider {
+	switch cfg.TelephonyProvider {
+	case "fake":
+		log.Printf("Using in-memory fake telephony provider - no real calls will be placed")
+		return NewFakeProvider(cfg.TwilioPhoneNumber)
+	case "", "twilio":
+		return NewTwilioService(cfg)
+	default:
+		log.Printf("Unknown telephony provider '%s', falling back to twilio", cfg.TelephonyProvider)
+		return NewTwilioService(cfg)
+	}
+}
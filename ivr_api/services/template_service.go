@@ -0,0 +1,254 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/prabhatkumar/ivrcalling/models"
+	"github.com/prabhatkumar/ivrcalling/ssml"
+)
+
+// Built-in template variables filled from the call itself
+const (
+	VariableName        = "name"
+	VariablePhoneNumber = "phone_number"
+)
+
+var fieldNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// NormalizeFieldName turns a column header such as "Due Date" into a
+// template variable name such as "due_date"
+func NormalizeFieldName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_')
+	}), "_")
+	return name
+}
+
+// ValidateFields checks that custom field names can be used as template variables
+func ValidateFields(fields map[string]string) error {
+	for name := range fields {
+		if !fieldNamePattern.MatchString(name) {
+			return fmt.Errorf("field name '%s' must contain only lowercase letters, digits and underscores", name)
+		}
+	}
+	return nil
+}
+
+// TemplateVariables parses a message template such as "You owe {{.amount}}"
+// and returns the variables it uses
+func TemplateVariables(text string) ([]string, error) {
+	tmpl, err := template.New("message").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	seen := map[string]bool{}
+	if tmpl.Tree != nil {
+		collectFields(tmpl.Tree.Root, seen)
+	}
+
+	variables := make([]string, 0, len(seen))
+	for name := range seen {
+		variables = append(variables, name)
+	}
+	sort.Strings(variables)
+	return variables, nil
+}
+
+// ValidateMessage checks the template syntax and the SSML of a campaign text
+func ValidateMessage(text string) error {
+	if _, err := TemplateVariables(text); err != nil {
+		return err
+	}
+	return ssml.Validate(text)
+}
+
+// collectFields walks a template parse tree and records the top-level fields it reads
+func collectFields(node parse.Node, seen map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectFields(child, seen)
+		}
+	case *parse.ActionNode:
+		collectFields(n.Pipe, seen)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				collectFields(arg, seen)
+			}
+		}
+	case *parse.FieldNode:
+		seen[n.Ident[0]] = true
+	case *parse.IfNode:
+		collectBranch(&n.BranchNode, seen)
+	case *parse.RangeNode:
+		collectBranch(&n.BranchNode, seen)
+	case *parse.WithNode:
+		collectBranch(&n.BranchNode, seen)
+	case *parse.TemplateNode:
+		collectFields(n.Pipe, seen)
+	}
+}
+
+func collectBranch(n *parse.BranchNode, seen map[string]bool) {
+	collectFields(n.Pipe, seen)
+	collectFields(n.List, seen)
+	collectFields(n.ElseList, seen)
+}
+
+// CampaignVariables returns the variables used by the intro text, menu
+// prompts, action messages, voicemail and consent messages of a campaign, reporting the first template
+// with a syntax error. Values gathered by "collect" actions are filled in
+// during the call and are not required from contacts; sensitive ones are
+// never stored and cannot be used.
+func CampaignVariables(campaign *models.Campaign) ([]string, error) {
+	seen := map[string]bool{}
+	add := func(label, text string) error {
+		variables, err := TemplateVariables(text)
+		if err != nil {
+			return fmt.Errorf("%s: %w", label, err)
+		}
+		for _, name := range variables {
+			seen[name] = true
+		}
+		return nil
+	}
+
+	if err := add("Intro text", campaign.IntroText); err != nil {
+		return nil, err
+	}
+	if campaign.MachineDetection != nil {
+		if err := add("Voicemail message", campaign.MachineDetection.VoicemailMessage); err != nil {
+			return nil, err
+		}
+	}
+	if campaign.Recording != nil {
+		if err := add("Consent message", campaign.Recording.ConsentMessage); err != nil {
+			return nil, err
+		}
+	}
+
+	var walk func(actions []models.IVRAction, prefix string) error
+	walk = func(actions []models.IVRAction, prefix string) error {
+		for i, action := range actions {
+			label := fmt.Sprintf("%d", i+1)
+			if prefix != "" {
+				label = prefix + "." + label
+			}
+			if err := add("Action "+label+" message", action.Message); err != nil {
+				return err
+			}
+			if action.Collect != nil {
+				if err := add("Action "+label+" invalid message", action.Collect.InvalidMessage); err != nil {
+					return err
+				}
+				if err := add("Action "+label+" success message", action.Collect.SuccessMessage); err != nil {
+					return err
+				}
+			}
+			if action.Record != nil {
+				if err := add("Action "+label+" success message", action.Record.SuccessMessage); err != nil {
+					return err
+				}
+			}
+			if action.Forward != nil {
+				if err := add("Action "+label+" whisper", action.Forward.Whisper); err != nil {
+					return err
+				}
+				if err := add("Action "+label+" unavailable message", action.Forward.UnavailableMessage); err != nil {
+					return err
+				}
+				if err := add("Action "+label+" after-hours message", action.Forward.AfterHoursMessage); err != nil {
+					return err
+				}
+			}
+			if action.SubMenu != nil {
+				if err := add("Action "+label+" sub-menu prompt", action.SubMenu.Prompt); err != nil {
+					return err
+				}
+				if err := walk(action.SubMenu.Actions, label); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(campaign.Actions, ""); err != nil {
+		return nil, err
+	}
+
+	collected := CollectSettingsByName(campaign.Actions)
+	variables := make([]string, 0, len(seen))
+	for name := range seen {
+		if settings, ok := collected[name]; ok {
+			if !StoresCollectedValue(settings) {
+				return nil, fmt.Errorf("{{.%s}} is a sensitive collected value and cannot be used in messages", name)
+			}
+			continue
+		}
+		variables = append(variables, name)
+	}
+	sort.Strings(variables)
+	return variables, nil
+}
+
+// MissingVariables returns the variables a contact has no value for.
+// The built-in name and phone_number variables are always available.
+func MissingVariables(variables []string, fields map[string]string) []string {
+	var missing []string
+	for _, name := range variables {
+		if name == VariableName || name == VariablePhoneNumber {
+			continue
+		}
+		if _, ok := fields[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// CallVariables returns the template variables of a call: its custom fields,
+// the values collected so far and the built-in name and phone_number
+func CallVariables(call *models.Call) map[string]string {
+	variables := make(map[string]string, len(call.Fields)+len(call.Collected)+2)
+	for name, value := range call.Fields {
+		variables[name] = value
+	}
+	for name, value := range call.Collected {
+		variables[name] = value
+	}
+	variables[VariableName] = call.CustomerName
+	variables[VariablePhoneNumber] = call.PhoneNumber
+	return variables
+}
+
+// RenderTemplate fills in the variables of a message template
+func RenderTemplate(text string, variables map[string]string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New("message").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, variables); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
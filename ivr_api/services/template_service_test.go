@@ -0,0 +1,262 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prabhatkumar/ivrcalling/models"
+)
+
+func TestNormalizeFieldName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"amount", "amount"},
+		{"Due Date", "due_date"},
+		{"  Account #  ", "account"},
+		{"order-number", "order_number"},
+		{"First  Name", "first_name"},
+		{"already_snake_2", "already_snake_2"},
+		{"Émile", "mile"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeFieldName(tt.name); got != tt.want {
+			t.Errorf("NormalizeFieldName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  map[string]string
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"valid", map[string]string{"amount": "10", "due_date": "May 1", "_private": "x", "line2": "y"}, false},
+		{"uppercase", map[string]string{"Amount": "10"}, true},
+		{"space", map[string]string{"due date": "May 1"}, true},
+		{"leading digit", map[string]string{"2nd": "x"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateFields(tt.fields); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateFields(%v) error = %v, wantErr %v", tt.fields, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTemplateVariables(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    []string
+		wantErr bool
+	}{
+		{"plain text", "Hello there", []string{}, false},
+		{"single", "You owe {{.amount}}", []string{"amount"}, false},
+		{"sorted and unique", "{{.name}}, {{.amount}} is due {{.due_date}}. Pay {{.amount}} now", []string{"amount", "due_date", "name"}, false},
+		{"if and else", "{{if .vip}}Dear {{.name}}{{else}}Hello{{end}}", []string{"name", "vip"}, false},
+		{"with", "{{with .plan}}Plan {{.}}{{end}}", []string{"plan"}, false},
+		{"nested field", "{{.address.city}}", []string{"address"}, false},
+		{"unclosed action", "You owe {{.amount", nil, true},
+		{"unknown function", "{{upper .name}}", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TemplateVariables(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TemplateVariables(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TemplateVariables(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateMessage(t *testing.T) {
+	tests := []struct {
+		text    string
+		wantErr string
+	}{
+		{"Hello {{.name}}", ""},
+		{`Hello <break time="1s"/> {{.name}}`, ""},
+		{"Hello {{.name", "invalid template"},
+		{`Hello <blink>{{.name}}</blink>`, "<blink> is not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			err := ValidateMessage(tt.text)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateMessage returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateMessage error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	variables := map[string]string{"name": "Asha", "amount": "$10 & more"}
+
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{"plain text is returned as is", "Hello {there}", "Hello {there}", false},
+		{"variables", "Hi {{.name}}, you owe {{.amount}}", "Hi Asha, you owe $10 & more", false},
+		{"conditional", "{{if .name}}Hi {{.name}}{{else}}Hello{{end}}", "Hi Asha", false},
+		{"missing variable", "Due {{.due_date}}", "", true},
+		{"syntax error", "Hi {{.name", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderTemplate(tt.text, variables)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RenderTemplate(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("RenderTemplate(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCampaignVariables(t *testing.T) {
+	collectPin := models.IVRAction{
+		ActionType:  "collect",
+		ActionInput: "3",
+		Message:     "Enter your PIN",
+		Collect:     &models.CollectSettings{Name: "pin", MaxLength: 4, Sensitive: true},
+	}
+	collectOrder := models.IVRAction{
+		ActionType:  "collect",
+		ActionInput: "2",
+		Message:     "Enter your order number, {{.name}}",
+		Collect:     &models.CollectSettings{Name: "order_number", MaxLength: 6, SuccessMessage: "Order {{.order_number}} for {{.account}}"},
+	}
+
+	tests := []struct {
+		name     string
+		campaign models.Campaign
+		want     []string
+		wantErr  string
+	}{
+		{
+			name:     "no templates",
+			campaign: models.Campaign{IntroText: "Hello"},
+			want:     []string{},
+		},
+		{
+			name: "every text of the campaign",
+			campaign: models.Campaign{
+				IntroText:        "Hello {{.name}}",
+				MachineDetection: &models.MachineDetection{VoicemailMessage: "Call us about {{.amount}}"},
+				Recording:        &models.RecordingSettings{ConsentMessage: "{{.company}} records this call"},
+				Actions: []models.IVRAction{
+					{ActionType: "information", ActionInput: "1", Message: "Your balance is {{.balance}}"},
+					{ActionType: "menu", ActionInput: "9", SubMenu: &models.MenuNode{
+						Prompt: "Options for {{.plan}}",
+						Actions: []models.IVRAction{
+							{ActionType: "forward", ActionInput: "1", ForwardPhone: "+14155550100", Forward: &models.ForwardSettings{Whisper: "Customer {{.account}}"}},
+						},
+					}},
+				},
+			},
+			want: []string{"account", "amount", "balance", "company", "name", "plan"},
+		},
+		{
+			name:     "collected values are not required",
+			campaign: models.Campaign{IntroText: "Hi", Actions: []models.IVRAction{collectOrder}},
+			want:     []string{"account", "name"},
+		},
+		{
+			name: "sensitive collected values cannot be used",
+			campaign: models.Campaign{IntroText: "Hi", Actions: []models.IVRAction{
+				collectPin,
+				{ActionType: "information", ActionInput: "1", Message: "Your PIN is {{.pin}}"},
+			}},
+			wantErr: "{{.pin}} is a sensitive collected value",
+		},
+		{
+			name:     "syntax error names the text",
+			campaign: models.Campaign{IntroText: "Hi", Actions: []models.IVRAction{{ActionType: "information", ActionInput: "1", Message: "{{.name"}}},
+			wantErr:  "Action 1 message",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CampaignVariables(&tt.campaign)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("CampaignVariables error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CampaignVariables returned error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CampaignVariables = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMissingVariables(t *testing.T) {
+	variables := []string{"amount", "due_date", "name", "phone_number"}
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   []string
+	}{
+		{"all present", map[string]string{"amount": "10", "due_date": "May 1"}, nil},
+		{"built-ins are never missing", map[string]string{"amount": "10"}, []string{"due_date"}},
+		{"empty values count as present", map[string]string{"amount": "", "due_date": ""}, nil},
+		{"no fields", nil, []string{"amount", "due_date"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MissingVariables(variables, tt.fields); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MissingVariables = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallVariables(t *testing.T) {
+	call := &models.Call{
+		CustomerName: "Asha",
+		PhoneNumber:  "+919876543210",
+		Fields:       map[string]string{"amount": "10", "name": "ignored"},
+		Collected:    map[string]string{"order_number": "123456", "amount": "20"},
+	}
+
+	want := map[string]string{
+		"amount":       "20",
+		"order_number": "123456",
+		"name":         "Asha",
+		"phone_number": "+919876543210",
+	}
+	if got := CallVariables(call); !reflect.DeepEqual(got, want) {
+		t.Errorf("CallVariables = %v, want %v", got, want)
+	}
+}
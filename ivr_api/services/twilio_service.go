@@ -1,16 +1,26 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/prabhatkumar/ivrcalling/config"
 	"github.com/twilio/twilio-go"
+	"github.com/twilio/twilio-go/client"
 	twilioApi "github.com/twilio/twilio-go/rest/api/v2010"
 )
 
 type TwilioService struct {
 	client      *twilio.RestClient
+	httpClient  *http.Client
+	accountSID  string
+	authToken   string
 	phoneNumber string
 	webhookURL  string
 }
@@ -23,13 +33,16 @@ func NewTwilioService(cfg *config.Config) *TwilioService {
 
 	return &TwilioService{
 		client:      client,
+		httpClient:  &http.Client{Timeout: 5 * time.Minute},
+		accountSID:  cfg.TwilioAccountSID,
+		authToken:   cfg.TwilioAuthToken,
 		phoneNumber: cfg.TwilioPhoneNumber,
 		webhookURL:  cfg.WebhookBaseURL,
 	}
 }
 
 // MakeCall initiates an outbound IVR call
-func (s *TwilioService) MakeCall(toNumber string, language string, callID string) (*twilioApi.ApiV2010Call, error) {
+func (s *TwilioService) MakeCall(toNumber string, language string, callID string, options CallOptions) (*ProviderCall, error) {
 	// Construct webhook URL with call ID and language
 	statusCallbackURL := fmt.Sprintf("%s/api/webhook/status", s.webhookURL)
 	voiceURL := fmt.Sprintf("%s/api/webhook/voice?call_id=%s&language=%s", s.webhookURL, callID, language)
@@ -49,6 +62,29 @@ func (s *TwilioService) MakeCall(toNumber string, language string, callID string
 	params.SetStatusCallbackMethod("POST")
 	params.SetStatusCallbackEvent([]string{"initiated", "ringing", "answered", "completed"})
 
+	if settings := options.MachineDetection; MachineDetectionEnabled(settings) {
+		params.SetMachineDetection(TwilioMachineDetectionMode(settings))
+		if settings.TimeoutSeconds > 0 {
+			params.SetMachineDetectionTimeout(settings.TimeoutSeconds)
+		}
+		if settings.Async {
+			// The IVR starts right away; the result is posted to the AMD webhook
+			params.SetAsyncAmd("true")
+			params.SetAsyncAmdStatusCallback(fmt.Sprintf("%s/api/webhook/amd", s.webhookURL))
+			params.SetAsyncAmdStatusCallbackMethod("POST")
+		}
+		log.Printf("Machine detection: %s (async: %v)", TwilioMachineDetectionMode(settings), settings.Async)
+	}
+
+	if settings := options.Recording; RecordsWholeCall(settings) {
+		params.SetRecord(true)
+		params.SetRecordingChannels(RecordingChannels(settings))
+		params.SetRecordingStatusCallback(s.webhookURL + recordingCallbackPath)
+		params.SetRecordingStatusCallbackMethod("POST")
+		params.SetRecordingStatusCallbackEvent([]string{"in-progress", "completed", "absent"})
+		log.Printf("Recording: whole call (%s)", RecordingChannels(settings))
+	}
+
 	call, err := s.client.Api.CreateCall(params)
 	if err != nil {
 		log.Printf("✗ Twilio call creation failed: %v", err)
@@ -56,15 +92,102 @@ func (s *TwilioService) MakeCall(toNumber string, language string, callID string
 	}
 
 	log.Printf("✓ Twilio call created - SID: %s", *call.Sid)
-	return call, nil
+	return toProviderCall(call), nil
 }
 
 // GetCallDetails retrieves call information from Twilio
-func (s *TwilioService) GetCallDetails(callSid string) (*twilioApi.ApiV2010Call, error) {
+func (s *TwilioService) GetCallDetails(callSid string) (*ProviderCall, error) {
 	call, err := s.client.Api.FetchCall(callSid, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch call: %w", err)
 	}
 
-	return call, nil
+	return toProviderCall(call), nil
+}
+
+// HangupCall ends a Twilio call by moving it to the completed state
+func (s *TwilioService) HangupCall(callSid string) error {
+	params := &twilioApi.UpdateCallParams{}
+	params.SetStatus("completed")
+
+	if _, err := s.client.Api.UpdateCall(callSid, params); err != nil {
+		return fmt.Errorf("failed to hang up call: %w", err)
+	}
+
+	log.Printf("✓ Twilio call hung up - SID: %s", callSid)
+	return nil
+}
+
+// RedirectCall points a Twilio call in progress at another webhook
+func (s *TwilioService) RedirectCall(callSid string, path string) error {
+	params := &twilioApi.UpdateCallParams{}
+	params.SetUrl(s.webhookURL + path)
+	params.SetMethod("POST")
+
+	if _, err := s.client.Api.UpdateCall(callSid, params); err != nil {
+		return fmt.Errorf("failed to redirect call: %w", err)
+	}
+
+	log.Printf("✓ Twilio call redirected - SID: %s, Path: %s", callSid, path)
+	return nil
+}
+
+// FetchRecording downloads the MP3 audio of a Twilio recording
+func (s *TwilioService) FetchRecording(ctx context.Context, recordingSid string) (io.ReadCloser, string, error) {
+	mediaURL := fmt.Sprintf("https://api.twilio.com/2010-04-01/Accounts/%s/Recordings/%s.mp3", s.accountSID, recordingSid)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, mediaURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create recording request: %w", err)
+	}
+	req.SetBasicAuth(s.accountSID, s.authToken)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch recording: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("failed to fetch recording %s: %w", recordingSid, ErrRecordingNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("failed to fetch recording: Twilio returned %s", resp.Status)
+	}
+
+	return resp.Body, resp.Header.Get("Content-Type"), nil
+}
+
+// DeleteRecording deletes a Twilio recording
+func (s *TwilioService) DeleteRecording(recordingSid string) error {
+	if err := s.client.Api.DeleteRecording(recordingSid, nil); err != nil {
+		var restErr *client.TwilioRestError
+		if errors.As(err, &restErr) && restErr.Status == http.StatusNotFound {
+			return fmt.Errorf("failed to delete recording %s: %w", recordingSid, ErrRecordingNotFound)
+		}
+		return fmt.Errorf("failed to delete recording: %w", err)
+	}
+
+	log.Printf("✓ Twilio recording deleted - SID: %s", recordingSid)
+	return nil
+}
+
+// toProviderCall converts a Twilio SDK call into a ProviderCall
+func toProviderCall(call *twilioApi.ApiV2010Call) *ProviderCall {
+	result := &ProviderCall{}
+	if call.Sid != nil {
+		result.SID = *call.Sid
+	}
+	if call.Status != nil {
+		result.Status = *call.Status
+	}
+	if call.To != nil {
+		result.To = *call.To
+	}
+	if call.From != nil {
+		result.From = *call.From
+	}
+	if call.Duration != nil {
+		result.Duration, _ = strconv.Atoi(*call.Duration)
+	}
+	return result
 }
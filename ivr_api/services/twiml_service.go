@@ -3,24 +3,145 @@ package services
 import (
 	"fmt"
 	"log"
+	"net/url"
 	"strings"
 
 	"github.com/prabhatkumar/ivrcalling/models"
+	"github.com/prabhatkumar/ivrcalling/ssml"
+	"github.com/prabhatkumar/ivrshared/twiml"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Webhook paths the generated TwiML points Twilio to
+const (
+	gatherPath        = "/api/webhook/gather"
+	voicePath         = "/api/webhook/voice"
+	collectPath       = "/api/webhook/collect"
+	recordPath        = "/api/webhook/record"
+	transcriptionPath = "/api/webhook/transcription"
+	forwardPath       = "/api/webhook/forward"
+	whisperPath       = "/api/webhook/whisper"
 )
 
 // TwiMLGenerator generates TwiML responses for IVR
 type TwiMLGenerator struct {
-	language string
-	strings  LanguageStrings
+	language    string
+	strings     LanguageStrings
+	voice       Voice
+	speechInput *models.SpeechSettings
+	variables   map[string]string
+	recording   *models.RecordingSettings
+	consent     string
+	startRecord *models.RecordingSettings
+	open        map[primitive.ObjectID]bool
 }
 
 func NewTwiMLGenerator(language string) *TwiMLGenerator {
 	return &TwiMLGenerator{
 		language: language,
 		strings:  GetLanguageStrings(language),
+		voice:    DefaultVoice(language),
 	}
 }
 
+// WithVoice selects a voice of the catalogue, usually the campaign's. Voices
+// not offered for the generator's language fall back to its default voice.
+func (g *TwiMLGenerator) WithVoice(name string) *TwiMLGenerator {
+	g.voice = ResolveVoice(g.language, name)
+	return g
+}
+
+// WithSpeechInput lets callers say their choice on campaign menus
+func (g *TwiMLGenerator) WithSpeechInput(settings *models.SpeechSettings) *TwiMLGenerator {
+	g.speechInput = settings
+	return g
+}
+
+// WithVariables sets the values used to render {{.variable}} placeholders in
+// campaign texts, usually from CallVariables
+func (g *TwiMLGenerator) WithVariables(variables map[string]string) *TwiMLGenerator {
+	g.variables = variables
+	return g
+}
+
+// WithRecording records forwarded conversations when the campaign's
+// recording mode is "forward"
+func (g *TwiMLGenerator) WithRecording(settings *models.RecordingSettings) *TwiMLGenerator {
+	g.recording = settings
+	return g
+}
+
+// WithBusinessHours sets which business calendars are open, usually from
+// BusinessHoursService.Availability. Menus leave out or reword options whose
+// calendar is closed.
+func (g *TwiMLGenerator) WithBusinessHours(open map[primitive.ObjectID]bool) *TwiMLGenerator {
+	g.open = open
+	return g
+}
+
+// WithConsent says a recording consent message before the welcome. It is only
+// set for the first TwiML of a call, so repeated menus do not say it again.
+func (g *TwiMLGenerator) WithConsent(message string) *TwiMLGenerator {
+	g.consent = message
+	return g
+}
+
+// WithRecordingStart records the whole call from the first TwiML on. Inbound
+// calls need it since they are not placed with CallOptions, and a recording
+// cannot be started through the API before the call is answered.
+func (g *TwiMLGenerator) WithRecordingStart(settings *models.RecordingSettings) *TwiMLGenerator {
+	g.startRecord = settings
+	return g
+}
+
+// speech renders a campaign text into an SSML fragment. In SSML texts the
+// variable values are escaped so they cannot add markup of their own.
+func (g *TwiMLGenerator) speech(text string) string {
+	if !ssml.IsMarkup(text) {
+		return ssml.Escape(g.render(text, g.variables))
+	}
+
+	escaped := make(map[string]string, len(g.variables))
+	for name, value := range g.variables {
+		escaped[name] = ssml.Escape(value)
+	}
+	rendered := g.render(text, escaped)
+	fragment, err := ssml.Fragment(rendered)
+	if err != nil {
+		// Texts are validated when campaigns are saved; speak the words rather than fail the call
+		log.Printf("✗ Invalid SSML in '%s': %v", text, err)
+		return ssml.Escape(ssml.PlainText(rendered))
+	}
+	return fragment
+}
+
+// render fills in the template variables of a campaign text. Variables are
+// checked before dialing, so a missing one is logged and left empty rather
+// than failing the call.
+func (g *TwiMLGenerator) render(text string, values map[string]string) string {
+	rendered, err := RenderTemplate(text, values)
+	if err == nil {
+		return rendered
+	}
+	log.Printf("✗ Failed to render template '%s': %v", text, err)
+
+	variables, verr := TemplateVariables(text)
+	if verr != nil {
+		return text
+	}
+	filled := make(map[string]string, len(values)+len(variables))
+	for _, name := range variables {
+		filled[name] = ""
+	}
+	for name, value := range values {
+		filled[name] = value
+	}
+	if rendered, err = RenderTemplate(text, filled); err != nil {
+		return text
+	}
+	return rendered
+}
+
 // GenerateDynamicWelcome generates the welcome message TwiML with campaign intro and actions
 func (g *TwiMLGenerator) GenerateDynamicWelcome(customerName string, campaign *models.Campaign) string {
 	greeting := fmt.Sprintf(g.strings.Welcome, customerName)
@@ -29,14 +150,14 @@ func (g *TwiMLGenerator) GenerateDynamicWelcome(customerName string, campaign *m
 	}
 
 	// Build intro text - ensure it's not empty
-	introText := strings.TrimSpace(campaign.IntroText)
+	introText := strings.TrimSpace(g.speech(campaign.IntroText))
 	if introText == "" {
-		introText = g.strings.MainMenu
+		introText = ssml.Escape(g.strings.MainMenu)
 		log.Printf("WARNING: Campaign intro_text is empty, using default main menu")
 	}
 
 	// Build menu from actions
-	menuText := g.buildMenuFromActions(campaign.Actions)
+	menuText := g.buildMenuFromActions(campaign.Actions, 0)
 
 	log.Printf("=== GENERATING DYNAMIC WELCOME TwiML ===")
 	log.Printf("Campaign Name: %s", campaign.Name)
@@ -45,117 +166,354 @@ func (g *TwiMLGenerator) GenerateDynamicWelcome(customerName string, campaign *m
 	log.Printf("Menu Text: %s", menuText)
 	log.Printf("★★★ USING DYNAMIC IVR FLOW ★★★")
 
-	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<Response>
-    <Say voice="alice" language="%s">%s</Say>
-    <Say voice="alice" language="%s">%s</Say>
-    <Gather action="/api/webhook/gather" method="POST" numDigits="1" timeout="5">
-        <Say voice="alice" language="%s">%s</Say>
-    </Gather>
-    <Say voice="alice" language="%s">%s</Say>
-    <Redirect>/api/webhook/voice</Redirect>
-</Response>`,
-		g.getVoiceLanguage(), greeting,
-		g.getVoiceLanguage(), introText,
-		g.getVoiceLanguage(), menuText,
-		g.getVoiceLanguage(), g.strings.InvalidInput)
-}
-
-// GenerateDynamicResponse generates TwiML based on action configuration
-func (g *TwiMLGenerator) GenerateDynamicResponse(action *models.IVRAction, campaign *models.Campaign) string {
+	return g.withConsent(twiml.NewResponse()).Add(
+		g.say(greeting),
+		g.saySSML(introText),
+		g.menuGather(campaign.Actions, g.saySSML(menuText)),
+		g.say(g.strings.InvalidInput),
+		twiml.Redirect{URL: gatherPath},
+	).String()
+}
+
+// GenerateMenu generates TwiML for a sub-menu at the given depth of the menu tree
+func (g *TwiMLGenerator) GenerateMenu(node *models.MenuNode, depth int) string {
+	menuText := g.buildMenuFromActions(node.Actions, depth)
+
+	log.Printf("=== GENERATING MENU TwiML (depth %d) ===", depth)
+	log.Printf("Prompt: %s", node.Prompt)
+	log.Printf("Menu Text: %s", menuText)
+
+	response := twiml.NewResponse()
+	if prompt := strings.TrimSpace(g.speech(node.Prompt)); prompt != "" {
+		response.Add(g.saySSML(prompt))
+	}
+	return response.Add(
+		g.menuGather(node.Actions, g.saySSML(menuText)),
+		g.say(g.strings.InvalidInput),
+		twiml.Redirect{URL: gatherPath},
+	).String()
+}
+
+// GenerateDynamicResponse generates TwiML based on action configuration.
+// node is the menu level the action belongs to and depth its distance from the root.
+func (g *TwiMLGenerator) GenerateDynamicResponse(action *models.IVRAction, node *models.MenuNode, depth int) string {
 	log.Printf("=== GENERATING DYNAMIC RESPONSE ===")
 	log.Printf("Action Type: %s", action.ActionType)
 	log.Printf("Message: %s", action.Message)
 	log.Printf("Forward Phone: %s", action.ForwardPhone)
 
 	if action.ActionType == "forward" {
-		return g.GenerateForward(action.ForwardPhone, action.Message)
+		return g.GenerateForward(action, 0)
+	}
+
+	if action.ActionType == "menu" && action.SubMenu != nil {
+		return g.GenerateMenu(action.SubMenu, depth+1)
+	}
+
+	if action.ActionType == "collect" && action.Collect != nil {
+		return g.GenerateCollect(action, 0)
+	}
+
+	if action.ActionType == "record" && action.Record != nil {
+		return g.GenerateRecord(action)
 	}
 
 	// Information type - check if message is URL or text
-	message := action.Message
+	message := g.speech(action.Message)
 	if message == "" {
-		message = g.strings.InvalidInput
+		message = ssml.Escape(g.strings.InvalidInput)
 	}
 
 	// Check if message is a URL (starts with http:// or https://)
-	if strings.HasPrefix(message, "http://") || strings.HasPrefix(message, "https://") {
+	if url := ssml.PlainText(message); strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
 		log.Printf("Message is URL - playing audio")
-		return g.GeneratePlayAudio(message, campaign)
+		return g.GeneratePlayAudio(url, node, depth)
 	}
 
 	// Otherwise, use text-to-speech
 	log.Printf("Message is text - using TTS")
-	return g.GenerateTextToSpeech(message, campaign)
-}
-
-// GeneratePlayAudio generates TwiML to play audio file
-func (g *TwiMLGenerator) GeneratePlayAudio(audioURL string, campaign *models.Campaign) string {
-	menuText := g.buildMenuFromActions(campaign.Actions)
-
-	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<Response>
-    <Play>%s</Play>
-    <Gather action="/api/webhook/gather" method="POST" numDigits="1" timeout="5">
-        <Say voice="alice" language="%s">%s</Say>
-    </Gather>
-    <Redirect>/api/webhook/voice</Redirect>
-</Response>`,
-		audioURL,
-		g.getVoiceLanguage(), menuText)
-}
-
-// GenerateTextToSpeech generates TwiML for text-to-speech
-func (g *TwiMLGenerator) GenerateTextToSpeech(message string, campaign *models.Campaign) string {
-	menuText := g.buildMenuFromActions(campaign.Actions)
-
-	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<Response>
-    <Say voice="alice" language="%s">%s</Say>
-    <Gather action="/api/webhook/gather" method="POST" numDigits="1" timeout="5">
-        <Say voice="alice" language="%s">%s</Say>
-    </Gather>
-    <Redirect>/api/webhook/voice</Redirect>
-</Response>`,
-		g.getVoiceLanguage(), message,
-		g.getVoiceLanguage(), menuText)
-}
-
-// GenerateForward generates TwiML to forward call to another number
-func (g *TwiMLGenerator) GenerateForward(phoneNumber string, message string) string {
-	if message != "" {
-		return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<Response>
-    <Say voice="alice" language="%s">%s</Say>
-    <Dial>%s</Dial>
-    <Say voice="alice" language="%s">%s</Say>
-    <Hangup/>
-</Response>`,
-			g.getVoiceLanguage(), message,
-			phoneNumber,
-			g.getVoiceLanguage(), g.strings.Goodbye)
-	}
-
-	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<Response>
-    <Say voice="alice" language="%s">Forwarding your call. Please wait.</Say>
-    <Dial>%s</Dial>
-    <Say voice="alice" language="%s">%s</Say>
-    <Hangup/>
-</Response>`,
-		g.getVoiceLanguage(),
-		phoneNumber,
-		g.getVoiceLanguage(), g.strings.Goodbye)
-}
-
-// buildMenuFromActions creates menu text from campaign actions
-func (g *TwiMLGenerator) buildMenuFromActions(actions []models.IVRAction) string {
+	return g.GenerateTextToSpeech(message, node, depth)
+}
+
+// GeneratePlayAudio generates TwiML to play audio file, then re-offers the menu it was chosen from
+func (g *TwiMLGenerator) GeneratePlayAudio(audioURL string, node *models.MenuNode, depth int) string {
+	menuText := g.buildMenuFromActions(node.Actions, depth)
+
+	return twiml.NewResponse(
+		twiml.Play{URL: audioURL},
+		g.menuGather(node.Actions, g.saySSML(menuText)),
+		twiml.Redirect{URL: gatherPath},
+	).String()
+}
+
+// GenerateTextToSpeech generates TwiML speaking an SSML message, then re-offers the menu it was chosen from
+func (g *TwiMLGenerator) GenerateTextToSpeech(message string, node *models.MenuNode, depth int) string {
+	menuText := g.buildMenuFromActions(node.Actions, depth)
+
+	return twiml.NewResponse(
+		g.saySSML(message),
+		g.menuGather(node.Actions, g.saySSML(menuText)),
+		twiml.Redirect{URL: gatherPath},
+	).String()
+}
+
+// GenerateCollect generates TwiML asking the caller to key in the value of a
+// "collect" action. attempt counts the re-prompts so far; after the first one
+// the action's invalid message is said before the prompt. A timeout redirects
+// to the collect webhook without digits, which counts as an invalid value.
+func (g *TwiMLGenerator) GenerateCollect(action *models.IVRAction, attempt int) string {
+	settings := action.Collect
+	callback := fmt.Sprintf("%s?key=%s&attempt=%d", collectPath, url.QueryEscape(action.ActionInput), attempt)
+
+	response := twiml.NewResponse()
+	if attempt > 0 {
+		notice := strings.TrimSpace(g.speech(settings.InvalidMessage))
+		if notice == "" {
+			notice = ssml.Escape(g.strings.InvalidInput)
+		}
+		response.Add(g.saySSML(notice))
+	}
+
+	prompt := strings.TrimSpace(g.speech(action.Message))
+	return response.Add(
+		twiml.Gather{
+			Action:      callback,
+			Method:      "POST",
+			NumDigits:   settings.MaxLength,
+			FinishOnKey: CollectFinishKey(settings),
+			Timeout:     collectTimeoutSeconds,
+			Verbs:       []twiml.Verb{g.saySSML(prompt)},
+		},
+		twiml.Redirect{URL: callback},
+	).String()
+}
+
+// GenerateCollected generates TwiML confirming a collected value, then
+// re-offers the menu the action was chosen from
+func (g *TwiMLGenerator) GenerateCollected(settings *models.CollectSettings, node *models.MenuNode, depth int) string {
+	message := strings.TrimSpace(g.speech(settings.SuccessMessage))
+	if message == "" {
+		message = ssml.Escape(g.strings.ThankYou)
+	}
+	return g.GenerateTextToSpeech(message, node, depth)
+}
+
+// GenerateCollectFailed generates TwiML for a caller who used up the re-prompts
+// of a "collect" action, then re-offers the menu it was chosen from
+func (g *TwiMLGenerator) GenerateCollectFailed(node *models.MenuNode, depth int) string {
+	return g.GenerateTextToSpeech(ssml.Escape(g.strings.InvalidInput), node, depth)
+}
+
+// GenerateRecord generates TwiML playing the prompt of a "record" action and
+// recording the caller's message after the beep. Twilio skips the action
+// callback when nothing was recorded, so the redirect that follows reaches
+// the record webhook without a recording.
+func (g *TwiMLGenerator) GenerateRecord(action *models.IVRAction) string {
+	settings := action.Record
+	callback := fmt.Sprintf("%s?key=%s", recordPath, url.QueryEscape(action.ActionInput))
+
+	record := twiml.Record{
+		Action:                  callback,
+		Method:                  "POST",
+		Timeout:                 recordSilenceTimeout,
+		MaxLength:               RecordMaxLength(settings),
+		FinishOnKey:             RecordFinishKey(settings),
+		PlayBeep:                "true",
+		RecordingStatusCallback: recordingCallbackPath,
+	}
+	if settings.Transcribe {
+		record.Transcribe = true
+		record.TranscribeCallback = transcriptionPath
+	}
+
+	prompt := strings.TrimSpace(g.speech(action.Message))
+	response := twiml.NewResponse()
+	if url := ssml.PlainText(prompt); strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		response.Add(twiml.Play{URL: url})
+	} else {
+		response.Add(g.saySSML(prompt))
+	}
+	return response.Add(
+		record,
+		twiml.Redirect{URL: callback},
+	).String()
+}
+
+// GenerateRecorded generates TwiML thanking the caller for a recorded
+// message, then re-offers the menu the action was chosen from
+func (g *TwiMLGenerator) GenerateRecorded(settings *models.RecordSettings, node *models.MenuNode, depth int) string {
+	message := strings.TrimSpace(g.speech(settings.SuccessMessage))
+	if message == "" {
+		message = ssml.Escape(g.strings.ThankYou)
+	}
+	return g.GenerateTextToSpeech(message, node, depth)
+}
+
+// GenerateForward generates TwiML forwarding the call for a "forward" action.
+// leg is the number of the ring group to dial next when numbers are dialed
+// one after the other; with the simultaneous strategy all ring at once. The
+// action message, and with forward recording the consent message, are said
+// before the first leg. The outcome is posted to the forward webhook.
+func (g *TwiMLGenerator) GenerateForward(action *models.IVRAction, leg int) string {
+	numbers := ForwardNumbers(action)
+	if leg < 0 || leg >= len(numbers) {
+		leg = 0
+	}
+
+	dial := twiml.Dial{
+		Action: fmt.Sprintf("%s?key=%s&leg=%d", forwardPath, url.QueryEscape(action.ActionInput), leg),
+		Method: "POST",
+	}
+	var whisper string
+	if settings := action.Forward; settings != nil {
+		dial.Timeout = settings.Timeout
+		dial.CallerID = settings.CallerID
+		if strings.TrimSpace(settings.Whisper) != "" {
+			whisper = fmt.Sprintf("%s?key=%s", whisperPath, url.QueryEscape(action.ActionInput))
+		}
+	}
+
+	dialed := numbers[leg : leg+1]
+	if ForwardsSimultaneously(action) {
+		dialed = numbers
+	}
+	for _, number := range dialed {
+		noun := twiml.Number{Value: number}
+		if whisper != "" {
+			noun.URL, noun.Method = whisper, "POST"
+		}
+		dial.Numbers = append(dial.Numbers, noun)
+	}
+
+	response := twiml.NewResponse()
+	if leg == 0 {
+		message := g.speech(action.Message)
+		if message == "" {
+			message = "Forwarding your call. Please wait."
+		}
+		response.Add(g.saySSML(message))
+	}
+	if RecordsForwards(g.recording) {
+		if consent := strings.TrimSpace(g.speech(g.recording.ConsentMessage)); consent != "" && leg == 0 {
+			response.Add(g.saySSML(consent))
+		}
+		dial.Record = "record-from-answer"
+		if g.recording.DualChannel {
+			dial.Record = "record-from-answer-dual"
+		}
+		dial.RecordingStatusCallback = recordingCallbackPath
+		dial.RecordingStatusCallbackEvent = "in-progress completed absent"
+	}
+
+	return response.Add(dial).String()
+}
+
+// GenerateWhisper generates the TwiML said to an agent who answers a forward
+// action, before the caller is connected
+func (g *TwiMLGenerator) GenerateWhisper(message string) string {
+	response := twiml.NewResponse()
+	if rendered := strings.TrimSpace(g.speech(message)); rendered != "" {
+		response.Add(g.saySSML(rendered))
+	}
+	return response.String()
+}
+
+// GenerateAfterHours generates TwiML for an option chosen while its business
+// calendar is closed and it has no after-hours action: the after-hours
+// message, if any, then the menu it was chosen from again
+func (g *TwiMLGenerator) GenerateAfterHours(action *models.IVRAction, node *models.MenuNode, depth int) string {
+	var message string
+	if action.Forward != nil {
+		message = strings.TrimSpace(g.speech(action.Forward.AfterHoursMessage))
+	}
+	if message == "" {
+		return g.GenerateMenu(node, depth)
+	}
+	return g.GenerateTextToSpeech(message, node, depth)
+}
+
+// GenerateForwardEnded generates TwiML ending the call after the caller spoke with an agent
+func (g *TwiMLGenerator) GenerateForwardEnded() string {
+	return twiml.NewResponse(
+		g.say(g.strings.Goodbye),
+		twiml.Hangup{},
+	).String()
+}
+
+// GenerateForwardFallback generates TwiML for a forward action nobody
+// answered: the action's unavailable message, then the menu it was chosen
+// from again, a hang-up, or a redirect to the fallback action.
+func (g *TwiMLGenerator) GenerateForwardFallback(action *models.IVRAction, node *models.MenuNode, depth int) string {
+	var unavailable string
+	if action.Forward != nil {
+		unavailable = strings.TrimSpace(g.speech(action.Forward.UnavailableMessage))
+	}
+
+	switch fallback := ForwardFallback(action); fallback {
+	case ForwardFallbackMenu:
+		if unavailable == "" {
+			return g.GenerateMenu(node, depth)
+		}
+		return g.GenerateTextToSpeech(unavailable, node, depth)
+	case ForwardFallbackHangup:
+		response := twiml.NewResponse()
+		if unavailable != "" {
+			response.Add(g.saySSML(unavailable))
+		}
+		return response.Add(
+			g.say(g.strings.Goodbye),
+			twiml.Hangup{},
+		).String()
+	default:
+		response := twiml.NewResponse()
+		if unavailable != "" {
+			response.Add(g.saySSML(unavailable))
+		}
+		return response.Add(twiml.Redirect{
+			Method: "POST",
+			URL:    fmt.Sprintf("%s?key=%s&fallback=1", forwardPath, url.QueryEscape(action.ActionInput)),
+		}).String()
+	}
+}
+
+// GenerateVoicemail generates TwiML leaving a campaign's voicemail message,
+// text or an audio URL, on an answering machine and hanging up
+func (g *TwiMLGenerator) GenerateVoicemail(message string) string {
+	log.Printf("=== GENERATING VOICEMAIL TwiML ===")
+
+	response := twiml.NewResponse()
+	rendered := strings.TrimSpace(g.speech(message))
+	if url := ssml.PlainText(rendered); strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		response.Add(twiml.Play{URL: url})
+	} else if rendered != "" {
+		response.Add(g.saySSML(rendered))
+	}
+	return response.Add(twiml.Hangup{}).String()
+}
+
+// GenerateHangup generates TwiML ending the call without a word, for calls
+// answered by a machine that gets no message
+func (g *TwiMLGenerator) GenerateHangup() string {
+	return twiml.NewResponse(twiml.Hangup{}).String()
+}
+
+// buildMenuFromActions creates the SSML menu text from the actions of one menu level.
+// At the root 0 repeats the menu; in a sub-menu 0 goes back one level.
+func (g *TwiMLGenerator) buildMenuFromActions(actions []models.IVRAction, depth int) string {
+	zeroOption := "Press 0 to repeat this menu"
+	if depth > 0 {
+		zeroOption = "Press 0 to go back to the previous menu"
+	}
+
 	if len(actions) == 0 {
 		// Return a simple prompt when no actions are defined
+		if depth > 0 {
+			return zeroOption
+		}
 		return "Press 0 to hear this message again"
 	}
 
-	log.Printf("=== BUILDING MENU FROM %d ACTIONS ===", len(actions))
+	log.Printf("=== BUILDING MENU FROM %d ACTIONS (depth %d) ===", len(actions), depth)
 	var menuParts []string
 	for i, action := range actions {
 		// Skip actions with empty input keys
@@ -168,18 +526,48 @@ func (g *TwiMLGenerator) buildMenuFromActions(actions []models.IVRAction) string
 		log.Printf("Action %d: Type=%s, Input=%s, Message='%s', Phone=%s",
 			i+1, action.ActionType, action.ActionInput, action.Message, action.ForwardPhone)
 
-		if action.ActionType == "forward" {
+		// Options closed by their business calendar are described as their
+		// after-hours action under the same key, or left out
+		if !ActionAvailable(&action, g.open) {
+			alternative := AfterHoursAction(&action, &models.MenuNode{Actions: actions})
+			if alternative == nil || !ActionAvailable(alternative, g.open) {
+				log.Printf("  → Closed outside business hours, left out")
+				continue
+			}
+			log.Printf("  → Closed outside business hours, offering action %s instead", alternative.ActionInput)
+			key := action.ActionInput
+			action = *alternative
+			action.ActionInput = key
+		}
+
+		if action.ActionType == "menu" {
+			// Sub-menu - the message is a short label such as "offers"
+			label := strings.TrimSpace(g.speech(action.Message))
+			if label == "" {
+				actionDesc = fmt.Sprintf("Press %s for more options", action.ActionInput)
+			} else {
+				actionDesc = fmt.Sprintf("Press %s for %s", action.ActionInput, label)
+			}
+			log.Printf("  → Sub-menu: %s", actionDesc)
+		} else if action.ActionType == "collect" && action.Collect != nil {
+			actionDesc = fmt.Sprintf("Press %s to enter your %s", action.ActionInput, collectLabel(action.Collect.Name))
+			log.Printf("  → Collect action: %s", actionDesc)
+		} else if action.ActionType == "record" && action.Record != nil {
+			actionDesc = fmt.Sprintf("Press %s to leave us a message", action.ActionInput)
+			log.Printf("  → Record action: %s", actionDesc)
+		} else if action.ActionType == "forward" {
 			// Use custom message if provided, otherwise use default
-			if action.Message != "" && strings.TrimSpace(action.Message) != "" {
-				actionDesc = fmt.Sprintf("Press %s to %s", action.ActionInput, strings.TrimSpace(action.Message))
+			if message := strings.TrimSpace(g.speech(action.Message)); message != "" {
+				actionDesc = fmt.Sprintf("Press %s to %s", action.ActionInput, message)
 				log.Printf("  → Forward with custom message: %s", actionDesc)
 			} else {
 				actionDesc = fmt.Sprintf("Press %s to speak with an agent", action.ActionInput)
 				log.Printf("  → Forward with default message: %s", actionDesc)
 			}
 		} else {
-			// Information action - use first few words of message as description
-			message := strings.TrimSpace(action.Message)
+			// Information action - use first few words of message as description.
+			// The markup of the message is dropped from the summary
+			message := strings.TrimSpace(ssml.PlainText(g.speech(action.Message)))
 			if message == "" {
 				actionDesc = fmt.Sprintf("Press %s for more information", action.ActionInput)
 				log.Printf("  → Info action with no message, using default: %s", actionDesc)
@@ -191,9 +579,7 @@ func (g *TwiMLGenerator) buildMenuFromActions(actions []models.IVRAction) string
 					if len(words) > 5 {
 						desc += "..."
 					}
-					// Escape special characters that might confuse TTS
-					desc = strings.ReplaceAll(desc, "%", " percent")
-					actionDesc = fmt.Sprintf("Press %s for %s", action.ActionInput, desc)
+					actionDesc = fmt.Sprintf("Press %s for %s", action.ActionInput, ssml.Escape(desc))
 					log.Printf("  → Info action with message: %s", actionDesc)
 				} else {
 					actionDesc = fmt.Sprintf("Press %s for more information", action.ActionInput)
@@ -204,8 +590,8 @@ func (g *TwiMLGenerator) buildMenuFromActions(actions []models.IVRAction) string
 		menuParts = append(menuParts, actionDesc)
 	}
 
-	// Add option to return to main menu or repeat
-	menuParts = append(menuParts, "Press 0 to repeat this menu")
+	// Add option to go back or repeat
+	menuParts = append(menuParts, zeroOption)
 
 	finalMenu := strings.Join(menuParts, ". ")
 	log.Printf("=== FINAL MENU TEXT: %s ===", finalMenu)
@@ -228,122 +614,117 @@ func (g *TwiMLGenerator) GenerateWelcome(customerName string) string {
 		greeting = strings.Replace(g.strings.Welcome, "%s, ", "", 1)
 	}
 
-	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<Response>
-    <Say voice="alice" language="%s">%s</Say>
-    <Say voice="alice" language="%s">%s</Say>
-    <Gather action="/api/webhook/gather" method="POST" numDigits="1" timeout="5">
-        <Say voice="alice" language="%s">%s</Say>
-    </Gather>
-    <Say voice="alice" language="%s">%s</Say>
-    <Redirect>/api/webhook/voice</Redirect>
-</Response>`,
-		g.getVoiceLanguage(), greeting,
-		g.getVoiceLanguage(), g.strings.MainMenu,
-		g.getVoiceLanguage(), g.strings.PressToRepeat,
-		g.getVoiceLanguage(), g.strings.InvalidInput)
+	return g.withConsent(twiml.NewResponse()).Add(
+		g.say(greeting),
+		g.say(g.strings.MainMenu),
+		g.gather(gatherPath, g.say(g.strings.PressToRepeat)),
+		g.say(g.strings.InvalidInput),
+		twiml.Redirect{URL: voicePath},
+	).String()
 }
 
 // GenerateMainMenu generates the main menu TwiML
 func (g *TwiMLGenerator) GenerateMainMenu() string {
-	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<Response>
-    <Gather action="/api/webhook/gather" method="POST" numDigits="1" timeout="5">
-        <Say voice="alice" language="%s">%s</Say>
-    </Gather>
-    <Say voice="alice" language="%s">%s</Say>
-    <Redirect>/api/webhook/voice</Redirect>
-</Response>`,
-		g.getVoiceLanguage(), g.strings.MainMenu,
-		g.getVoiceLanguage(), g.strings.InvalidInput)
+	return twiml.NewResponse(
+		g.gather(gatherPath, g.say(g.strings.MainMenu)),
+		g.say(g.strings.InvalidInput),
+		twiml.Redirect{URL: voicePath},
+	).String()
 }
 
 // GenerateProductInfo generates product information TwiML
 func (g *TwiMLGenerator) GenerateProductInfo() string {
-	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<Response>
-    <Say voice="alice" language="%s">%s</Say>
-    <Gather action="/api/webhook/gather" method="POST" numDigits="1" timeout="5">
-        <Say voice="alice" language="%s">%s</Say>
-    </Gather>
-    <Redirect>/api/webhook/voice</Redirect>
-</Response>`,
-		g.getVoiceLanguage(), g.strings.ProductInfo,
-		g.getVoiceLanguage(), g.strings.PressForInfo)
+	return twiml.NewResponse(
+		g.say(g.strings.ProductInfo),
+		g.gather(gatherPath, g.say(g.strings.PressForInfo)),
+		twiml.Redirect{URL: voicePath},
+	).String()
 }
 
 // GenerateOfferDetails generates offer details TwiML
 func (g *TwiMLGenerator) GenerateOfferDetails() string {
-	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<Response>
-    <Say voice="alice" language="%s">%s</Say>
-    <Gather action="/api/webhook/gather" method="POST" numDigits="1" timeout="5">
-        <Say voice="alice" language="%s">%s</Say>
-    </Gather>
-    <Redirect>/api/webhook/voice</Redirect>
-</Response>`,
-		g.getVoiceLanguage(), g.strings.OfferDetails,
-		g.getVoiceLanguage(), g.strings.PressForInfo)
+	return twiml.NewResponse(
+		g.say(g.strings.OfferDetails),
+		g.gather(gatherPath, g.say(g.strings.PressForInfo)),
+		twiml.Redirect{URL: voicePath},
+	).String()
 }
 
 // GenerateOptOut generates opt-out confirmation TwiML
 func (g *TwiMLGenerator) GenerateOptOut() string {
-	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<Response>
-    <Gather action="/api/webhook/gather" method="POST" numDigits="1" timeout="5">
-        <Say voice="alice" language="%s">%s</Say>
-    </Gather>
-    <Redirect>/api/webhook/voice</Redirect>
-</Response>`,
-		g.getVoiceLanguage(), g.strings.PressToOptOut)
+	return twiml.NewResponse(
+		g.gather(gatherPath, g.say(g.strings.PressToOptOut)),
+		twiml.Redirect{URL: voicePath},
+	).String()
 }
 
 // GenerateOptOutConfirm generates opt-out final confirmation TwiML
 func (g *TwiMLGenerator) GenerateOptOutConfirm() string {
-	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<Response>
-    <Say voice="alice" language="%s">%s</Say>
-    <Say voice="alice" language="%s">%s</Say>
-    <Hangup/>
-</Response>`,
-		g.getVoiceLanguage(), g.strings.OptOutConfirm,
-		g.getVoiceLanguage(), g.strings.Goodbye)
+	return twiml.NewResponse(
+		g.say(g.strings.OptOutConfirm),
+		g.say(g.strings.Goodbye),
+		twiml.Hangup{},
+	).String()
 }
 
 // GenerateGoodbye generates goodbye message TwiML
 func (g *TwiMLGenerator) GenerateGoodbye() string {
-	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<Response>
-    <Say voice="alice" language="%s">%s</Say>
-    <Say voice="alice" language="%s">%s</Say>
-    <Hangup/>
-</Response>`,
-		g.getVoiceLanguage(), g.strings.ThankYou,
-		g.getVoiceLanguage(), g.strings.Goodbye)
+	return twiml.NewResponse(
+		g.say(g.strings.ThankYou),
+		g.say(g.strings.Goodbye),
+		twiml.Hangup{},
+	).String()
 }
 
 // GenerateInvalidInput generates invalid input message TwiML
 func (g *TwiMLGenerator) GenerateInvalidInput() string {
-	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<Response>
-    <Say voice="alice" language="%s">%s</Say>
-    <Redirect>/api/webhook/voice</Redirect>
-</Response>`,
-		g.getVoiceLanguage(), g.strings.InvalidInput)
+	return twiml.NewResponse(
+		g.say(g.strings.InvalidInput),
+		twiml.Redirect{URL: voicePath},
+	).String()
 }
 
-// getVoiceLanguage maps language codes to Twilio voice language codes
-func (g *TwiMLGenerator) getVoiceLanguage() string {
-	voiceMap := map[string]string{
-		"en": "en-US",
-		"es": "es-ES",
-		"fr": "fr-FR",
-		"de": "de-DE",
-		"hi": "hi-IN",
+// withConsent starts a response with the recording start and the consent
+// message, if they are set
+func (g *TwiMLGenerator) withConsent(response *twiml.Response) *twiml.Response {
+	if g.startRecord != nil {
+		response.Add(twiml.Start{Recording: &twiml.Recording{
+			Channels:                      RecordingChannels(g.startRecord),
+			RecordingStatusCallback:       recordingCallbackPath,
+			RecordingStatusCallbackMethod: "POST",
+			RecordingStatusCallbackEvent:  "in-progress completed absent",
+		}})
 	}
+	if consent := strings.TrimSpace(g.speech(g.consent)); consent != "" {
+		response.Add(g.saySSML(consent))
+	}
+	return response
+}
+
+// say speaks plain text with the generator's voice
+func (g *TwiMLGenerator) say(text string) twiml.Say {
+	return twiml.Say{Voice: g.voice.Name, Language: g.voice.Locale, Text: text}
+}
+
+// saySSML speaks an SSML fragment with the generator's voice
+func (g *TwiMLGenerator) saySSML(fragment string) twiml.Say {
+	return twiml.Say{Voice: g.voice.Name, Language: g.voice.Locale, SSML: fragment}
+}
+
+// gather collects a single key press while playing the given verbs
+func (g *TwiMLGenerator) gather(action string, verbs ...twiml.Verb) twiml.Gather {
+	return twiml.Gather{Action: action, Method: "POST", NumDigits: 1, Timeout: 5, Verbs: verbs}
+}
 
-	if voice, ok := voiceMap[g.language]; ok {
-		return voice
+// menuGather gathers the choice on a campaign menu level: a key press, or
+// with speech input enabled also one of the actions' keywords
+func (g *TwiMLGenerator) menuGather(actions []models.IVRAction, verbs ...twiml.Verb) twiml.Gather {
+	gather := g.gather(gatherPath, verbs...)
+	if SpeechEnabled(g.speechInput) {
+		gather.Input = "dtmf speech"
+		gather.Language = g.voice.Locale
+		gather.SpeechTimeout = "auto"
+		gather.Hints = strings.Join(SpeechHints(actions), ", ")
 	}
-	return "en-US" // default
+	return gather
 }
@@ -0,0 +1,277 @@
+// Package ssml validates the subset of SSML accepted in campaign texts and
+// turns texts into fragments that can be embedded in a TwiML <Say>.
+//
+// Supported elements:
+//
+//	<break time="500ms"/> or <break strength="strong"/>
+//	<emphasis level="strong">...</emphasis>
+//	<say-as interpret-as="digits|date|currency">...</say-as>
+//	<prosody rate="slow">...</prosody>
+//	<phoneme alphabet="ipa" ph="...">...</phoneme>
+//
+// Texts without any "<" are plain text and are escaped as needed.
+package ssml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Error explains why an SSML text is invalid
+type Error struct {
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return "invalid SSML: " + e.Reason
+}
+
+// element describes an allowed element: its attributes with their validators
+// and whether it may contain nested elements
+type element struct {
+	attributes map[string]func(string) error
+	required   []string
+	empty      bool // may not contain anything
+	textOnly   bool // may contain text but no elements
+}
+
+var (
+	durationPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)(ms|s)$`)
+	percentPattern  = regexp.MustCompile(`^(\d+)%$`)
+)
+
+// maxBreakSeconds is the longest pause Twilio voices accept
+const maxBreakSeconds = 10
+
+var elements = map[string]element{
+	"break": {
+		attributes: map[string]func(string) error{
+			"time":     validateBreakTime,
+			"strength": oneOf("none", "x-weak", "weak", "medium", "strong", "x-strong"),
+		},
+		empty: true,
+	},
+	"emphasis": {
+		attributes: map[string]func(string) error{
+			"level": oneOf("strong", "moderate", "reduced"),
+		},
+	},
+	"say-as": {
+		attributes: map[string]func(string) error{
+			"interpret-as": oneOf("digits", "date", "currency"),
+			"format":       oneOf("mdy", "dmy", "ymd", "md", "dm", "ym", "my", "d", "m", "y"),
+		},
+		required: []string{"interpret-as"},
+		textOnly: true,
+	},
+	"prosody": {
+		attributes: map[string]func(string) error{
+			"rate": validateRate,
+		},
+		required: []string{"rate"},
+	},
+	"phoneme": {
+		attributes: map[string]func(string) error{
+			"alphabet": oneOf("ipa", "x-sampa"),
+			"ph":       nonEmpty,
+		},
+		required: []string{"ph"},
+		textOnly: true,
+	},
+}
+
+// IsMarkup reports whether a text is written in SSML rather than plain text
+func IsMarkup(text string) bool {
+	return strings.Contains(text, "<")
+}
+
+// Validate checks a campaign text against the supported subset. Plain text is
+// always valid.
+func Validate(text string) error {
+	_, err := Fragment(text)
+	return err
+}
+
+// Fragment returns the text as SSML ready to embed in a <Say>. Plain text is
+// escaped; markup is validated and re-encoded so only the supported subset is
+// ever emitted.
+func Fragment(text string) (string, error) {
+	if !IsMarkup(text) {
+		return Escape(text), nil
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader("<speak>" + text + "</speak>"))
+	decoder.Strict = true
+
+	var sb strings.Builder
+	encoder := xml.NewEncoder(&sb)
+	var stack []string
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var syntaxErr *xml.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				return "", &Error{Reason: syntaxErr.Msg}
+			}
+			return "", &Error{Reason: err.Error()}
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := t.Name.Local
+			if len(stack) == 0 {
+				// The <speak> wrapper added above
+				stack = append(stack, name)
+				continue
+			}
+			if name == "speak" {
+				return "", &Error{Reason: "<speak> is added automatically and must not be written"}
+			}
+			spec, ok := elements[name]
+			if !ok || t.Name.Space != "" {
+				return "", &Error{Reason: fmt.Sprintf("<%s> is not supported", name)}
+			}
+			parent := elements[stack[len(stack)-1]]
+			if parent.empty || parent.textOnly {
+				return "", &Error{Reason: fmt.Sprintf("<%s> cannot contain <%s>", stack[len(stack)-1], name)}
+			}
+			if err := validateAttributes(name, spec, t.Attr); err != nil {
+				return "", err
+			}
+			stack = append(stack, name)
+			if err := encoder.EncodeToken(xml.StartElement{Name: xml.Name{Local: name}, Attr: t.Attr}); err != nil {
+				return "", err
+			}
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				continue
+			}
+			if err := encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: t.Name.Local}}); err != nil {
+				return "", err
+			}
+		case xml.CharData:
+			if len(stack) > 0 && elements[stack[len(stack)-1]].empty && strings.TrimSpace(string(t)) != "" {
+				return "", &Error{Reason: fmt.Sprintf("<%s> must be empty", stack[len(stack)-1])}
+			}
+			if err := encoder.EncodeToken(t); err != nil {
+				return "", err
+			}
+		case xml.Comment:
+			// Dropped
+		default:
+			return "", &Error{Reason: "processing instructions and directives are not allowed"}
+		}
+	}
+
+	if err := encoder.Flush(); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// Escape turns plain text into an SSML fragment
+func Escape(text string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(text))
+	return sb.String()
+}
+
+// PlainText returns the words of an SSML fragment without its markup. It is
+// lenient so that malformed markup still yields its words.
+func PlainText(fragment string) string {
+	decoder := xml.NewDecoder(strings.NewReader("<speak>" + fragment + "</speak>"))
+	decoder.Strict = false
+	var sb strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		if data, ok := token.(xml.CharData); ok {
+			sb.Write(data)
+		}
+	}
+	return sb.String()
+}
+
+func validateAttributes(name string, spec element, attrs []xml.Attr) error {
+	present := make(map[string]bool, len(attrs))
+	for _, attr := range attrs {
+		validate, ok := spec.attributes[attr.Name.Local]
+		if !ok || attr.Name.Space != "" {
+			return &Error{Reason: fmt.Sprintf("<%s> does not support the %s attribute", name, attr.Name.Local)}
+		}
+		if err := validate(attr.Value); err != nil {
+			return &Error{Reason: fmt.Sprintf("<%s %s=\"%s\">: %v", name, attr.Name.Local, attr.Value, err)}
+		}
+		present[attr.Name.Local] = true
+	}
+	for _, attr := range spec.required {
+		if !present[attr] {
+			return &Error{Reason: fmt.Sprintf("<%s> requires the %s attribute", name, attr)}
+		}
+	}
+	if name == "say-as" && present["format"] {
+		for _, attr := range attrs {
+			if attr.Name.Local == "interpret-as" && attr.Value != "date" {
+				return &Error{Reason: "<say-as> format is only supported for dates"}
+			}
+		}
+	}
+	return nil
+}
+
+func oneOf(values ...string) func(string) error {
+	return func(value string) error {
+		for _, v := range values {
+			if value == v {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(values, ", "))
+	}
+}
+
+func nonEmpty(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return errors.New("must not be empty")
+	}
+	return nil
+}
+
+func validateBreakTime(value string) error {
+	match := durationPattern.FindStringSubmatch(value)
+	if match == nil {
+		return errors.New("must be a duration such as 500ms or 2s")
+	}
+	seconds, _ := strconv.ParseFloat(match[1], 64)
+	if match[2] == "ms" {
+		seconds /= 1000
+	}
+	if seconds > maxBreakSeconds {
+		return fmt.Errorf("must be at most %ds", maxBreakSeconds)
+	}
+	return nil
+}
+
+func validateRate(value string) error {
+	if err := oneOf("x-slow", "slow", "medium", "fast", "x-fast")(value); err == nil {
+		return nil
+	}
+	if match := percentPattern.FindStringSubmatch(value); match != nil {
+		if percent, _ := strconv.Atoi(match[1]); percent >= 20 && percent <= 200 {
+			return nil
+		}
+	}
+	return errors.New("must be x-slow, slow, medium, fast, x-fast or a percentage from 20% to 200%")
+}
@@ -0,0 +1,111 @@
+package ssml
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFragment(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain text is escaped", `Tom & Jerry's "show"`, `Tom &amp; Jerry&#39;s &#34;show&#34;`},
+		{"break", `Hello<break time="500ms"/>there`, `Hello<break time="500ms"></break>there`},
+		{"break strength", `<break strength="x-strong"/>`, `<break strength="x-strong"></break>`},
+		{"emphasis", `<emphasis level="strong">now</emphasis>`, `<emphasis level="strong">now</emphasis>`},
+		{"say-as date", `<say-as interpret-as="date" format="dmy">01-02-2024</say-as>`, `<say-as interpret-as="date" format="dmy">01-02-2024</say-as>`},
+		{"prosody percentage", `<prosody rate="80%">slowly</prosody>`, `<prosody rate="80%">slowly</prosody>`},
+		{"phoneme", `<phoneme alphabet="ipa" ph="təˈmɑːtəʊ">tomato</phoneme>`, `<phoneme alphabet="ipa" ph="təˈmɑːtəʊ">tomato</phoneme>`},
+		{"nested", `<prosody rate="slow"><emphasis>one</emphasis> two</prosody>`, `<prosody rate="slow"><emphasis>one</emphasis> two</prosody>`},
+		{"comments are dropped", `Hi<!-- note --> there<break time="1s"/>`, `Hi there<break time="1s"></break>`},
+		{"entities are re-encoded", `<emphasis>R&amp;D</emphasis>`, `<emphasis>R&amp;D</emphasis>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Fragment(tt.text)
+			if err != nil {
+				t.Fatalf("Fragment(%q) returned error: %v", tt.text, err)
+			}
+			if got != tt.want {
+				t.Errorf("Fragment(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		reason string
+	}{
+		{"unclosed element", `<emphasis>now`, "element <emphasis> closed by </speak>"},
+		{"unsupported element", `<audio src="x.mp3"/>`, "<audio> is not supported"},
+		{"speak written", `<speak>Hi</speak>`, "<speak> is added automatically"},
+		{"namespaced element", `<amazon:effect name="whispered">hi</amazon:effect>`, "is not supported"},
+		{"unsupported attribute", `<break time="1s" onclick="x"/>`, "does not support the onclick attribute"},
+		{"missing required attribute", `<say-as>123</say-as>`, "requires the interpret-as attribute"},
+		{"break too long", `<break time="11s"/>`, "must be at most 10s"},
+		{"break not a duration", `<break time="long"/>`, "must be a duration"},
+		{"rate out of range", `<prosody rate="300%">fast</prosody>`, "percentage from 20% to 200%"},
+		{"invalid level", `<emphasis level="loud">hi</emphasis>`, "must be one of strong, moderate, reduced"},
+		{"format outside dates", `<say-as interpret-as="digits" format="dmy">12</say-as>`, "only supported for dates"},
+		{"element inside text-only element", `<say-as interpret-as="digits"><emphasis>1</emphasis></say-as>`, "<say-as> cannot contain <emphasis>"},
+		{"text inside empty element", `<break time="1s">pause</break>`, "<break> must be empty"},
+		{"empty phoneme", `<phoneme ph=" ">x</phoneme>`, "must not be empty"},
+		{"processing instruction", `<?php echo 1 ?>`, "processing instructions and directives are not allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.text)
+			var ssmlErr *Error
+			if !errors.As(err, &ssmlErr) {
+				t.Fatalf("Validate(%q) error = %v, want *Error", tt.text, err)
+			}
+			if !strings.Contains(ssmlErr.Reason, tt.reason) {
+				t.Errorf("Reason = %q, want it to contain %q", ssmlErr.Reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestIsMarkup(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"Press 1 for sales", false},
+		{"Tom & Jerry", false},
+		{`Wait<break time="1s"/>`, true},
+		{"1 < 2", true},
+	}
+
+	for _, tt := range tests {
+		if got := IsMarkup(tt.text); got != tt.want {
+			t.Errorf("IsMarkup(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		fragment string
+		want     string
+	}{
+		{"Hello there", "Hello there"},
+		{`Hello<break time="1s"></break> <emphasis>there</emphasis>`, "Hello there"},
+		{`R&amp;D`, "R&D"},
+		{`<emphasis>unclosed`, "unclosed"},
+	}
+
+	for _, tt := range tests {
+		if got := PlainText(tt.fragment); got != tt.want {
+			t.Errorf("PlainText(%q) = %q, want %q", tt.fragment, got, tt.want)
+		}
+	}
+}
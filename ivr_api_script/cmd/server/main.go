@@ -46,7 +46,7 @@ func main() {
 	router.Use(corsMiddleware())
 
 	// Setup routes
-	api.SetupRoutes(router, callHandler, twimlHandler)
+	api.SetupRoutes(router, config.AppConfig, callHandler, twimlHandler)
 
 	// Start server
 	addr := ":" + config.AppConfig.Port
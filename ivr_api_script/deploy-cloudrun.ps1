@@ -44,7 +44,7 @@ gcloud services enable containerregistry.googleapis.com
 
 # Build Docker image
 Write-ColorOutput "`n📦 Building Docker image..." "Yellow"
-docker build -t $ImageName .
+docker build -f Dockerfile -t $ImageName ..
 
 if ($LASTEXITCODE -ne 0) {
     Write-ColorOutput "❌ Docker build failed!" "Red"
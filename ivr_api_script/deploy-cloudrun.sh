@@ -43,7 +43,7 @@ gcloud services enable containerregistry.googleapis.com
 
 # Build Docker image
 echo -e "\n${YELLOW}📦 Building Docker image...${NC}"
-docker build -t $IMAGE_NAME .
+docker build -f Dockerfile -t $IMAGE_NAME ..
 
 echo -e "${GREEN}✅ Docker build successful!${NC}"
 
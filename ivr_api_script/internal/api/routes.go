@@ -2,10 +2,12 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/qandi/ivr-calling-api/internal/config"
 	"github.com/qandi/ivr-calling-api/internal/handlers"
+	"github.com/qandi/ivr-calling-api/internal/middleware"
 )
 
-func SetupRoutes(router *gin.Engine, callHandler *handlers.CallHandler, twimlHandler *handlers.TwiMLHandler) {
+func SetupRoutes(router *gin.Engine, cfg *config.Config, callHandler *handlers.CallHandler, twimlHandler *handlers.TwiMLHandler) {
 	// Health check
 	router.GET("/health", callHandler.HealthCheck)
 
@@ -25,13 +27,13 @@ func SetupRoutes(router *gin.Engine, callHandler *handlers.CallHandler, twimlHan
 		}
 
 		// Configuration routes
-		config := v1.Group("/config")
+		configRoutes := v1.Group("/config")
 		{
-			config.GET("/ivr", callHandler.GetIVRConfig)
+			configRoutes.GET("/ivr", callHandler.GetIVRConfig)
 		}
 
 		// TwiML routes (for Twilio to call)
-		twiml := v1.Group("/twiml")
+		twiml := v1.Group("/twiml", middleware.TwilioSignature(cfg))
 		{
 			twiml.GET("/welcome", twimlHandler.WelcomeMessage)
 			twiml.POST("/welcome", twimlHandler.WelcomeMessage)
@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -15,6 +16,15 @@ type Config struct {
 	TwilioPhoneNumber string
 	QITeamPhone       string
 	ServerBaseURL     string
+
+	// TTSVoice and TTSLanguage select the Twilio text-to-speech voice,
+	// e.g. Polly.Aditi with en-IN
+	TTSVoice    string
+	TTSLanguage string
+
+	// TwilioValidateSignature rejects TwiML requests without a valid
+	// X-Twilio-Signature; only disable it for local development
+	TwilioValidateSignature bool
 }
 
 var AppConfig *Config
@@ -34,6 +44,10 @@ func LoadConfig() {
 		TwilioPhoneNumber: getEnv("TWILIO_PHONE_NUMBER", ""),
 		QITeamPhone:       getEnv("QI_TEAM_PHONE", "+917905252436"),
 		ServerBaseURL:     getEnv("SERVER_BASE_URL", "http://localhost:8080"),
+		TTSVoice:          getEnv("TTS_VOICE", "Polly.Aditi"),
+		TTSLanguage:       getEnv("TTS_LANGUAGE", "en-IN"),
+
+		TwilioValidateSignature: getEnvBool("TWILIO_VALIDATE_SIGNATURE", true),
 	}
 }
 
@@ -43,3 +57,11 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
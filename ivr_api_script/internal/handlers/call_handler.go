@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prabhatkumar/ivrshared/phone"
 	"github.com/qandi/ivr-calling-api/internal/models"
 	"github.com/qandi/ivr-calling-api/internal/service"
 )
@@ -41,6 +43,14 @@ func (h *CallHandler) InitiateCall(c *gin.Context) {
 	}
 
 	response, err := h.twilioService.InitiateCall(req.PhoneNumber, req.CallbackURL)
+	var phoneErr *phone.Error
+	if errors.As(err, &phoneErr) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid phone number",
+			Message: phoneErr.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Failed to initiate call",
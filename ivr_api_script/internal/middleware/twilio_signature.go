@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prabhatkumar/ivrshared/signature"
+	"github.com/qandi/ivr-calling-api/internal/config"
+)
+
+// TwilioSignature rejects requests that do not carry a valid X-Twilio-Signature
+// header. Twilio signs the public URL it called (SERVER_BASE_URL plus the path
+// and query) followed by the sorted POST parameters.
+// See https://www.twilio.com/docs/usage/security#validating-requests
+func TwilioSignature(cfg *config.Config) gin.HandlerFunc {
+	if !cfg.TwilioValidateSignature {
+		log.Println("WARNING: Twilio signature validation is disabled")
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	if cfg.TwilioAuthToken == "" {
+		log.Println("WARNING: TWILIO_AUTH_TOKEN is not set - all TwiML requests will be rejected")
+	}
+
+	baseURL := strings.TrimRight(cfg.ServerBaseURL, "/")
+
+	return func(c *gin.Context) {
+		header := c.GetHeader("X-Twilio-Signature")
+		if header == "" || cfg.TwilioAuthToken == "" {
+			log.Printf("Rejected %s - missing Twilio signature", c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Invalid Twilio signature"})
+			return
+		}
+
+		if err := c.Request.ParseForm(); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid form data"})
+			return
+		}
+
+		url := baseURL + c.Request.URL.RequestURI()
+		if !signature.Validate(cfg.TwilioAuthToken, url, c.Request.PostForm, header) {
+			log.Printf("Rejected %s - invalid Twilio signature", c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Invalid Twilio signature"})
+			return
+		}
+
+		c.Next()
+	}
+}
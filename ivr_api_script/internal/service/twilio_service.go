@@ -4,13 +4,14 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"html"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
+	"github.com/prabhatkumar/ivrshared/phone"
+	"github.com/prabhatkumar/ivrshared/twiml"
 	"github.com/qandi/ivr-calling-api/internal/config"
 	"github.com/qandi/ivr-calling-api/internal/models"
 )
@@ -35,10 +36,12 @@ func NewTwilioService(cfg *config.Config) *TwilioService {
 
 // InitiateCall initiates an IVR call using Twilio
 func (s *TwilioService) InitiateCall(phoneNumber, callbackURL string) (*models.CallResponse, error) {
-	// Validate phone number
-	if !isValidPhoneNumber(phoneNumber) {
-		return nil, fmt.Errorf("invalid phone number format")
+	// Validate the phone number and normalize it to E.164
+	number, err := phone.ParseDialable(phoneNumber, "")
+	if err != nil {
+		return nil, err
 	}
+	phoneNumber = number.E164
 
 	// Validate Twilio configuration
 	if s.config.TwilioAccountSID == "" || s.config.TwilioAuthToken == "" {
@@ -138,23 +141,20 @@ func (s *TwilioService) GenerateWelcomeTwiML() string {
 	// Build the menu options
 	var menuOptions string
 	for _, action := range s.ivrConfig.Actions {
-		menuOptions += html.EscapeString(action.Message) + ". "
+		menuOptions += action.Message + ". "
 	}
 
-	twiml := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<Response>
-    <Say voice="Polly.Aditi" language="en-IN">%s</Say>
-    <Gather numDigits="1" action="%s/api/v1/twiml/handle-input" method="POST" timeout="10">
-        <Say voice="Polly.Aditi" language="en-IN">%s</Say>
-    </Gather>
-    <Say voice="Polly.Aditi" language="en-IN">We did not receive any input. Goodbye!</Say>
-</Response>`,
-		html.EscapeString(s.ivrConfig.IntroText),
-		html.EscapeString(s.config.ServerBaseURL),
-		menuOptions,
-	)
-
-	return twiml
+	return twiml.NewResponse(
+		s.say(s.ivrConfig.IntroText),
+		twiml.Gather{
+			NumDigits: 1,
+			Action:    s.config.ServerBaseURL + "/api/v1/twiml/handle-input",
+			Method:    "POST",
+			Timeout:   10,
+			Verbs:     []twiml.Verb{s.say(menuOptions)},
+		},
+		s.say("We did not receive any input. Goodbye!"),
+	).String()
 }
 
 // GenerateHandleInputTwiML generates TwiML based on user's digit input
@@ -164,29 +164,22 @@ func (s *TwilioService) GenerateHandleInputTwiML(digit string) string {
 			switch action.Action {
 			case "forward":
 				// Forward the call to Q&I team
-				return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<Response>
-    <Say voice="Polly.Aditi" language="en-IN">Connecting you to the Q and I team. Please wait.</Say>
-    <Dial timeout="30" callerId="%s">
-        <Number>%s</Number>
-    </Dial>
-    <Say voice="Polly.Aditi" language="en-IN">Sorry, we could not connect your call at this time. This may be because the number is not verified on our trial account. Please try again later or contact us directly. %s</Say>
-</Response>`,
-					s.config.TwilioPhoneNumber,
-					action.ForwardTo,
-					html.EscapeString(s.ivrConfig.EndMessage),
-				)
+				return twiml.NewResponse(
+					s.say("Connecting you to the Q and I team. Please wait."),
+					twiml.Dial{
+						Timeout:  30,
+						CallerID: s.config.TwilioPhoneNumber,
+						Numbers:  []twiml.Number{{Value: action.ForwardTo}},
+					},
+					s.say("Sorry, we could not connect your call at this time. This may be because the number is not verified on our trial account. Please try again later or contact us directly. "+s.ivrConfig.EndMessage),
+				).String()
 
 			case "inform":
 				// Provide information
-				return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<Response>
-    <Say voice="Polly.Aditi" language="en-IN">%s</Say>
-    <Say voice="Polly.Aditi" language="en-IN">%s</Say>
-</Response>`,
-					html.EscapeString(action.Description),
-					html.EscapeString(s.ivrConfig.EndMessage),
-				)
+				return twiml.NewResponse(
+					s.say(action.Description),
+					s.say(s.ivrConfig.EndMessage),
+				).String()
 
 			case "repeat":
 				// Repeat the welcome message
@@ -196,10 +189,12 @@ func (s *TwilioService) GenerateHandleInputTwiML(digit string) string {
 	}
 
 	// Invalid input
-	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<Response>
-    <Say voice="Polly.Aditi" language="en-IN">Invalid input. %s</Say>
-</Response>`, html.EscapeString(s.ivrConfig.EndMessage))
+	return twiml.NewResponse(s.say("Invalid input. " + s.ivrConfig.EndMessage)).String()
+}
+
+// say speaks text with the configured voice
+func (s *TwilioService) say(text string) twiml.Say {
+	return twiml.Say{Voice: s.config.TTSVoice, Language: s.config.TTSLanguage, Text: text}
 }
 
 // handleDigitInput processes digit input from the caller
@@ -219,12 +214,3 @@ func (s *TwilioService) handleDigitInput(callID, digit string) {
 	}
 	fmt.Printf("Invalid digit pressed: %s\n", digit)
 }
-
-// Helper functions
-func isValidPhoneNumber(phone string) bool {
-	// Basic validation - should start with + and contain only digits
-	if len(phone) < 10 || phone[0] != '+' {
-		return false
-	}
-	return true
-}
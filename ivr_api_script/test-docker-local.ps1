@@ -6,7 +6,7 @@ Write-Host "========================================`n" -ForegroundColor Cyan
 
 # Build the image
 Write-Host "📦 Building Docker image..." -ForegroundColor Yellow
-docker build -t ivr-api:local .
+docker build -f Dockerfile -t ivr-api:local ..
 
 if ($LASTEXITCODE -ne 0) {
     Write-Host "❌ Build failed!" -ForegroundColor Red
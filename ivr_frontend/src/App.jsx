@@ -1,15 +1,43 @@
-import React from 'react';
+import React, { useState, useEffect } from 'react';
 import { BrowserRouter as Router, Routes, Route } from 'react-router-dom';
 import Sidebar from './components/Sidebar';
 import DashboardPage from './pages/DashboardPage';
 import CampaignsPage from './pages/CampaignsPage';
 import CampaignCallsPage from './pages/CampaignCallsPage';
+import LoginPage from './pages/LoginPage';
+import { authService } from './services/api';
 
 function App() {
+    // null while checking whether the session token (or disabled auth) lets us in
+    const [authenticated, setAuthenticated] = useState(null);
+
+    useEffect(() => {
+        authService
+            .getCurrentPrincipal()
+            .then(() => setAuthenticated(true))
+            .catch(() => setAuthenticated(false));
+
+        const handleLogout = () => setAuthenticated(false);
+        window.addEventListener('auth:logout', handleLogout);
+        return () => window.removeEventListener('auth:logout', handleLogout);
+    }, []);
+
+    if (authenticated === null) {
+        return (
+            <div className="flex items-center justify-center min-h-screen bg-gray-50">
+                <div className="text-gray-500">Loading...</div>
+            </div>
+        );
+    }
+
+    if (!authenticated) {
+        return <LoginPage onLogin={() => setAuthenticated(true)} />;
+    }
+
     return (
         <Router>
             <div className="flex min-h-screen bg-gray-50">
-                <Sidebar />
+                <Sidebar onLogout={authService.logout} />
                 <main className="flex-1 p-8 overflow-y-auto">
                     <Routes>
                         <Route path="/" element={<DashboardPage />} />
@@ -1,15 +1,15 @@
 import React from 'react';
 import { NavLink } from 'react-router-dom';
-import { LayoutDashboard, Megaphone, Phone } from 'lucide-react';
+import { LayoutDashboard, Megaphone, Phone, LogOut } from 'lucide-react';
 
-const Sidebar = () => {
+const Sidebar = ({ onLogout }) => {
     const navItems = [
         { path: '/', icon: <LayoutDashboard size={20} />, label: 'Dashboard' },
         { path: '/campaigns', icon: <Megaphone size={20} />, label: 'Campaigns' },
     ];
 
     return (
-        <div className="w-64 bg-gray-900 text-white min-h-screen">
+        <div className="w-64 bg-gray-900 text-white min-h-screen flex flex-col">
             {/* Logo */}
             <div className="p-6 border-b border-gray-800">
                 <div className="flex items-center gap-3">
@@ -24,7 +24,7 @@ const Sidebar = () => {
             </div>
 
             {/* Navigation */}
-            <nav className="p-4">
+            <nav className="p-4 flex-1">
                 <ul className="space-y-2">
                     {navItems.map((item) => (
                         <li key={item.path}>
@@ -45,6 +45,17 @@ const Sidebar = () => {
                     ))}
                 </ul>
             </nav>
+
+            {/* Sign out */}
+            <div className="p-4 border-t border-gray-800">
+                <button
+                    onClick={onLogout}
+                    className="flex items-center gap-3 w-full px-4 py-3 rounded-lg text-gray-300 hover:bg-gray-800 hover:text-white transition"
+                >
+                    <LogOut size={20} />
+                    <span className="font-medium">Sign Out</span>
+                </button>
+            </div>
         </div>
     );
 };
@@ -18,9 +18,25 @@ const CampaignCallsPage = () => {
 
     useEffect(() => {
         loadData();
-        // Poll for updates every 5 seconds
-        const interval = setInterval(loadData, 5000);
-        return () => clearInterval(interval);
+
+        // Reload when a call changes, batching bursts of events into one request
+        let reloadTimer = null;
+        const unsubscribe = campaignService.subscribeToEvents(id, () => {
+            if (!reloadTimer) {
+                reloadTimer = setTimeout(() => {
+                    reloadTimer = null;
+                    loadData();
+                }, 500);
+            }
+        });
+
+        // Slow poll as a fallback in case the event stream drops
+        const interval = setInterval(loadData, 30000);
+        return () => {
+            unsubscribe();
+            clearTimeout(reloadTimer);
+            clearInterval(interval);
+        };
     }, [id]);
 
     const loadData = async () => {
@@ -0,0 +1,70 @@
+import React, { useState } from 'react';
+import { authService } from '../services/api';
+import { Phone, KeyRound } from 'lucide-react';
+
+const LoginPage = ({ onLogin }) => {
+    const [apiKey, setApiKey] = useState('');
+    const [error, setError] = useState('');
+    const [submitting, setSubmitting] = useState(false);
+
+    const handleSubmit = async (e) => {
+        e.preventDefault();
+        setError('');
+        setSubmitting(true);
+        try {
+            await authService.login(apiKey.trim());
+            setApiKey('');
+            onLogin();
+        } catch (err) {
+            setError(err.response?.data?.error || 'Failed to sign in');
+        } finally {
+            setSubmitting(false);
+        }
+    };
+
+    return (
+        <div className="flex items-center justify-center min-h-screen bg-gray-50">
+            <div className="w-full max-w-md bg-white rounded-lg shadow-md p-8">
+                <div className="flex items-center gap-3 mb-6">
+                    <div className="p-2 bg-blue-600 rounded-lg text-white">
+                        <Phone size={24} />
+                    </div>
+                    <div>
+                        <h1 className="text-xl font-bold text-gray-900">IVR System</h1>
+                        <p className="text-sm text-gray-500">Sign in with your API key</p>
+                    </div>
+                </div>
+
+                <form onSubmit={handleSubmit} className="space-y-4">
+                    <div>
+                        <label className="block text-sm font-medium text-gray-700 mb-2">API Key</label>
+                        <input
+                            type="password"
+                            value={apiKey}
+                            onChange={(e) => setApiKey(e.target.value)}
+                            className="w-full px-4 py-2 border border-gray-300 rounded-lg focus:ring-2 focus:ring-blue-500 focus:border-transparent"
+                            autoComplete="off"
+                            required
+                        />
+                        <p className="text-xs text-gray-500 mt-1">
+                            The key is only used to get a session token and is not stored.
+                        </p>
+                    </div>
+
+                    {error && <div className="text-sm text-red-600">{error}</div>}
+
+                    <button
+                        type="submit"
+                        disabled={submitting || !apiKey.trim()}
+                        className="w-full flex items-center justify-center gap-2 px-4 py-2 bg-blue-600 text-white rounded-lg hover:bg-blue-700 transition disabled:opacity-50"
+                    >
+                        <KeyRound size={18} />
+                        {submitting ? 'Signing in...' : 'Sign In'}
+                    </button>
+                </form>
+            </div>
+        </div>
+    );
+};
+
+export default LoginPage;
@@ -2,6 +2,9 @@ import axios from 'axios';
 
 const API_BASE_URL = import.meta.env.VITE_API_URL || 'http://localhost:8080/api';
 
+// Only the short-lived bearer token is kept, for this browser tab; API keys are never stored
+const TOKEN_KEY = 'ivr_access_token';
+
 const api = axios.create({
     baseURL: API_BASE_URL,
     headers: {
@@ -9,6 +12,49 @@ const api = axios.create({
     },
 });
 
+api.interceptors.request.use((config) => {
+    const token = sessionStorage.getItem(TOKEN_KEY);
+    if (token) {
+        config.headers.Authorization = `Bearer ${token}`;
+    }
+    return config;
+});
+
+// An expired or revoked token sends the user back to the login screen
+api.interceptors.response.use(
+    (response) => response,
+    (error) => {
+        if (error.response?.status === 401) {
+            authService.logout();
+        }
+        return Promise.reject(error);
+    }
+);
+
+// Auth APIs
+export const authService = {
+    // Exchange an API key for a bearer token
+    login: async (apiKey) => {
+        const response = await axios.post(`${API_BASE_URL}/auth/token`, null, {
+            headers: { 'X-API-Key': apiKey },
+        });
+        sessionStorage.setItem(TOKEN_KEY, response.data.token);
+        return response.data;
+    },
+
+    // Forget the token and tell the app to show the login screen
+    logout: () => {
+        sessionStorage.removeItem(TOKEN_KEY);
+        window.dispatchEvent(new Event('auth:logout'));
+    },
+
+    // Get the signed-in principal; fails with 401 when a login is needed
+    getCurrentPrincipal: async () => {
+        const response = await api.get('/auth/me');
+        return response.data;
+    },
+};
+
 // Campaign APIs
 export const campaignService = {
     // Get all campaigns
@@ -46,6 +92,23 @@ export const campaignService = {
         const response = await api.get(`/campaigns/${id}/calls`);
         return response.data;
     },
+
+    // Subscribe to live call events (Server-Sent Events). Returns a function that closes the stream.
+    subscribeToEvents: (id, onEvent) => {
+        const url = new URL(`${API_BASE_URL}/campaigns/${id}/events`, window.location.origin);
+        const token = sessionStorage.getItem(TOKEN_KEY);
+        if (token) {
+            url.searchParams.set('access_token', token);
+        }
+
+        const source = new EventSource(url.toString());
+        const eventTypes = ['initiated', 'scheduled', 'ringing', 'answered', 'digit_pressed', 'completed', 'failed', 'retry_scheduled'];
+        eventTypes.forEach((type) => {
+            source.addEventListener(type, (event) => onEvent(JSON.parse(event.data)));
+        });
+
+        return () => source.close();
+    },
 };
 
 // Call APIs
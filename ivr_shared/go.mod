@@ -0,0 +1,3 @@
+module github.com/prabhatkumar/ivrshared
+
+go 1.21
@@ -0,0 +1,338 @@
+package phone
+
+// country holds the numbering rules of a calling code
+type country struct {
+	code        string
+	regions     []string // ISO 3166 regions sharing the calling code, the first one is reported
+	trunkPrefix string   // dialed before national numbers, stripped from E.164
+	rules       []rule   // checked in order, so specific prefixes come before general ones
+}
+
+// rule describes a range of national significant numbers
+type rule struct {
+	numberType NumberType
+	lengths    []int
+	prefixes   []string
+}
+
+// span returns the lengths from min to max inclusive
+func span(from, to int) []int {
+	lengths := make([]int, 0, to-from+1)
+	for length := from; length <= to; length++ {
+		lengths = append(lengths, length)
+	}
+	return lengths
+}
+
+var (
+	digits1to9 = []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"}
+	digits2to9 = []string{"2", "3", "4", "5", "6", "7", "8", "9"}
+)
+
+// countries lists the numbering plans the service knows. Calling codes of
+// other countries are accepted with the E.164 length limits only.
+var countries = []*country{
+	{code: "1", regions: []string{"US", "CA"}, trunkPrefix: "1", rules: []rule{
+		{TypeTollFree, []int{10}, []string{"800", "833", "844", "855", "866", "877", "888"}},
+		{TypePremiumRate, []int{10}, []string{"900", "976"}},
+		{TypeFixedOrMobile, []int{10}, digits2to9},
+	}},
+	{code: "7", regions: []string{"RU", "KZ"}, trunkPrefix: "8", rules: []rule{
+		{TypeTollFree, []int{10}, []string{"800"}},
+		{TypePremiumRate, []int{10}, []string{"809"}},
+		{TypeMobile, []int{10}, []string{"9", "70", "77"}},
+		{TypeFixedLine, []int{10}, []string{"3", "4", "6", "7", "8"}},
+	}},
+	{code: "20", regions: []string{"EG"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{10}, []string{"10", "11", "12", "15"}},
+		{TypeTollFree, []int{10}, []string{"800"}},
+		{TypePremiumRate, []int{10}, []string{"900"}},
+		{TypeFixedLine, []int{8, 9}, []string{"2", "3", "4", "5", "6", "8", "9"}},
+	}},
+	{code: "27", regions: []string{"ZA"}, trunkPrefix: "0", rules: []rule{
+		{TypeTollFree, []int{9}, []string{"80"}},
+		{TypePremiumRate, []int{9}, []string{"90"}},
+		{TypeMobile, []int{9}, []string{"6", "7", "81", "82", "83", "84"}},
+		{TypeFixedLine, []int{9}, []string{"1", "2", "3", "4", "5"}},
+	}},
+	{code: "30", regions: []string{"GR"}, rules: []rule{
+		{TypeMobile, []int{10}, []string{"69"}},
+		{TypeTollFree, []int{10}, []string{"800"}},
+		{TypePremiumRate, []int{10}, []string{"90"}},
+		{TypeFixedLine, []int{10}, []string{"2"}},
+	}},
+	{code: "31", regions: []string{"NL"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{9}, []string{"6"}},
+		{TypeTollFree, span(7, 10), []string{"800"}},
+		{TypePremiumRate, span(7, 10), []string{"900", "906", "909"}},
+		{TypeFixedLine, []int{9}, []string{"1", "2", "3", "4", "5", "7", "8"}},
+	}},
+	{code: "32", regions: []string{"BE"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{9}, []string{"46", "47", "48", "49"}},
+		{TypeTollFree, []int{8}, []string{"800"}},
+		{TypePremiumRate, []int{8}, []string{"90"}},
+		{TypeFixedLine, []int{8}, digits1to9},
+	}},
+	{code: "33", regions: []string{"FR"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{9}, []string{"6", "7"}},
+		{TypeTollFree, []int{9}, []string{"80"}},
+		{TypePremiumRate, []int{9}, []string{"89"}},
+		{TypeFixedLine, []int{9}, []string{"1", "2", "3", "4", "5", "9"}},
+	}},
+	{code: "34", regions: []string{"ES"}, rules: []rule{
+		{TypeMobile, []int{9}, []string{"6", "7"}},
+		{TypeTollFree, []int{9}, []string{"800", "900"}},
+		{TypePremiumRate, []int{9}, []string{"80", "90"}},
+		{TypeFixedLine, []int{9}, []string{"8", "9"}},
+	}},
+	// Italian fixed-line numbers keep their leading 0, so there is no trunk prefix
+	{code: "39", regions: []string{"IT"}, rules: []rule{
+		{TypeMobile, []int{9, 10}, []string{"3"}},
+		{TypeTollFree, []int{6, 9}, []string{"80"}},
+		{TypePremiumRate, []int{6, 9, 10}, []string{"89"}},
+		{TypeFixedLine, span(6, 11), []string{"0"}},
+	}},
+	{code: "41", regions: []string{"CH"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{9}, []string{"75", "76", "77", "78", "79"}},
+		{TypeTollFree, []int{9}, []string{"800"}},
+		{TypePremiumRate, []int{9}, []string{"90"}},
+		{TypeFixedLine, []int{9}, []string{"2", "3", "4", "5", "6", "7", "8"}},
+	}},
+	{code: "43", regions: []string{"AT"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, span(10, 13), []string{"6"}},
+		{TypeTollFree, span(9, 13), []string{"800"}},
+		{TypePremiumRate, span(9, 13), []string{"9"}},
+		{TypeFixedLine, span(4, 13), []string{"1", "2", "3", "4", "5", "7"}},
+	}},
+	{code: "44", regions: []string{"GB"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{10}, []string{"71", "72", "73", "74", "75", "77", "78", "79"}},
+		{TypeTollFree, []int{9, 10}, []string{"800", "808"}},
+		{TypePremiumRate, []int{10}, []string{"9"}},
+		{TypeFixedLine, []int{9, 10}, []string{"1", "2", "3", "5"}},
+	}},
+	{code: "45", regions: []string{"DK"}, rules: []rule{
+		{TypeTollFree, []int{8}, []string{"80"}},
+		{TypePremiumRate, []int{8}, []string{"90"}},
+		{TypeMobile, []int{8}, []string{"2", "30", "31", "40", "41", "42", "50", "51", "52", "53", "60", "61", "71", "81", "91", "92", "93"}},
+		{TypeFixedLine, []int{8}, []string{"3", "4", "5", "6", "7", "8", "9"}},
+	}},
+	{code: "46", regions: []string{"SE"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{9}, []string{"70", "72", "73", "76", "79"}},
+		{TypeTollFree, span(7, 9), []string{"20"}},
+		{TypePremiumRate, span(7, 10), []string{"900", "939", "944"}},
+		{TypeFixedLine, span(7, 9), []string{"1", "2", "3", "4", "5", "6", "8", "9"}},
+	}},
+	{code: "47", regions: []string{"NO"}, rules: []rule{
+		{TypeMobile, []int{8}, []string{"4", "9"}},
+		{TypeTollFree, []int{8}, []string{"80"}},
+		{TypePremiumRate, []int{8}, []string{"82"}},
+		{TypeFixedLine, []int{8}, []string{"2", "3", "5", "6", "7"}},
+	}},
+	{code: "48", regions: []string{"PL"}, rules: []rule{
+		{TypeMobile, []int{9}, []string{"45", "50", "51", "53", "57", "60", "66", "69", "72", "73", "78", "79", "88"}},
+		{TypeTollFree, []int{9}, []string{"800"}},
+		{TypePremiumRate, []int{9}, []string{"70"}},
+		{TypeFixedLine, []int{9}, digits1to9},
+	}},
+	{code: "49", regions: []string{"DE"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{10, 11}, []string{"15", "16", "17"}},
+		{TypeTollFree, []int{10}, []string{"800"}},
+		{TypePremiumRate, []int{10, 11}, []string{"900"}},
+		{TypeFixedLine, span(5, 11), digits2to9},
+	}},
+	{code: "52", regions: []string{"MX"}, rules: []rule{
+		{TypeTollFree, []int{10}, []string{"800"}},
+		{TypePremiumRate, []int{10}, []string{"900"}},
+		{TypeFixedOrMobile, []int{10}, digits2to9},
+	}},
+	{code: "54", regions: []string{"AR"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{11}, []string{"9"}},
+		{TypeTollFree, []int{10}, []string{"800"}},
+		{TypePremiumRate, []int{10}, []string{"6"}},
+		{TypeFixedLine, []int{10}, []string{"1", "2", "3"}},
+	}},
+	// Brazilian mobile numbers have a ninth digit after the area code
+	{code: "55", regions: []string{"BR"}, trunkPrefix: "0", rules: []rule{
+		{TypeTollFree, []int{10}, []string{"800"}},
+		{TypePremiumRate, []int{10}, []string{"900"}},
+		{TypeMobile, []int{11}, digits1to9},
+		{TypeFixedLine, []int{10}, digits1to9},
+	}},
+	{code: "56", regions: []string{"CL"}, rules: []rule{
+		{TypeMobile, []int{9}, []string{"9"}},
+		{TypeTollFree, []int{9}, []string{"800"}},
+		{TypeFixedLine, []int{9}, []string{"2", "3", "4", "5", "6", "7"}},
+	}},
+	{code: "57", regions: []string{"CO"}, rules: []rule{
+		{TypeMobile, []int{10}, []string{"3"}},
+		{TypeTollFree, []int{11}, []string{"1800"}},
+		{TypePremiumRate, []int{11}, []string{"1900"}},
+		{TypeFixedLine, []int{10}, []string{"60"}},
+	}},
+	{code: "60", regions: []string{"MY"}, trunkPrefix: "0", rules: []rule{
+		{TypeTollFree, []int{9, 10}, []string{"1800"}},
+		{TypePremiumRate, []int{9, 10}, []string{"1600"}},
+		{TypeMobile, []int{9, 10}, []string{"1"}},
+		{TypeFixedLine, []int{8, 9}, []string{"3", "4", "5", "6", "7", "8", "9"}},
+	}},
+	{code: "61", regions: []string{"AU"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{9}, []string{"4"}},
+		{TypeTollFree, []int{10}, []string{"1800"}},
+		{TypePremiumRate, []int{10}, []string{"190"}},
+		{TypeFixedLine, []int{9}, []string{"2", "3", "7", "8"}},
+	}},
+	{code: "62", regions: []string{"ID"}, trunkPrefix: "0", rules: []rule{
+		{TypeTollFree, []int{9, 10}, []string{"800"}},
+		{TypePremiumRate, []int{9, 10}, []string{"809"}},
+		{TypeMobile, span(9, 12), []string{"8"}},
+		{TypeFixedLine, span(7, 11), []string{"2", "3", "4", "5", "6", "7", "9"}},
+	}},
+	{code: "63", regions: []string{"PH"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{10}, []string{"9"}},
+		{TypeTollFree, []int{10, 11}, []string{"1800"}},
+		{TypeFixedLine, []int{8, 9}, []string{"2", "3", "4", "5", "6", "7", "8"}},
+	}},
+	{code: "64", regions: []string{"NZ"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, span(8, 10), []string{"2"}},
+		{TypeTollFree, []int{8, 9}, []string{"800", "508"}},
+		{TypePremiumRate, []int{8, 9}, []string{"900"}},
+		{TypeFixedLine, []int{8}, []string{"3", "4", "6", "7", "9"}},
+	}},
+	{code: "65", regions: []string{"SG"}, rules: []rule{
+		{TypeMobile, []int{8}, []string{"8", "9"}},
+		{TypeTollFree, []int{11}, []string{"1800"}},
+		{TypePremiumRate, []int{11}, []string{"1900"}},
+		{TypeFixedLine, []int{8}, []string{"6"}},
+	}},
+	{code: "66", regions: []string{"TH"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{9}, []string{"6", "8", "9"}},
+		{TypeTollFree, []int{10}, []string{"1800"}},
+		{TypePremiumRate, []int{10}, []string{"1900"}},
+		{TypeFixedLine, []int{8}, []string{"2", "3", "4", "5", "7"}},
+	}},
+	{code: "81", regions: []string{"JP"}, trunkPrefix: "0", rules: []rule{
+		{TypeTollFree, []int{9}, []string{"120"}},
+		{TypeTollFree, []int{10}, []string{"800"}},
+		{TypePremiumRate, []int{9}, []string{"990"}},
+		{TypeMobile, []int{10}, []string{"70", "80", "90"}},
+		{TypeFixedLine, []int{9}, digits1to9},
+	}},
+	{code: "82", regions: []string{"KR"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{9, 10}, []string{"10", "11", "16", "17", "18", "19"}},
+		{TypeTollFree, []int{9, 10}, []string{"80"}},
+		{TypePremiumRate, []int{9, 10}, []string{"60"}},
+		{TypeFixedLine, []int{8, 9}, []string{"2"}},
+		{TypeFixedLine, []int{9, 10}, []string{"3", "4", "5", "6"}},
+	}},
+	{code: "84", regions: []string{"VN"}, trunkPrefix: "0", rules: []rule{
+		{TypeTollFree, []int{8, 10}, []string{"1800"}},
+		{TypePremiumRate, []int{8, 10}, []string{"1900"}},
+		{TypeMobile, []int{9}, []string{"3", "5", "7", "8", "9"}},
+		{TypeFixedLine, []int{10}, []string{"2"}},
+	}},
+	{code: "86", regions: []string{"CN"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{11}, []string{"13", "14", "15", "16", "17", "18", "19"}},
+		{TypeTollFree, []int{10}, []string{"800"}},
+		{TypeFixedLine, []int{10}, []string{"2"}},
+		{TypeFixedLine, []int{10, 11}, []string{"3", "4", "5", "6", "7", "8", "9"}},
+	}},
+	{code: "90", regions: []string{"TR"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{10}, []string{"5"}},
+		{TypeTollFree, []int{10}, []string{"800"}},
+		{TypePremiumRate, []int{10}, []string{"900"}},
+		{TypeFixedLine, []int{10}, []string{"2", "3", "4"}},
+	}},
+	{code: "91", regions: []string{"IN"}, trunkPrefix: "0", rules: []rule{
+		{TypeTollFree, []int{10, 11}, []string{"1800"}},
+		{TypePremiumRate, []int{10, 11}, []string{"1900"}},
+		{TypeMobile, []int{10}, []string{"6", "7", "8", "9"}},
+		{TypeFixedLine, []int{10}, []string{"1", "2", "3", "4", "5"}},
+	}},
+	{code: "92", regions: []string{"PK"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{10}, []string{"3"}},
+		{TypeTollFree, []int{8}, []string{"800"}},
+		{TypePremiumRate, []int{8}, []string{"900"}},
+		{TypeFixedLine, []int{9, 10}, []string{"2", "4", "5", "6", "7", "8", "9"}},
+	}},
+	{code: "94", regions: []string{"LK"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{9}, []string{"7"}},
+		{TypeFixedLine, []int{9}, []string{"1", "2", "3", "4", "5", "6", "8", "9"}},
+	}},
+	{code: "234", regions: []string{"NG"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{10}, []string{"70", "80", "81", "90", "91"}},
+		{TypeTollFree, []int{10}, []string{"800"}},
+		{TypeFixedLine, []int{8, 9}, digits1to9},
+	}},
+	{code: "254", regions: []string{"KE"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{9}, []string{"1", "7"}},
+		{TypeTollFree, []int{9}, []string{"800"}},
+		{TypePremiumRate, []int{9}, []string{"900"}},
+		{TypeFixedLine, []int{9}, []string{"2", "4", "5", "6"}},
+	}},
+	{code: "351", regions: []string{"PT"}, rules: []rule{
+		{TypeMobile, []int{9}, []string{"9"}},
+		{TypeTollFree, []int{9}, []string{"800"}},
+		{TypePremiumRate, []int{9}, []string{"6"}},
+		{TypeFixedLine, []int{9}, []string{"2"}},
+	}},
+	{code: "353", regions: []string{"IE"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{9}, []string{"83", "85", "86", "87", "89"}},
+		{TypeTollFree, []int{10}, []string{"1800"}},
+		{TypePremiumRate, []int{10}, []string{"15"}},
+		{TypeFixedLine, span(7, 9), []string{"1", "2", "4", "5", "6", "7", "9"}},
+	}},
+	{code: "880", regions: []string{"BD"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{10}, []string{"1"}},
+		{TypeFixedLine, span(6, 10), digits2to9},
+	}},
+	{code: "966", regions: []string{"SA"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{9}, []string{"5"}},
+		{TypeTollFree, []int{10}, []string{"800"}},
+		{TypeFixedLine, []int{9}, []string{"1"}},
+	}},
+	{code: "971", regions: []string{"AE"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{9}, []string{"5"}},
+		{TypeTollFree, span(5, 12), []string{"800"}},
+		{TypePremiumRate, []int{9}, []string{"900"}},
+		{TypeFixedLine, []int{8}, []string{"2", "3", "4", "6", "7", "9"}},
+	}},
+	{code: "977", regions: []string{"NP"}, trunkPrefix: "0", rules: []rule{
+		{TypeMobile, []int{10}, []string{"97", "98"}},
+		{TypeFixedLine, []int{7, 8}, digits1to9},
+	}},
+}
+
+// otherCallingCodes are the calling codes in use by countries without rules
+var otherCallingCodes = []string{
+	"36", "40", "51", "53", "58", "95", "98",
+	"211", "212", "213", "216", "218", "220", "221", "222", "223", "224", "225", "226", "227",
+	"228", "229", "230", "231", "232", "233", "235", "236", "237", "238", "239", "240", "241",
+	"242", "243", "244", "245", "246", "248", "249", "250", "251", "252", "253", "255", "256",
+	"257", "258", "260", "261", "262", "263", "264", "265", "266", "267", "268", "269", "290",
+	"291", "297", "298", "299", "350", "352", "354", "355", "356", "357", "358", "359", "370",
+	"371", "372", "373", "374", "375", "376", "377", "378", "380", "381", "382", "383", "385",
+	"386", "387", "389", "420", "421", "423", "500", "501", "502", "503", "504", "505", "506",
+	"507", "508", "509", "590", "591", "592", "593", "594", "595", "596", "597", "598", "599",
+	"670", "672", "673", "674", "675", "676", "677", "678", "679", "680", "681", "682", "683",
+	"685", "686", "687", "688", "689", "690", "691", "692", "850", "852", "853", "855", "856",
+	"886", "960", "961", "962", "963", "964", "965", "967", "968", "970", "972", "973", "974",
+	"975", "976", "992", "993", "994", "995", "996", "998",
+}
+
+var (
+	countriesByCode      = map[string]*country{}
+	countriesByRegion    = map[string]*country{}
+	assignedCallingCodes = map[string]bool{}
+)
+
+func init() {
+	for _, code := range otherCallingCodes {
+		assignedCallingCodes[code] = true
+	}
+	for _, c := range countries {
+		countriesByCode[c.code] = c
+		for _, region := range c.regions {
+			countriesByRegion[region] = c
+		}
+	}
+}
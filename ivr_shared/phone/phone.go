@@ -0,0 +1,239 @@
+// Package phone parses phone numbers into E.164 format and validates them
+// against per-country length and prefix rules.
+package phone
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NumberType is the kind of line a phone number belongs to
+type NumberType string
+
+const (
+	TypeUnknown       NumberType = "unknown"
+	TypeFixedLine     NumberType = "fixed_line"
+	TypeMobile        NumberType = "mobile"
+	TypeFixedOrMobile NumberType = "fixed_line_or_mobile" // countries where the number does not tell
+	TypeTollFree      NumberType = "toll_free"
+	TypePremiumRate   NumberType = "premium_rate"
+)
+
+const (
+	maxE164Digits       = 15
+	minNationalDigits   = 4
+	internationalPrefix = "00"
+)
+
+// Number is a parsed phone number
+type Number struct {
+	E164        string     `json:"e164"`         // e.g. +14155550100
+	CountryCode string     `json:"country_code"` // calling code without "+", e.g. 1
+	Region      string     `json:"region"`       // ISO 3166 country, empty when the country has no rules
+	National    string     `json:"national"`     // national significant number
+	Type        NumberType `json:"type"`
+}
+
+// Error explains why a phone number is invalid
+type Error struct {
+	Input  string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid phone number '%s': %s", e.Input, e.Reason)
+}
+
+// Parse parses a phone number to E.164. Numbers without a leading "+" (or
+// "00") are read as national numbers of defaultRegion, an ISO 3166 country
+// code such as "US"; without a default region they must be international.
+func Parse(raw string, defaultRegion string) (*Number, error) {
+	input := strings.TrimSpace(raw)
+	if input == "" {
+		return nil, &Error{Input: raw, Reason: "number is empty"}
+	}
+
+	digits, international, err := stripFormatting(input)
+	if err != nil {
+		return nil, &Error{Input: raw, Reason: err.Error()}
+	}
+	if !international && strings.HasPrefix(digits, internationalPrefix) {
+		digits, international = digits[len(internationalPrefix):], true
+	}
+	if len(digits) > maxE164Digits+1 {
+		return nil, &Error{Input: raw, Reason: "too many digits"}
+	}
+
+	if international {
+		return parseInternational(raw, digits)
+	}
+
+	if defaultRegion == "" {
+		return nil, &Error{Input: raw, Reason: "missing country code (use the +<country code> format)"}
+	}
+	c, ok := countriesByRegion[strings.ToUpper(defaultRegion)]
+	if !ok {
+		return nil, &Error{Input: raw, Reason: fmt.Sprintf("unsupported region '%s'", defaultRegion)}
+	}
+	return parseNational(raw, digits, c)
+}
+
+// Normalize returns the E.164 form of a phone number
+func Normalize(raw string, defaultRegion string) (string, error) {
+	number, err := Parse(raw, defaultRegion)
+	if err != nil {
+		return "", err
+	}
+	return number.E164, nil
+}
+
+// ParseDialable parses a number that may be called by the service.
+// Premium-rate numbers are rejected to avoid unexpected call charges.
+func ParseDialable(raw string, defaultRegion string) (*Number, error) {
+	number, err := Parse(raw, defaultRegion)
+	if err != nil {
+		return nil, err
+	}
+	if number.Type == TypePremiumRate {
+		return nil, &Error{Input: raw, Reason: "premium-rate numbers cannot be called"}
+	}
+	return number, nil
+}
+
+// IsValid reports whether an international number can be parsed
+func IsValid(raw string) bool {
+	_, err := Parse(raw, "")
+	return err == nil
+}
+
+// stripFormatting removes spaces and punctuation, rejecting anything that
+// cannot be part of a phone number
+func stripFormatting(input string) (string, bool, error) {
+	var b strings.Builder
+	international := false
+	for i, r := range input {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' && i == 0:
+			international = true
+		case strings.ContainsRune(" -.()/\t", r):
+		default:
+			return "", false, fmt.Errorf("unexpected character '%c'", r)
+		}
+	}
+	if b.Len() == 0 {
+		return "", false, fmt.Errorf("no digits")
+	}
+	return b.String(), international, nil
+}
+
+func parseInternational(raw, digits string) (*Number, error) {
+	// Calling codes are prefix-free, so the first match wins
+	for length := 1; length <= 3 && length < len(digits); length++ {
+		code := digits[:length]
+		if c, ok := countriesByCode[code]; ok {
+			national := digits[length:]
+			// Tolerate a trunk prefix written after the country code, as in +44 (0)20 ...
+			if c.trunkPrefix != "" && strings.HasPrefix(national, c.trunkPrefix) {
+				if _, err := c.classify(national); err != nil {
+					if _, err := c.classify(national[len(c.trunkPrefix):]); err == nil {
+						national = national[len(c.trunkPrefix):]
+					}
+				}
+			}
+			return c.build(raw, national)
+		}
+		if assignedCallingCodes[code] {
+			return parseUnlisted(raw, code, digits[length:])
+		}
+	}
+	return nil, &Error{Input: raw, Reason: "unknown country calling code"}
+}
+
+func parseNational(raw, digits string, c *country) (*Number, error) {
+	if c.trunkPrefix != "" && strings.HasPrefix(digits, c.trunkPrefix) {
+		if _, err := c.classify(digits[len(c.trunkPrefix):]); err == nil {
+			return c.build(raw, digits[len(c.trunkPrefix):])
+		}
+	}
+	// The number may already carry the country code without a "+"
+	if strings.HasPrefix(digits, c.code) {
+		if _, err := c.classify(digits); err != nil {
+			if _, err := c.classify(digits[len(c.code):]); err == nil {
+				return c.build(raw, digits[len(c.code):])
+			}
+		}
+	}
+	return c.build(raw, digits)
+}
+
+// parseUnlisted accepts numbers of countries without rules using the E.164 length limits only
+func parseUnlisted(raw, code, national string) (*Number, error) {
+	switch {
+	case len(national) < minNationalDigits:
+		return nil, &Error{Input: raw, Reason: "too short"}
+	case len(code)+len(national) > maxE164Digits:
+		return nil, &Error{Input: raw, Reason: "too long"}
+	}
+	return &Number{
+		E164:        "+" + code + national,
+		CountryCode: code,
+		National:    national,
+		Type:        TypeUnknown,
+	}, nil
+}
+
+func (c *country) build(raw, national string) (*Number, error) {
+	numberType, err := c.classify(national)
+	if err != nil {
+		return nil, &Error{Input: raw, Reason: err.Error()}
+	}
+	return &Number{
+		E164:        "+" + c.code + national,
+		CountryCode: c.code,
+		Region:      c.regions[0],
+		National:    national,
+		Type:        numberType,
+	}, nil
+}
+
+// classify finds the rule matching the national number's prefix and length.
+// When prefixes match but no length does, the error says which way it is off.
+func (c *country) classify(national string) (NumberType, error) {
+	prefixMatched := false
+	shortest, longest := maxE164Digits, 0
+	for _, r := range c.rules {
+		if !r.matchesPrefix(national) {
+			continue
+		}
+		prefixMatched = true
+		for _, length := range r.lengths {
+			if length == len(national) {
+				return r.numberType, nil
+			}
+			shortest = min(shortest, length)
+			longest = max(longest, length)
+		}
+	}
+
+	switch {
+	case !prefixMatched:
+		return "", fmt.Errorf("not a valid number in %s", c.regions[0])
+	case len(national) < shortest:
+		return "", fmt.Errorf("too short for %s", c.regions[0])
+	case len(national) > longest:
+		return "", fmt.Errorf("too long for %s", c.regions[0])
+	default:
+		return "", fmt.Errorf("wrong length for %s", c.regions[0])
+	}
+}
+
+func (r rule) matchesPrefix(national string) bool {
+	for _, prefix := range r.prefixes {
+		if strings.HasPrefix(national, prefix) {
+			return true
+		}
+	}
+	return false
+}
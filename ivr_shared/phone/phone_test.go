@@ -0,0 +1,137 @@
+package phone
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name          string
+		raw           string
+		defaultRegion string
+		e164          string
+		region        string
+		numberType    NumberType
+	}{
+		{"US international", "+1 (415) 555-0100", "", "+14155550100", "US", TypeFixedOrMobile},
+		{"US national", "415.555.0100", "US", "+14155550100", "US", TypeFixedOrMobile},
+		{"US national with trunk prefix", "1 415 555 0100", "us", "+14155550100", "US", TypeFixedOrMobile},
+		{"US toll free", "+1 800 555 0100", "", "+18005550100", "US", TypeTollFree},
+		{"00 international prefix", "0044 20 7946 0958", "", "+442079460958", "GB", TypeFixedLine},
+		{"GB trunk prefix after country code", "+44 (0)20 7946 0958", "", "+442079460958", "GB", TypeFixedLine},
+		{"GB national mobile", "07700 900123", "GB", "+447700900123", "GB", TypeMobile},
+		{"IN mobile", "+91 98765 43210", "", "+919876543210", "IN", TypeMobile},
+		{"IN national with country code", "919876543210", "IN", "+919876543210", "IN", TypeMobile},
+		{"DE mobile", "+49 151 23456789", "", "+4915123456789", "DE", TypeMobile},
+		{"IT fixed line keeps its 0", "+39 06 1234 5678", "", "+390612345678", "IT", TypeFixedLine},
+		{"country without rules", "+36 1 234 5678", "", "+3612345678", "", TypeUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			number, err := Parse(tt.raw, tt.defaultRegion)
+			if err != nil {
+				t.Fatalf("Parse(%q, %q) returned error: %v", tt.raw, tt.defaultRegion, err)
+			}
+			if number.E164 != tt.e164 {
+				t.Errorf("E164 = %q, want %q", number.E164, tt.e164)
+			}
+			if number.Region != tt.region {
+				t.Errorf("Region = %q, want %q", number.Region, tt.region)
+			}
+			if number.Type != tt.numberType {
+				t.Errorf("Type = %q, want %q", number.Type, tt.numberType)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		raw           string
+		defaultRegion string
+		reason        string
+	}{
+		{"empty", "  ", "", "number is empty"},
+		{"letters", "+1 415 CALL NOW", "", "unexpected character"},
+		{"plus inside the number", "1+4155550100", "", "unexpected character"},
+		{"no digits", "+()", "", "no digits"},
+		{"national without region", "4155550100", "", "missing country code"},
+		{"unsupported region", "4155550100", "XX", "unsupported region"},
+		{"unknown calling code", "+999 1234 5678", "", "unknown country calling code"},
+		{"too many digits", "+1234567890123456789", "", "too many digits"},
+		{"too short", "+1 415 555", "", "too short for US"},
+		{"too long", "+44 7700 9001234", "", "too long for GB"},
+		{"invalid prefix", "+1 015 555 0100", "", "not a valid number in US"},
+		{"unlisted country too short", "+36 123", "", "too short"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.raw, tt.defaultRegion)
+			var phoneErr *Error
+			if !errors.As(err, &phoneErr) {
+				t.Fatalf("Parse(%q, %q) error = %v, want *Error", tt.raw, tt.defaultRegion, err)
+			}
+			if !strings.Contains(phoneErr.Reason, tt.reason) {
+				t.Errorf("Reason = %q, want it to contain %q", phoneErr.Reason, tt.reason)
+			}
+			if phoneErr.Input != tt.raw {
+				t.Errorf("Input = %q, want %q", phoneErr.Input, tt.raw)
+			}
+		})
+	}
+}
+
+func TestParseDialable(t *testing.T) {
+	tests := []struct {
+		raw     string
+		wantErr bool
+	}{
+		{"+14155550100", false},
+		{"+18005550100", false},
+		{"+19005550100", true},
+		{"+447700900123", false},
+		{"+44 909 879 0000", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			_, err := ParseDialable(tt.raw, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseDialable(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeAndIsValid(t *testing.T) {
+	tests := []struct {
+		raw   string
+		e164  string
+		valid bool
+	}{
+		{"+1 415 555 0100", "+14155550100", true},
+		{"00 91 98765 43210", "+919876543210", true},
+		{"4155550100", "", false},
+		{"not a number", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			e164, err := Normalize(tt.raw, "")
+			if (err == nil) != tt.valid {
+				t.Fatalf("Normalize(%q) error = %v, want valid %v", tt.raw, err, tt.valid)
+			}
+			if e164 != tt.e164 {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.raw, e164, tt.e164)
+			}
+			if got := IsValid(tt.raw); got != tt.valid {
+				t.Errorf("IsValid(%q) = %v, want %v", tt.raw, got, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+// Package signature validates the X-Twilio-Signature header Twilio sends with
+// every webhook request.
+// See https://www.twilio.com/docs/usage/security#validating-requests
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Compute returns the signature of a request to the full public URL Twilio
+// called, query string included, with the given POST parameters: the base64
+// HMAC-SHA1, keyed with the auth token, of the URL followed by every parameter
+// name and value sorted by name. A name posted several times is added once per
+// distinct value, in value order, as Twilio's helper libraries do.
+func Compute(authToken string, rawURL string, params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var data strings.Builder
+	data.WriteString(rawURL)
+	for _, key := range keys {
+		values := append([]string(nil), params[key]...)
+		sort.Strings(values)
+		for i, value := range values {
+			if i > 0 && value == values[i-1] {
+				continue
+			}
+			data.WriteString(key)
+			data.WriteString(value)
+		}
+	}
+
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(data.String()))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Validate reports whether signature is the one Twilio computes for the
+// request. An empty auth token or signature never validates.
+func Validate(authToken string, rawURL string, params url.Values, signature string) bool {
+	if authToken == "" || signature == "" {
+		return false
+	}
+	expected := Compute(authToken, rawURL, params)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,73 @@
+package signature
+
+import (
+	"net/url"
+	"testing"
+)
+
+// The request from Twilio's documentation on validating requests
+const (
+	docAuthToken = "12345"
+	docURL       = "https://mycompany.com/myapp.php?foo=1&bar=2"
+	docSignature = "0/KCTR6DLpKmkAf8muzZqo1nDgQ="
+)
+
+func docParams() url.Values {
+	return url.Values{
+		"CallSid": {"CA1234567890ABCDE"},
+		"Caller":  {"+12349013030"},
+		"Digits":  {"1234"},
+		"From":    {"+12349013030"},
+		"To":      {"+18005551212"},
+	}
+}
+
+func TestCompute(t *testing.T) {
+	if got := Compute(docAuthToken, docURL, docParams()); got != docSignature {
+		t.Errorf("Compute = %s, want %s", got, docSignature)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tampered := docParams()
+	tampered.Set("Digits", "4321")
+
+	extra := docParams()
+	extra.Add("Digits", "5678")
+
+	tests := []struct {
+		name      string
+		authToken string
+		url       string
+		params    url.Values
+		signature string
+		want      bool
+	}{
+		{"documented example", docAuthToken, docURL, docParams(), docSignature, true},
+		{"wrong auth token", "54321", docURL, docParams(), docSignature, false},
+		{"other url", docAuthToken, "https://mycompany.com/myapp.php?foo=1&bar=3", docParams(), docSignature, false},
+		{"tampered parameter", docAuthToken, docURL, tampered, docSignature, false},
+		{"extra value of a parameter", docAuthToken, docURL, extra, docSignature, false},
+		{"missing signature", docAuthToken, docURL, docParams(), "", false},
+		{"missing auth token", "", docURL, docParams(), docSignature, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Validate(tt.authToken, tt.url, tt.params, tt.signature); got != tt.want {
+				t.Errorf("Validate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeMultipleValues(t *testing.T) {
+	// Values of a repeated name are signed in value order, each distinct value once
+	signed := Compute(docAuthToken, docURL, url.Values{"Key": {"b", "a", "b"}})
+	if want := Compute(docAuthToken, docURL, url.Values{"Key": {"a", "b"}}); signed != want {
+		t.Errorf("Compute of repeated values = %s, want %s", signed, want)
+	}
+	if first := Compute(docAuthToken, docURL, url.Values{"Key": {"a"}}); signed == first {
+		t.Error("Compute ignores all but the first value of a repeated name")
+	}
+}
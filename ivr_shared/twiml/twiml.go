@@ -0,0 +1,171 @@
+// Package twiml builds Twilio Markup Language responses. Every verb is a
+// struct marshalled through encoding/xml, so text and attribute values are
+// always escaped.
+package twiml
+
+import (
+	"encoding/xml"
+)
+
+// Verb is an element that can appear in a Response or be nested in a Gather
+type Verb interface {
+	verb()
+}
+
+// Response is the root element of a TwiML document
+type Response struct {
+	XMLName xml.Name `xml:"Response"`
+	Verbs   []Verb
+}
+
+// NewResponse creates a response with the given verbs
+func NewResponse(verbs ...Verb) *Response {
+	return &Response{Verbs: verbs}
+}
+
+// Add appends verbs to the response
+func (r *Response) Add(verbs ...Verb) *Response {
+	r.Verbs = append(r.Verbs, verbs...)
+	return r
+}
+
+// Marshal renders the response as an indented XML document
+func (r *Response) Marshal() ([]byte, error) {
+	body, err := xml.MarshalIndent(r, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// String renders the response. Marshalling can only fail for types outside
+// this package, which the Verb interface rules out; a hang-up is returned
+// just in case.
+func (r *Response) String() string {
+	body, err := r.Marshal()
+	if err != nil {
+		return xml.Header + "<Response><Hangup></Hangup></Response>"
+	}
+	return string(body)
+}
+
+// Say speaks text with text-to-speech. Text is escaped; SSML is written as
+// is and must be an already validated SSML fragment.
+type Say struct {
+	XMLName  xml.Name `xml:"Say"`
+	Voice    string   `xml:"voice,attr,omitempty"`
+	Language string   `xml:"language,attr,omitempty"`
+	Loop     int      `xml:"loop,attr,omitempty"`
+	Text     string   `xml:",chardata"`
+	SSML     string   `xml:",innerxml"`
+}
+
+// Play plays an audio file, or DTMF tones when Digits is set
+type Play struct {
+	XMLName xml.Name `xml:"Play"`
+	Loop    int      `xml:"loop,attr,omitempty"`
+	Digits  string   `xml:"digits,attr,omitempty"`
+	URL     string   `xml:",chardata"`
+}
+
+// Pause waits silently for Length seconds
+type Pause struct {
+	XMLName xml.Name `xml:"Pause"`
+	Length  int      `xml:"length,attr,omitempty"`
+}
+
+// Gather collects key presses or speech while playing its nested Say, Play and Pause verbs
+type Gather struct {
+	XMLName       xml.Name `xml:"Gather"`
+	Action        string   `xml:"action,attr,omitempty"`
+	Method        string   `xml:"method,attr,omitempty"`
+	Input         string   `xml:"input,attr,omitempty"` // dtmf, speech or "dtmf speech"
+	NumDigits     int      `xml:"numDigits,attr,omitempty"`
+	Timeout       int      `xml:"timeout,attr,omitempty"`
+	FinishOnKey   string   `xml:"finishOnKey,attr,omitempty"`
+	Language      string   `xml:"language,attr,omitempty"`
+	Hints         string   `xml:"hints,attr,omitempty"`
+	SpeechTimeout string   `xml:"speechTimeout,attr,omitempty"`
+	Verbs         []Verb
+}
+
+// Dial connects the call to another party, either Number or the nested Numbers
+type Dial struct {
+	XMLName                      xml.Name `xml:"Dial"`
+	Action                       string   `xml:"action,attr,omitempty"`
+	Method                       string   `xml:"method,attr,omitempty"`
+	Timeout                      int      `xml:"timeout,attr,omitempty"`
+	CallerID                     string   `xml:"callerId,attr,omitempty"`
+	Record                       string   `xml:"record,attr,omitempty"` // record-from-answer or record-from-answer-dual
+	RecordingStatusCallback      string   `xml:"recordingStatusCallback,attr,omitempty"`
+	RecordingStatusCallbackEvent string   `xml:"recordingStatusCallbackEvent,attr,omitempty"`
+	Number                       string   `xml:",chardata"`
+	Numbers                      []Number
+}
+
+// Number is a phone number nested in a Dial. URL is a TwiML document played
+// to the called party before the calls are connected.
+type Number struct {
+	XMLName xml.Name `xml:"Number"`
+	URL     string   `xml:"url,attr,omitempty"`
+	Method  string   `xml:"method,attr,omitempty"`
+	Value   string   `xml:",chardata"`
+}
+
+// Redirect continues the call with the TwiML at URL
+type Redirect struct {
+	XMLName xml.Name `xml:"Redirect"`
+	Method  string   `xml:"method,attr,omitempty"`
+	URL     string   `xml:",chardata"`
+}
+
+// Hangup ends the call
+type Hangup struct {
+	XMLName xml.Name `xml:"Hangup"`
+}
+
+// Record records the caller and posts the recording to Action
+type Record struct {
+	XMLName                 xml.Name `xml:"Record"`
+	Action                  string   `xml:"action,attr,omitempty"`
+	Method                  string   `xml:"method,attr,omitempty"`
+	Timeout                 int      `xml:"timeout,attr,omitempty"`
+	MaxLength               int      `xml:"maxLength,attr,omitempty"`
+	FinishOnKey             string   `xml:"finishOnKey,attr,omitempty"`
+	PlayBeep                string   `xml:"playBeep,attr,omitempty"`
+	Transcribe              bool     `xml:"transcribe,attr,omitempty"`
+	TranscribeCallback      string   `xml:"transcribeCallback,attr,omitempty"`
+	RecordingStatusCallback string   `xml:"recordingStatusCallback,attr,omitempty"`
+}
+
+// Start begins work that runs alongside the rest of the call, such as a Recording
+type Start struct {
+	XMLName   xml.Name `xml:"Start"`
+	Recording *Recording
+}
+
+// Recording records the call in the background from the point its Start is reached
+type Recording struct {
+	XMLName                       xml.Name `xml:"Recording"`
+	Channels                      string   `xml:"channels,attr,omitempty"` // mono or dual
+	RecordingStatusCallback       string   `xml:"recordingStatusCallback,attr,omitempty"`
+	RecordingStatusCallbackMethod string   `xml:"recordingStatusCallbackMethod,attr,omitempty"`
+	RecordingStatusCallbackEvent  string   `xml:"recordingStatusCallbackEvent,attr,omitempty"`
+}
+
+// Reject refuses an incoming call without answering it
+type Reject struct {
+	XMLName xml.Name `xml:"Reject"`
+	Reason  string   `xml:"reason,attr,omitempty"` // rejected or busy
+}
+
+func (Say) verb()      {}
+func (Play) verb()     {}
+func (Pause) verb()    {}
+func (Gather) verb()   {}
+func (Dial) verb()     {}
+func (Redirect) verb() {}
+func (Hangup) verb()   {}
+func (Record) verb()   {}
+func (Reject) verb()   {}
+func (Start) verb()    {}
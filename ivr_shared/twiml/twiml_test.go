@@ -0,0 +1,114 @@
+package twiml
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestResponseString(t *testing.T) {
+	tests := []struct {
+		name  string
+		verbs []Verb
+		want  string
+	}{
+		{
+			name:  "empty",
+			verbs: nil,
+			want:  `<Response></Response>`,
+		},
+		{
+			name:  "say escapes text",
+			verbs: []Verb{Say{Voice: "Polly.Joanna", Language: "en-US", Text: `Press 1 for "R&D" <now>`}},
+			want:  `<Say voice="Polly.Joanna" language="en-US">Press 1 for &#34;R&amp;D&#34; &lt;now&gt;</Say>`,
+		},
+		{
+			name:  "say writes SSML as is",
+			verbs: []Verb{Say{SSML: `Hello <break time="1s"/> there`}},
+			want:  `<Say>Hello <break time="1s"/> there</Say>`,
+		},
+		{
+			name:  "omitted attributes",
+			verbs: []Verb{Pause{}, Hangup{}},
+			want:  `<Pause></Pause>`,
+		},
+		{
+			name: "gather nests verbs",
+			verbs: []Verb{Gather{
+				Action:    "/api/webhook/gather",
+				Method:    "POST",
+				Input:     "dtmf speech",
+				NumDigits: 1,
+				Timeout:   5,
+				Verbs:     []Verb{Say{Text: "Press 1"}, Play{URL: "https://example.com/a.mp3"}},
+			}},
+			want: `<Gather action="/api/webhook/gather" method="POST" input="dtmf speech" numDigits="1" timeout="5">`,
+		},
+		{
+			name: "dial with recording callback",
+			verbs: []Verb{Dial{
+				CallerID:                     "+14155550100",
+				Record:                       "record-from-answer-dual",
+				RecordingStatusCallback:      "/api/webhook/recording",
+				RecordingStatusCallbackEvent: "in-progress completed absent",
+				Number:                       "+14155550199",
+			}},
+			want: `<Dial callerId="+14155550100" record="record-from-answer-dual" recordingStatusCallback="/api/webhook/recording" recordingStatusCallbackEvent="in-progress completed absent">+14155550199</Dial>`,
+		},
+		{
+			name:  "dial with whisper numbers",
+			verbs: []Verb{Dial{Numbers: []Number{{URL: "/api/webhook/whisper?a=1&b=2", Value: "+14155550199"}}}},
+			want:  `<Dial><Number url="/api/webhook/whisper?a=1&amp;b=2">+14155550199</Number></Dial>`,
+		},
+		{
+			name: "start recording",
+			verbs: []Verb{Start{Recording: &Recording{
+				Channels:                      "dual",
+				RecordingStatusCallback:       "/api/webhook/recording",
+				RecordingStatusCallbackMethod: "POST",
+			}}},
+			want: `<Start><Recording channels="dual" recordingStatusCallback="/api/webhook/recording" recordingStatusCallbackMethod="POST"></Recording></Start>`,
+		},
+		{
+			name:  "record",
+			verbs: []Verb{Record{Action: "/api/webhook/record?key=4", MaxLength: 120, PlayBeep: "true", Transcribe: true}},
+			want:  `<Record action="/api/webhook/record?key=4" maxLength="120" playBeep="true" transcribe="true"></Record>`,
+		},
+		{
+			name:  "redirect and reject",
+			verbs: []Verb{Redirect{Method: "POST", URL: "/api/webhook/voice"}, Reject{Reason: "busy"}},
+			want:  `<Redirect method="POST">/api/webhook/voice</Redirect>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewResponse(tt.verbs...).String()
+			if !strings.HasPrefix(got, xml.Header) {
+				t.Errorf("String() does not start with the XML header:\n%s", got)
+			}
+			if !strings.Contains(compact(got), tt.want) {
+				t.Errorf("String() = %s\nwant it to contain %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseAdd(t *testing.T) {
+	response := NewResponse(Say{Text: "first"}).Add(Say{Text: "second"}, Hangup{})
+
+	got := compact(response.String())
+	want := `<Response><Say>first</Say><Say>second</Say><Hangup></Hangup></Response>`
+	if !strings.Contains(got, want) {
+		t.Errorf("String() = %s, want it to contain %s", got, want)
+	}
+}
+
+// compact drops the indentation added by Marshal
+func compact(document string) string {
+	lines := strings.Split(document, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, "")
+}
@@ -31,7 +31,7 @@ Write-Host ""
 # Test 3: List Campaigns
 Write-Host "3. Testing List Campaigns..." -ForegroundColor Yellow
 try {
-    $response = Invoke-RestMethod -Uri "http://localhost:8080/api/campaigns" -Method Get
+    $response = Invoke-RestMethod -Uri "http://localhost:8080/api/campaigns" -Method Get -Headers @{ "X-API-Key" = $env:API_KEY }
     Write-Host "Found $($response.Count) campaign(s)" -ForegroundColor Cyan
     $response | ConvertTo-Json
     Write-Host "✓ List campaigns working" -ForegroundColor Green
@@ -64,7 +64,7 @@ $campaignData = @{
 
 try {
     $json = $campaignData | ConvertTo-Json -Depth 10
-    $response = Invoke-RestMethod -Uri "http://localhost:8080/api/campaigns" -Method Post -Body $json -ContentType "application/json"
+    $response = Invoke-RestMethod -Uri "http://localhost:8080/api/campaigns" -Method Post -Body $json -ContentType "application/json" -Headers @{ "X-API-Key" = $env:API_KEY }
     Write-Host "Campaign Created:" -ForegroundColor Cyan
     $response | ConvertTo-Json
     Write-Host "✓ Campaign creation successful" -ForegroundColor Green
@@ -2,6 +2,9 @@
 
 # Simple API Test Script
 # Run this to verify the backend is working correctly
+# Management endpoints need an API key: API_KEY=<key> ./test_api.sh
+
+API_KEY="${API_KEY:-}"
 
 echo "================================"
 echo "Testing IVR Backend API"
@@ -20,12 +23,13 @@ echo ""
 
 # Test 3: List Campaigns
 echo "3. Testing List Campaigns..."
-curl -s http://localhost:8080/api/campaigns | jq
+curl -s -H "X-API-Key: $API_KEY" http://localhost:8080/api/campaigns | jq
 echo ""
 
 # Test 4: Create Test Campaign
 echo "4. Creating Test Campaign..."
 curl -s -X POST http://localhost:8080/api/campaigns \
+  -H "X-API-Key: $API_KEY" \
   -H "Content-Type: application/json" \
   -d '{
     "name": "API Test Campaign",